@@ -2,50 +2,420 @@
 package config
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/jackc/pgx/v5"
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-// GetConfig загружает конфигурацию из .env и возвращает полностью инициализированный объект *pgx.ConnConfig.
-func GetConfig() *pgx.ConnConfig {
-	err := godotenv.Load()
+// Config - вся конфигурация приложения в одном месте.
+//
+// Значения собираются в порядке возрастания приоритета:
+// значения по умолчанию -> файл конфигурации (YAML или TOML) -> .env -> переменные окружения.
+type Config struct {
+	Server     ServerConfig     `yaml:"server" toml:"server" json:"server"`
+	Database   DatabaseConfig   `yaml:"database" toml:"database" json:"database"`
+	CORS       CORSConfig       `yaml:"cors" toml:"cors" json:"cors"`
+	JWT        JWTConfig        `yaml:"jwt" toml:"jwt" json:"jwt"`
+	Auth       AuthConfig       `yaml:"auth" toml:"auth" json:"auth"`
+	OAuth      OAuthConfig      `yaml:"oauth" toml:"oauth" json:"oauth"`
+	Export     ExportConfig     `yaml:"export" toml:"export" json:"export"`
+	Account    AccountConfig    `yaml:"account" toml:"account" json:"account"`
+	Rankings   RankingsConfig   `yaml:"rankings" toml:"rankings" json:"rankings"`
+	Share      ShareConfig      `yaml:"share" toml:"share" json:"share"`
+	Webhooks   WebhooksConfig   `yaml:"webhooks" toml:"webhooks" json:"webhooks"`
+	Jobs       JobsConfig       `yaml:"jobs" toml:"jobs" json:"jobs"`
+	Pagination PaginationConfig `yaml:"pagination" toml:"pagination" json:"pagination"`
+	Tracing    TracingConfig    `yaml:"tracing" toml:"tracing" json:"tracing"`
+	Log        LogConfig        `yaml:"log" toml:"log" json:"log"`
+}
+
+// ServerConfig описывает параметры HTTP-сервера и его остановки.
+type ServerConfig struct {
+	Host            string        `yaml:"host" toml:"host" json:"host"`
+	Port            int           `yaml:"port" toml:"port" json:"port"`
+	ReadTimeout     time.Duration `yaml:"read_timeout" toml:"read_timeout" json:"read_timeout"`
+	WriteTimeout    time.Duration `yaml:"write_timeout" toml:"write_timeout" json:"write_timeout"`
+	IdleTimeout     time.Duration `yaml:"idle_timeout" toml:"idle_timeout" json:"idle_timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" json:"shutdown_timeout"`
+	// DrainDelay - сколько после сигнала остановки /readyz отвечает 503, а сервер еще
+	// принимает запросы: за это время балансировщик успевает убрать экземпляр.
+	DrainDelay time.Duration `yaml:"drain_delay" toml:"drain_delay" json:"drain_delay"`
+	// TrustProxyHeaders - брать IP клиента из X-Forwarded-For/X-Real-IP.
+	// Включать только если API стоит за обратным прокси.
+	TrustProxyHeaders bool `yaml:"trust_proxy_headers" toml:"trust_proxy_headers" json:"trust_proxy_headers"`
+}
+
+// Addr возвращает адрес для http.Server в формате host:port.
+func (c ServerConfig) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+type DatabaseConfig struct {
+	Host     string `yaml:"host" toml:"host" json:"host"`
+	Port     int    `yaml:"port" toml:"port" json:"port"`
+	User     string `yaml:"user" toml:"user" json:"user"`
+	Password Secret `yaml:"password" toml:"password" json:"password"`
+	Name     string `yaml:"name" toml:"name" json:"name"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode" json:"sslmode"`
+}
+
+// ConnConfig собирает DSN из отдельных полей и передает его в pgx.ParseConfig.
+// Это необходимо, потому что pgx.ConnConfig нельзя корректно создать напрямую.
+func (c DatabaseConfig) ConnConfig() (*pgx.ConnConfig, error) {
+	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		quoteDSN(c.Host), c.Port, quoteDSN(c.User), quoteDSN(c.Password.Value()), quoteDSN(c.Name), quoteDSN(c.SSLMode))
+
+	connConfig, err := pgx.ParseConfig(dsn)
 	if err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
-	}
-
-	// 1. Собираем DSN-строку из отдельных переменных окружения.
-	// Это необходимо, чтобы передать ее в официальный конструктор pgx.ParseConfig.
-	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		getEnv("DB_HOST", "localhost"),
-		getEnv("DB_PORT", "5432"),
-		getEnv("DB_USER", ""),
-		getEnv("DB_PASSWORD", ""),
-		getEnv("DB_NAME", ""),
-		getEnv("DB_SSLMODE", "disable"),
-	)
-
-	// 2. ИСПОЛЬЗУЕМ ОБЯЗАТЕЛЬНЫЙ КОНСТРУКТОР ParseConfig.
-	// Он создает и правильно инициализирует структуру.
-	config, err := pgx.ParseConfig(dsn)
+		// Сам DSN в ошибку не попадает: в нем пароль
+		return nil, errors.New("unable to build database connection config")
+	}
+	return connConfig, nil
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins" json:"allowed_origins"`
+}
+
+type JWTConfig struct {
+	// Secret - ключ HS256 с kid "default". Оставлен для простых установок
+	// и для проверки токенов, выданных до перехода на ротацию ключей.
+	Secret Secret `yaml:"secret" toml:"secret" json:"secret"`
+	// Keys - набор ключей для ротации. Подписывает только ActiveKeyID,
+	// остальные используются для проверки еще не истекших токенов.
+	Keys        []JWTKeyConfig `yaml:"keys" toml:"keys" json:"keys"`
+	ActiveKeyID string         `yaml:"active_key_id" toml:"active_key_id" json:"active_key_id"`
+	Issuer      string         `yaml:"issuer" toml:"issuer" json:"issuer"`
+	Audience    string         `yaml:"audience" toml:"audience" json:"audience"`
+	TTL         time.Duration  `yaml:"ttl" toml:"ttl" json:"ttl"`
+	// Leeway - допустимое расхождение часов при проверке exp/nbf.
+	Leeway time.Duration `yaml:"leeway" toml:"leeway" json:"leeway"`
+}
+
+// JWTKeyConfig описывает один ключ подписи. Задается либо File (PEM с RSA или
+// Ed25519 ключом; публичный ключ годится только для проверки), либо Secret (HS256).
+type JWTKeyConfig struct {
+	ID     string `yaml:"id" toml:"id" json:"id"`
+	File   string `yaml:"file" toml:"file" json:"file"`
+	Secret Secret `yaml:"secret" toml:"secret" json:"secret"`
+}
+
+type AuthConfig struct {
+	BcryptCost int `yaml:"bcrypt_cost" toml:"bcrypt_cost" json:"bcrypt_cost"`
+	// TOTPIssuer - название сервиса в приложении-аутентификаторе.
+	TOTPIssuer string `yaml:"totp_issuer" toml:"totp_issuer" json:"totp_issuer"`
+	// TwoFactorChallengeTTL - сколько времени есть на ввод кода после проверки пароля.
+	TwoFactorChallengeTTL time.Duration `yaml:"two_factor_challenge_ttl" toml:"two_factor_challenge_ttl" json:"two_factor_challenge_ttl"`
+	// После TwoFactorMaxAttempts неверных кодов подряд проверка блокируется на TwoFactorLockout.
+	TwoFactorMaxAttempts int           `yaml:"two_factor_max_attempts" toml:"two_factor_max_attempts" json:"two_factor_max_attempts"`
+	TwoFactorLockout     time.Duration `yaml:"two_factor_lockout" toml:"two_factor_lockout" json:"two_factor_lockout"`
+}
+
+// OAuthConfig описывает вход через внешних провайдеров (OpenID Connect и GitHub).
+type OAuthConfig struct {
+	// RedirectBaseURL - внешний адрес API; callback провайдера будет <base>/auth/<name>/callback.
+	RedirectBaseURL string `yaml:"redirect_base_url" toml:"redirect_base_url" json:"redirect_base_url"`
+	// StateSecret подписывает cookie с состоянием входа.
+	StateSecret Secret `yaml:"state_secret" toml:"state_secret" json:"state_secret"`
+	// StateTTL - сколько времени у пользователя есть на вход у провайдера.
+	StateTTL  time.Duration         `yaml:"state_ttl" toml:"state_ttl" json:"state_ttl"`
+	Providers []OAuthProviderConfig `yaml:"providers" toml:"providers" json:"providers"`
+}
+
+type OAuthProviderConfig struct {
+	// Name - имя в URL (/auth/<name>/login) и в таблице user_identities.
+	Name string `yaml:"name" toml:"name" json:"name"`
+	// Type - "oidc" для Google и любого OpenID Connect провайдера или "github".
+	Type string `yaml:"type" toml:"type" json:"type"`
+	// IssuerURL - адрес OIDC issuer (например, https://accounts.google.com).
+	IssuerURL string `yaml:"issuer_url" toml:"issuer_url" json:"issuer_url"`
+	// APIURL - адрес REST API для type=github (по умолчанию https://api.github.com).
+	APIURL       string   `yaml:"api_url" toml:"api_url" json:"api_url"`
+	ClientID     string   `yaml:"client_id" toml:"client_id" json:"client_id"`
+	ClientSecret Secret   `yaml:"client_secret" toml:"client_secret" json:"client_secret"`
+	Scopes       []string `yaml:"scopes" toml:"scopes" json:"scopes"`
+}
+
+// ExportConfig описывает выгрузку персональных данных (POST /me/exports).
+type ExportConfig struct {
+	// Dir - каталог для готовых архивов.
+	Dir string `yaml:"dir" toml:"dir" json:"dir"`
+	// LinkSecret подписывает ссылки на скачивание.
+	LinkSecret Secret `yaml:"link_secret" toml:"link_secret" json:"link_secret"`
+	// LinkTTL - срок действия одной ссылки; новую можно получить в статусе выгрузки.
+	LinkTTL time.Duration `yaml:"link_ttl" toml:"link_ttl" json:"link_ttl"`
+	// Retention - сколько хранится готовый архив.
+	Retention time.Duration `yaml:"retention" toml:"retention" json:"retention"`
+}
+
+// AccountConfig описывает удаление аккаунтов.
+type AccountConfig struct {
+	// DeletionGracePeriod - сколько удаленный аккаунт можно восстановить входом.
+	DeletionGracePeriod time.Duration `yaml:"deletion_grace_period" toml:"deletion_grace_period" json:"deletion_grace_period"`
+	// PurgeInterval - как часто запускается стирание аккаунтов с истекшим периодом.
+	PurgeInterval time.Duration `yaml:"purge_interval" toml:"purge_interval" json:"purge_interval"`
+	// AnonymizeRecommendations - оставлять отправленные рекомендации получателям без автора
+	// (true) или удалять их вместе с аккаунтом (false).
+	AnonymizeRecommendations bool `yaml:"anonymize_recommendations" toml:"anonymize_recommendations" json:"anonymize_recommendations"`
+}
+
+// RankingsConfig описывает пересчет рейтингов медиа (/media/trending и /media/popular).
+type RankingsConfig struct {
+	// RefreshInterval - как часто пересчитываются рейтинги.
+	RefreshInterval time.Duration `yaml:"refresh_interval" toml:"refresh_interval" json:"refresh_interval"`
+	// Size - сколько медиа каждого типа попадает в рейтинг.
+	Size int `yaml:"size" toml:"size" json:"size"`
+}
+
+// ShareConfig описывает ссылки, которыми делятся медиа с кем угодно (POST /me/shares).
+type ShareConfig struct {
+	// LinkSecret подписывает ссылки.
+	LinkSecret Secret `yaml:"link_secret" toml:"link_secret" json:"link_secret"`
+	// LinkTTL - срок действия ссылки, если автор не указал свой.
+	LinkTTL time.Duration `yaml:"link_ttl" toml:"link_ttl" json:"link_ttl"`
+	// MaxLinkTTL - на сколько максимум можно выпустить ссылку.
+	MaxLinkTTL time.Duration `yaml:"max_link_ttl" toml:"max_link_ttl" json:"max_link_ttl"`
+}
+
+// WebhooksConfig описывает доставку событий во внешние интеграции (/me/webhooks).
+type WebhooksConfig struct {
+	// Timeout - сколько ждем ответа получателя.
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" json:"timeout"`
+	// MaxAttempts - сколько раз пробуем доставить событие, прежде чем сдаться.
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts" json:"max_attempts"`
+	// RetryBaseDelay - пауза после первой неудачи; дальше удваивается до MaxRetryDelay.
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay" toml:"retry_base_delay" json:"retry_base_delay"`
+	MaxRetryDelay  time.Duration `yaml:"max_retry_delay" toml:"max_retry_delay" json:"max_retry_delay"`
+	// Retention - сколько хранятся события и журнал доставок.
+	Retention time.Duration `yaml:"retention" toml:"retention" json:"retention"`
+	// MaxPerUser - сколько вебхуков может завести пользователь.
+	MaxPerUser int `yaml:"max_per_user" toml:"max_per_user" json:"max_per_user"`
+	// AllowPrivateNetworks разрешает адреса в локальной сети (например, для умного дома
+	// на своем сервере). На публичной установке включать нельзя.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" toml:"allow_private_networks" json:"allow_private_networks"`
+}
+
+// JobsConfig описывает очередь фоновых задач (выгрузки, стирание аккаунтов, периодические задачи).
+type JobsConfig struct {
+	// Workers - сколько задач выполняется одновременно, включая отправку вебхуков.
+	Workers int `yaml:"workers" toml:"workers" json:"workers"`
+	// PollInterval - как часто проверяется очередь.
+	PollInterval time.Duration `yaml:"poll_interval" toml:"poll_interval" json:"poll_interval"`
+	// Timeout - сколько может выполняться одна попытка задачи.
+	Timeout time.Duration `yaml:"timeout" toml:"timeout" json:"timeout"`
+	// MaxAttempts - сколько раз пробуем выполнить задачу, прежде чем сдаться.
+	MaxAttempts int `yaml:"max_attempts" toml:"max_attempts" json:"max_attempts"`
+	// RetryBaseDelay - пауза после первой неудачи; дальше удваивается до MaxRetryDelay.
+	RetryBaseDelay time.Duration `yaml:"retry_base_delay" toml:"retry_base_delay" json:"retry_base_delay"`
+	MaxRetryDelay  time.Duration `yaml:"max_retry_delay" toml:"max_retry_delay" json:"max_retry_delay"`
+	// Retention - сколько хранятся законченные задачи.
+	Retention time.Duration `yaml:"retention" toml:"retention" json:"retention"`
+}
+
+type PaginationConfig struct {
+	DefaultLimit int `yaml:"default_limit" toml:"default_limit" json:"default_limit"`
+	MaxLimit     int `yaml:"max_limit" toml:"max_limit" json:"max_limit"`
+}
+
+// TracingConfig описывает настройки OpenTelemetry-трассировки.
+type TracingConfig struct {
+	// Exporter - куда отправлять спаны: "none", "stdout", "file" или "otlp".
+	Exporter string `yaml:"exporter" toml:"exporter" json:"exporter"`
+	// FilePath - путь к файлу для экспортера "file".
+	FilePath string `yaml:"file" toml:"file" json:"file"`
+	// OTLPEndpoint - адрес OTLP/HTTP коллектора (host:port). Если пустой,
+	// экспортер берет его из стандартной переменной OTEL_EXPORTER_OTLP_ENDPOINT.
+	OTLPEndpoint string `yaml:"otlp_endpoint" toml:"otlp_endpoint" json:"otlp_endpoint"`
+	// OTLPInsecure отключает TLS при отправке в коллектор.
+	OTLPInsecure bool `yaml:"otlp_insecure" toml:"otlp_insecure" json:"otlp_insecure"`
+	// ServiceName попадает в ресурс каждого спана.
+	ServiceName string `yaml:"service_name" toml:"service_name" json:"service_name"`
+	// SampleRatio - доля трассируемых запросов от 0 до 1.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" json:"sample_ratio"`
+}
+
+type LogConfig struct {
+	// Level - минимальный уровень логов: debug, info, warn или error.
+	Level string `yaml:"level" toml:"level" json:"level"`
+}
+
+// Default возвращает конфигурацию со значениями по умолчанию.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            8080,
+			ReadTimeout:     10 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+			DrainDelay:      5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:    "localhost",
+			Port:    5432,
+			SSLMode: "disable",
+		},
+		CORS: CORSConfig{
+			// Адрес Vite dev-сервера фронтенда
+			AllowedOrigins: []string{"http://localhost:5173"},
+		},
+		JWT: JWTConfig{
+			Issuer: "recommendo",
+			TTL:    24 * time.Hour,
+			Leeway: 30 * time.Second,
+		},
+		Auth: AuthConfig{
+			BcryptCost:            10,
+			TOTPIssuer:            "Recommendo",
+			TwoFactorChallengeTTL: 5 * time.Minute,
+			TwoFactorMaxAttempts:  5,
+			TwoFactorLockout:      15 * time.Minute,
+		},
+		OAuth: OAuthConfig{
+			RedirectBaseURL: "http://localhost:8080",
+			StateTTL:        10 * time.Minute,
+		},
+		Export: ExportConfig{
+			Dir:       "exports",
+			LinkTTL:   time.Hour,
+			Retention: 7 * 24 * time.Hour,
+		},
+		Account: AccountConfig{
+			DeletionGracePeriod:      30 * 24 * time.Hour,
+			PurgeInterval:            time.Hour,
+			AnonymizeRecommendations: true,
+		},
+		Rankings: RankingsConfig{
+			RefreshInterval: 15 * time.Minute,
+			Size:            200,
+		},
+		Share: ShareConfig{
+			LinkTTL:    7 * 24 * time.Hour,
+			MaxLinkTTL: 90 * 24 * time.Hour,
+		},
+		Webhooks: WebhooksConfig{
+			Timeout:        10 * time.Second,
+			MaxAttempts:    8,
+			RetryBaseDelay: 30 * time.Second,
+			MaxRetryDelay:  6 * time.Hour,
+			Retention:      30 * 24 * time.Hour,
+			MaxPerUser:     10,
+		},
+		Jobs: JobsConfig{
+			Workers:        10,
+			PollInterval:   time.Second,
+			Timeout:        10 * time.Minute,
+			MaxAttempts:    5,
+			RetryBaseDelay: 30 * time.Second,
+			MaxRetryDelay:  time.Hour,
+			Retention:      7 * 24 * time.Hour,
+		},
+		Pagination: PaginationConfig{
+			DefaultLimit: 20,
+			MaxLimit:     100,
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			FilePath:    "traces.json",
+			ServiceName: "recommendo",
+			SampleRatio: 1,
+		},
+		Log: LogConfig{
+			Level: "info",
+		},
+	}
+}
+
+// Load собирает конфигурацию и валидирует ее.
+// path - необязательный путь к YAML/TOML файлу; пустая строка означает "без файла".
+// Отсутствующий .env не является ошибкой: в контейнерах переменные приходят из окружения.
+func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
 	if err != nil {
-		log.Fatalf("Unable to parse DSN: %v", err)
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Read собирает конфигурацию так же, как Load, но не валидирует ее: config print
+// должен показать и конфигурацию, которая не проходит проверку.
+func Read(path string) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("failed to load .env file: %w", err)
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
 	}
 
-	return config
+	return cfg, nil
 }
 
-// Вспомогательная функция остается без изменений.
-func getEnv(key string, defaultValue string) string {
-	value := os.Getenv(key)
-	if value == "" {
-		if defaultValue != "" {
-			return defaultValue
+// loadFile накладывает значения из файла поверх текущих. Формат определяется по расширению.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(strings.NewReader(string(data)))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("failed to parse YAML config %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("failed to parse TOML config %s: %w", path, err)
 		}
-		log.Fatalf("Environment variable %s not set", key)
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("unknown keys in TOML config %s: %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("unsupported config file format %q: use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+
+	return nil
+}
+
+// quoteDSN экранирует значение для DSN формата key=value.
+func quoteDSN(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+// Print выводит итоговую конфигурацию в YAML. Секреты заменяются на [REDACTED].
+func (c *Config) Print(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
 	}
-	return value
-}
\ No newline at end of file
+	return enc.Close()
+}
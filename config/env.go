@@ -0,0 +1,236 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// applyEnv переопределяет значения переменными окружения.
+// Пустая переменная считается незаданной.
+func (c *Config) applyEnv() error {
+	e := &envReader{}
+
+	e.string("SERVER_HOST", &c.Server.Host)
+	e.int("SERVER_PORT", &c.Server.Port)
+	e.duration("SERVER_READ_TIMEOUT", &c.Server.ReadTimeout)
+	e.duration("SERVER_WRITE_TIMEOUT", &c.Server.WriteTimeout)
+	e.duration("SERVER_IDLE_TIMEOUT", &c.Server.IdleTimeout)
+	e.duration("SERVER_SHUTDOWN_TIMEOUT", &c.Server.ShutdownTimeout)
+	e.duration("SERVER_DRAIN_DELAY", &c.Server.DrainDelay)
+	e.bool("SERVER_TRUST_PROXY_HEADERS", &c.Server.TrustProxyHeaders)
+
+	e.string("DB_HOST", &c.Database.Host)
+	e.int("DB_PORT", &c.Database.Port)
+	e.string("DB_USER", &c.Database.User)
+	e.secret("DB_PASSWORD", &c.Database.Password)
+	e.string("DB_NAME", &c.Database.Name)
+	e.string("DB_SSLMODE", &c.Database.SSLMode)
+
+	e.list("CORS_ALLOWED_ORIGINS", &c.CORS.AllowedOrigins)
+
+	e.secret("JWT_SECRET_KEY", &c.JWT.Secret)
+	e.jwtKeys("JWT_KEYS", &c.JWT.Keys)
+	e.string("JWT_ACTIVE_KEY_ID", &c.JWT.ActiveKeyID)
+	e.string("JWT_ISSUER", &c.JWT.Issuer)
+	e.string("JWT_AUDIENCE", &c.JWT.Audience)
+	e.duration("JWT_TTL", &c.JWT.TTL)
+	e.duration("JWT_LEEWAY", &c.JWT.Leeway)
+
+	e.int("BCRYPT_COST", &c.Auth.BcryptCost)
+	e.string("TOTP_ISSUER", &c.Auth.TOTPIssuer)
+	e.duration("TWO_FACTOR_CHALLENGE_TTL", &c.Auth.TwoFactorChallengeTTL)
+	e.int("TWO_FACTOR_MAX_ATTEMPTS", &c.Auth.TwoFactorMaxAttempts)
+	e.duration("TWO_FACTOR_LOCKOUT", &c.Auth.TwoFactorLockout)
+
+	e.string("OAUTH_REDIRECT_BASE_URL", &c.OAuth.RedirectBaseURL)
+	e.secret("OAUTH_STATE_SECRET", &c.OAuth.StateSecret)
+	e.duration("OAUTH_STATE_TTL", &c.OAuth.StateTTL)
+	e.oauthProviders("OAUTH_PROVIDERS", &c.OAuth.Providers)
+
+	e.string("EXPORT_DIR", &c.Export.Dir)
+	e.secret("EXPORT_LINK_SECRET", &c.Export.LinkSecret)
+	e.duration("EXPORT_LINK_TTL", &c.Export.LinkTTL)
+	e.duration("EXPORT_RETENTION", &c.Export.Retention)
+
+	e.duration("ACCOUNT_DELETION_GRACE_PERIOD", &c.Account.DeletionGracePeriod)
+	e.duration("ACCOUNT_PURGE_INTERVAL", &c.Account.PurgeInterval)
+	e.bool("ACCOUNT_ANONYMIZE_RECOMMENDATIONS", &c.Account.AnonymizeRecommendations)
+
+	e.duration("RANKINGS_REFRESH_INTERVAL", &c.Rankings.RefreshInterval)
+	e.int("RANKINGS_SIZE", &c.Rankings.Size)
+
+	e.secret("SHARE_LINK_SECRET", &c.Share.LinkSecret)
+	e.duration("SHARE_LINK_TTL", &c.Share.LinkTTL)
+	e.duration("SHARE_MAX_LINK_TTL", &c.Share.MaxLinkTTL)
+
+	e.duration("WEBHOOKS_TIMEOUT", &c.Webhooks.Timeout)
+	e.int("WEBHOOKS_MAX_ATTEMPTS", &c.Webhooks.MaxAttempts)
+	e.duration("WEBHOOKS_RETRY_BASE_DELAY", &c.Webhooks.RetryBaseDelay)
+	e.duration("WEBHOOKS_MAX_RETRY_DELAY", &c.Webhooks.MaxRetryDelay)
+	e.duration("WEBHOOKS_RETENTION", &c.Webhooks.Retention)
+	e.int("WEBHOOKS_MAX_PER_USER", &c.Webhooks.MaxPerUser)
+	e.bool("WEBHOOKS_ALLOW_PRIVATE_NETWORKS", &c.Webhooks.AllowPrivateNetworks)
+
+	e.int("JOBS_WORKERS", &c.Jobs.Workers)
+	e.duration("JOBS_POLL_INTERVAL", &c.Jobs.PollInterval)
+	e.duration("JOBS_TIMEOUT", &c.Jobs.Timeout)
+	e.int("JOBS_MAX_ATTEMPTS", &c.Jobs.MaxAttempts)
+	e.duration("JOBS_RETRY_BASE_DELAY", &c.Jobs.RetryBaseDelay)
+	e.duration("JOBS_MAX_RETRY_DELAY", &c.Jobs.MaxRetryDelay)
+	e.duration("JOBS_RETENTION", &c.Jobs.Retention)
+
+	e.int("PAGINATION_DEFAULT_LIMIT", &c.Pagination.DefaultLimit)
+	e.int("PAGINATION_MAX_LIMIT", &c.Pagination.MaxLimit)
+
+	e.string("TRACING_EXPORTER", &c.Tracing.Exporter)
+	e.string("TRACING_FILE", &c.Tracing.FilePath)
+	e.string("TRACING_OTLP_ENDPOINT", &c.Tracing.OTLPEndpoint)
+	e.bool("TRACING_OTLP_INSECURE", &c.Tracing.OTLPInsecure)
+	e.string("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	e.float("TRACING_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	e.string("LOG_LEVEL", &c.Log.Level)
+
+	return errors.Join(e.errs...)
+}
+
+// envReader копит ошибки разбора, чтобы сообщить обо всех сразу.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) lookup(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
+}
+
+func (e *envReader) fail(key, value, want string) {
+	e.errs = append(e.errs, fmt.Errorf("%s=%q: expected %s", key, value, want))
+}
+
+func (e *envReader) string(key string, dst *string) {
+	if value, ok := e.lookup(key); ok {
+		*dst = value
+	}
+}
+
+func (e *envReader) secret(key string, dst *Secret) {
+	if value, ok := e.lookup(key); ok {
+		*dst = Secret(value)
+	}
+}
+
+func (e *envReader) list(key string, dst *[]string) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*dst = items
+}
+
+func (e *envReader) int(key string, dst *int) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		e.fail(key, value, "an integer")
+		return
+	}
+	*dst = n
+}
+
+func (e *envReader) float(key string, dst *float64) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		e.fail(key, value, "a number")
+		return
+	}
+	*dst = f
+}
+
+func (e *envReader) bool(key string, dst *bool) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail(key, value, "true or false")
+		return
+	}
+	*dst = b
+}
+
+func (e *envReader) duration(key string, dst *time.Duration) {
+	value, ok := e.lookup(key)
+	if !ok {
+		return
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		e.fail(key, value, "a duration like 15s or 1h")
+		return
+	}
+	*dst = d
+}
+
+// jwtKeys разбирает список вида "kid1=/keys/a.pem,kid2=/keys/b.pub".
+func (e *envReader) jwtKeys(key string, dst *[]JWTKeyConfig) {
+	var pairs []string
+	e.list(key, &pairs)
+	if pairs == nil {
+		return
+	}
+
+	keys := make([]JWTKeyConfig, 0, len(pairs))
+	for _, pair := range pairs {
+		id, file, ok := strings.Cut(pair, "=")
+		if !ok || id == "" || file == "" {
+			e.fail(key, pair, "kid=/path/to/key.pem")
+			continue
+		}
+		keys = append(keys, JWTKeyConfig{ID: strings.TrimSpace(id), File: strings.TrimSpace(file)})
+	}
+	*dst = keys
+}
+
+// oauthProviders читает провайдеров из OAUTH_PROVIDERS=google,github и переменных
+// OAUTH_<NAME>_TYPE, _ISSUER_URL, _API_URL, _CLIENT_ID, _CLIENT_SECRET, _SCOPES.
+// Провайдеры из файла конфигурации при этом заменяются целиком.
+func (e *envReader) oauthProviders(key string, dst *[]OAuthProviderConfig) {
+	var names []string
+	e.list(key, &names)
+	if names == nil {
+		return
+	}
+
+	providers := make([]OAuthProviderConfig, 0, len(names))
+	for _, name := range names {
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		p := OAuthProviderConfig{Name: name, Type: "oidc"}
+		e.string(prefix+"TYPE", &p.Type)
+		e.string(prefix+"ISSUER_URL", &p.IssuerURL)
+		e.string(prefix+"API_URL", &p.APIURL)
+		e.string(prefix+"CLIENT_ID", &p.ClientID)
+		e.secret(prefix+"CLIENT_SECRET", &p.ClientSecret)
+		e.list(prefix+"SCOPES", &p.Scopes)
+		providers = append(providers, p)
+	}
+	*dst = providers
+}
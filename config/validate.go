@@ -0,0 +1,184 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Validate проверяет конфигурацию целиком и возвращает все найденные проблемы разом.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Port > 0 && c.Server.Port <= 65535, "server.port must be between 1 and 65535, got %d", c.Server.Port)
+	check(c.Server.ReadTimeout > 0, "server.read_timeout must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write_timeout must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle_timeout must be positive")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown_timeout must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain_delay must not be negative")
+
+	check(c.Database.Host != "", "database.host is required")
+	check(c.Database.Port > 0 && c.Database.Port <= 65535, "database.port must be between 1 and 65535, got %d", c.Database.Port)
+	check(c.Database.User != "", "database.user is required (DB_USER)")
+	check(c.Database.Name != "", "database.name is required (DB_NAME)")
+	check(slices.Contains([]string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}, c.Database.SSLMode),
+		"database.sslmode %q is not a valid libpq sslmode", c.Database.SSLMode)
+
+	errs = append(errs, c.JWT.validate()...)
+
+	// Границы bcrypt.MinCost и bcrypt.MaxCost
+	check(c.Auth.BcryptCost >= 4 && c.Auth.BcryptCost <= 31, "auth.bcrypt_cost must be between 4 and 31, got %d", c.Auth.BcryptCost)
+	check(c.Auth.TOTPIssuer != "", "auth.totp_issuer is required")
+	check(c.Auth.TwoFactorChallengeTTL > 0, "auth.two_factor_challenge_ttl must be positive")
+	check(c.Auth.TwoFactorMaxAttempts > 0, "auth.two_factor_max_attempts must be positive")
+	check(c.Auth.TwoFactorLockout > 0, "auth.two_factor_lockout must be positive")
+
+	errs = append(errs, c.OAuth.validate()...)
+
+	check(c.Export.Dir != "", "export.dir is required")
+	// Без секрета ссылку на чужую выгрузку можно было бы подделать
+	check(len(c.Export.LinkSecret) >= 32, "export.link_secret must be at least 32 bytes (EXPORT_LINK_SECRET)")
+	check(c.Export.LinkTTL > 0, "export.link_ttl must be positive")
+	check(c.Export.Retention >= c.Export.LinkTTL, "export.retention must be >= export.link_ttl")
+
+	check(c.Account.DeletionGracePeriod >= 0, "account.deletion_grace_period must not be negative")
+	check(c.Account.PurgeInterval >= time.Second, "account.purge_interval must be at least 1s")
+
+	check(c.Rankings.RefreshInterval >= time.Second, "rankings.refresh_interval must be at least 1s")
+	check(c.Rankings.Size > 0, "rankings.size must be positive")
+
+	check(len(c.Share.LinkSecret) >= 32, "share.link_secret must be at least 32 bytes (SHARE_LINK_SECRET)")
+	check(c.Share.LinkTTL > 0, "share.link_ttl must be positive")
+	check(c.Share.MaxLinkTTL >= c.Share.LinkTTL, "share.max_link_ttl must be >= share.link_ttl")
+
+	check(c.Webhooks.Timeout > 0, "webhooks.timeout must be positive")
+	check(c.Webhooks.MaxAttempts > 0, "webhooks.max_attempts must be positive")
+	check(c.Webhooks.RetryBaseDelay > 0, "webhooks.retry_base_delay must be positive")
+	check(c.Webhooks.MaxRetryDelay >= c.Webhooks.RetryBaseDelay, "webhooks.max_retry_delay must be >= webhooks.retry_base_delay")
+	// Журнал не должен исчезнуть раньше, чем закончатся повторы
+	check(c.Webhooks.Retention >= time.Duration(c.Webhooks.MaxAttempts)*c.Webhooks.MaxRetryDelay,
+		"webhooks.retention must be >= webhooks.max_attempts * webhooks.max_retry_delay")
+	check(c.Webhooks.MaxPerUser > 0, "webhooks.max_per_user must be positive")
+
+	check(c.Jobs.Workers > 0, "jobs.workers must be positive")
+	check(c.Jobs.PollInterval > 0, "jobs.poll_interval must be positive")
+	check(c.Jobs.Timeout > 0, "jobs.timeout must be positive")
+	check(c.Jobs.MaxAttempts > 0, "jobs.max_attempts must be positive")
+	check(c.Jobs.RetryBaseDelay > 0, "jobs.retry_base_delay must be positive")
+	check(c.Jobs.MaxRetryDelay >= c.Jobs.RetryBaseDelay, "jobs.max_retry_delay must be >= jobs.retry_base_delay")
+	check(c.Jobs.Retention > 0, "jobs.retention must be positive")
+
+	check(c.Pagination.DefaultLimit > 0, "pagination.default_limit must be positive")
+	check(c.Pagination.MaxLimit >= c.Pagination.DefaultLimit, "pagination.max_limit must be >= pagination.default_limit")
+
+	check(slices.Contains([]string{"none", "stdout", "file", "otlp"}, c.Tracing.Exporter),
+		"tracing.exporter must be one of none, stdout, file, otlp, got %q", c.Tracing.Exporter)
+	check(c.Tracing.Exporter != "file" || c.Tracing.FilePath != "", "tracing.file is required for the file exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio must be between 0 and 1")
+
+	check(slices.Contains([]string{"debug", "info", "warn", "error"}, c.Log.Level),
+		"log.level must be one of debug, info, warn, error, got %q", c.Log.Level)
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+func (c JWTConfig) validate() []error {
+	var errs []error
+
+	// Пустой или короткий ключ означал бы, что токен можно подделать
+	if c.Secret != "" && len(c.Secret) < 32 {
+		errs = append(errs, errors.New("jwt.secret must be at least 32 bytes (JWT_SECRET_KEY)"))
+	}
+	if c.Secret == "" && len(c.Keys) == 0 {
+		errs = append(errs, errors.New("jwt.secret (JWT_SECRET_KEY) or jwt.keys (JWT_KEYS) is required"))
+	}
+
+	ids := map[string]bool{}
+	if c.Secret != "" {
+		ids["default"] = true
+	}
+	for i, key := range c.Keys {
+		switch {
+		case key.ID == "":
+			errs = append(errs, fmt.Errorf("jwt.keys[%d].id is required", i))
+		case ids[key.ID]:
+			errs = append(errs, fmt.Errorf("jwt.keys[%d].id %q is duplicated", i, key.ID))
+		}
+		ids[key.ID] = true
+
+		if (key.File == "") == (key.Secret == "") {
+			errs = append(errs, fmt.Errorf("jwt.keys[%d] must set exactly one of file or secret", i))
+		}
+		if key.Secret != "" && len(key.Secret) < 32 {
+			errs = append(errs, fmt.Errorf("jwt.keys[%d].secret must be at least 32 bytes", i))
+		}
+	}
+
+	active := c.ActiveKeyID
+	if active == "" {
+		active = "default"
+	}
+	if len(ids) > 0 && !ids[active] {
+		errs = append(errs, fmt.Errorf("jwt.active_key_id %q does not match any configured key", active))
+	}
+
+	if c.TTL <= 0 {
+		errs = append(errs, errors.New("jwt.ttl must be positive"))
+	}
+	if c.Leeway < 0 {
+		errs = append(errs, errors.New("jwt.leeway must not be negative"))
+	}
+
+	return errs
+}
+
+func (c OAuthConfig) validate() []error {
+	if len(c.Providers) == 0 {
+		return nil
+	}
+
+	var errs []error
+	if len(c.StateSecret) < 32 {
+		errs = append(errs, errors.New("oauth.state_secret must be at least 32 bytes when providers are configured (OAUTH_STATE_SECRET)"))
+	}
+	if c.RedirectBaseURL == "" {
+		errs = append(errs, errors.New("oauth.redirect_base_url is required when providers are configured"))
+	}
+	if c.StateTTL <= 0 {
+		errs = append(errs, errors.New("oauth.state_ttl must be positive"))
+	}
+
+	names := map[string]bool{}
+	for i, p := range c.Providers {
+		if p.Name == "" {
+			errs = append(errs, fmt.Errorf("oauth.providers[%d].name is required", i))
+		} else if names[p.Name] {
+			errs = append(errs, fmt.Errorf("oauth.providers[%d].name %q is duplicated", i, p.Name))
+		}
+		names[p.Name] = true
+
+		switch p.Type {
+		case "oidc":
+			if p.IssuerURL == "" {
+				errs = append(errs, fmt.Errorf("oauth provider %q: issuer_url is required for type oidc", p.Name))
+			}
+		case "github":
+		default:
+			errs = append(errs, fmt.Errorf("oauth provider %q: type must be oidc or github, got %q", p.Name, p.Type))
+		}
+		if p.ClientID == "" {
+			errs = append(errs, fmt.Errorf("oauth provider %q: client_id is required", p.Name))
+		}
+	}
+
+	return errs
+}
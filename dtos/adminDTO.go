@@ -0,0 +1,25 @@
+package dtos
+
+import "time"
+
+// AdminUserResponseDTO - пользователь глазами модератора: со служебными полями,
+// которые не показываются в публичных ответах.
+type AdminUserResponseDTO struct {
+	ID               int        `json:"user_id"`
+	UserName         string     `json:"user_name"`
+	Email            string     `json:"email"`
+	Role             string     `json:"role"`
+	CreatedAt        time.Time  `json:"created_at"`
+	DeletedAt        *time.Time `json:"deleted_at,omitempty"`
+	DeactivatedAt    *time.Time `json:"deactivated_at,omitempty"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+}
+
+type SuspendUserDTO struct {
+	Reason string `json:"reason"`
+}
+
+type SetUserRoleDTO struct {
+	Role string `json:"role"`
+}
@@ -0,0 +1,15 @@
+package dtos
+
+import "time"
+
+type AuditEventResponseDTO struct {
+	ID         int64          `json:"event_id"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	ActorID    *int           `json:"actor_id"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"target_type,omitempty"`
+	TargetID   *int           `json:"target_id,omitempty"`
+	IPAddress  string         `json:"ip_address,omitempty"`
+	RequestID  string         `json:"request_id,omitempty"`
+	Metadata   map[string]any `json:"metadata,omitempty"`
+}
@@ -0,0 +1,63 @@
+package dtos
+
+import "time"
+
+type SaveCircleRequestDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type CircleResponseDTO struct {
+	ID          int       `json:"circle_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	MemberCount int       `json:"member_count"`
+	MyRole      string    `json:"my_role"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Members заполняется только в GET /circles/{circleID}
+	Members []CircleMemberDTO `json:"members,omitempty"`
+}
+
+type CircleMemberDTO struct {
+	User     UserSummaryDTO `json:"user"`
+	Role     string         `json:"role"`
+	JoinedAt time.Time      `json:"joined_at"`
+}
+
+type AddCircleMemberRequestDTO struct {
+	UserID int `json:"user_id"`
+	// Role - member (по умолчанию) или admin
+	Role string `json:"role"`
+}
+
+type SetCircleMemberRoleRequestDTO struct {
+	Role string `json:"role"`
+}
+
+type CircleRecommendationRequestDTO struct {
+	MediaID int    `json:"media_id"`
+	Note    string `json:"note"`
+}
+
+type CirclePostResponseDTO struct {
+	ID int64 `json:"post_id"`
+	// Author отсутствует у постов стертых аккаунтов
+	Author         *UserSummaryDTO     `json:"author,omitempty"`
+	Media          MediaResponseDTO    `json:"media"`
+	Note           string              `json:"note"`
+	CreatedAt      time.Time           `json:"created_at"`
+	Reactions      []CircleReactionDTO `json:"reactions"`
+	ReactionCounts map[string]int      `json:"reaction_counts"`
+	// RecipientCount - скольким участникам разошлась рекомендация; только в ответе на публикацию
+	RecipientCount *int64 `json:"recipient_count,omitempty"`
+}
+
+type CircleReactionDTO struct {
+	User      UserSummaryDTO `json:"user"`
+	Reaction  string         `json:"reaction"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+type SetCircleReactionRequestDTO struct {
+	Reaction string `json:"reaction"`
+}
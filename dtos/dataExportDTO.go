@@ -0,0 +1,60 @@
+package dtos
+
+import "time"
+
+type DataExportResponseDTO struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// DownloadURL - подписанная ссылка с ограниченным сроком действия, есть только у готовой выгрузки
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// Структуры ниже описывают JSON-файлы внутри архива выгрузки.
+
+type ExportProfileDTO struct {
+	UserID               int       `json:"user_id"`
+	UserName             string    `json:"user_name"`
+	Email                string    `json:"email"`
+	PasswordLoginEnabled bool      `json:"password_login_enabled"`
+	CreatedAt            time.Time `json:"created_at"`
+}
+
+type ExportUserDTO struct {
+	UserID   int    `json:"user_id"`
+	UserName string `json:"user_name"`
+}
+
+type ExportFollowDTO struct {
+	User       ExportUserDTO `json:"user"`
+	FollowedAt time.Time     `json:"followed_at"`
+}
+
+type ExportMediaDTO struct {
+	MediaID int    `json:"media_id"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Year    int    `json:"year"`
+	Author  string `json:"author"`
+}
+
+type ExportRecommendationDTO struct {
+	RecommendationID int            `json:"recommendation_id"`
+	Media            ExportMediaDTO `json:"media"`
+	// User - получатель для отправленных рекомендаций и отправитель для полученных
+	User      ExportUserDTO `json:"user"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+type ExportLibraryEntryDTO struct {
+	Media        ExportMediaDTO `json:"media"`
+	Status       string         `json:"status"`
+	Progress     int            `json:"progress"`
+	ProgressUnit string         `json:"progress_unit"`
+	StartedOn    string         `json:"started_on,omitempty"`
+	FinishedOn   string         `json:"finished_on,omitempty"`
+	Score        *int           `json:"score,omitempty"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
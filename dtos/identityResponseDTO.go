@@ -0,0 +1,13 @@
+package dtos
+
+import "time"
+
+type IdentityResponseDTO struct {
+	Provider  string    `json:"provider"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type AuthorizationURLResponseDTO struct {
+	AuthorizationURL string `json:"authorization_url"`
+}
@@ -0,0 +1,26 @@
+package dtos
+
+import "time"
+
+// SaveLibraryEntryDTO - тело PUT /me/library/{mediaID}. Даты в формате YYYY-MM-DD.
+type SaveLibraryEntryDTO struct {
+	// Status - planned, in_progress, completed или dropped
+	Status     string  `json:"status"`
+	Progress   int     `json:"progress"`
+	StartedOn  *string `json:"started_on"`
+	FinishedOn *string `json:"finished_on"`
+	Score      *int    `json:"score"`
+}
+
+type LibraryEntryResponseDTO struct {
+	Media    MediaResponseDTO `json:"media"`
+	Status   string           `json:"status"`
+	Progress int              `json:"progress"`
+	// ProgressUnit - episodes, pages, hours или minutes в зависимости от типа медиа
+	ProgressUnit string    `json:"progress_unit"`
+	StartedOn    *string   `json:"started_on,omitempty"`
+	FinishedOn   *string   `json:"finished_on,omitempty"`
+	Score        *int      `json:"score,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,104 @@
+package dtos
+
+import "time"
+
+type MediaCreatorDTO struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// SaveMediaDTO - тело POST /admin/media и PUT /admin/media/{mediaID}.
+// Поля, зависящие от типа, проверяются по type: runtime_minutes - у фильмов,
+// episode_count - у аниме и сериалов, page_count - у книг, platforms - у игр.
+type SaveMediaDTO struct {
+	Type              string            `json:"type"`
+	Name              string            `json:"name"`
+	Year              int               `json:"year"`
+	Author            string            `json:"author"`
+	OriginalTitle     string            `json:"original_title"`
+	AlternativeTitles []string          `json:"alternative_titles"`
+	Description       string            `json:"description"`
+	CoverURL          string            `json:"cover_url"`
+	Genres            []string          `json:"genres"`
+	Tags              []string          `json:"tags"`
+	RuntimeMinutes    int               `json:"runtime_minutes"`
+	EpisodeCount      int               `json:"episode_count"`
+	PageCount         int               `json:"page_count"`
+	Platforms         []string          `json:"platforms"`
+	Creators          []MediaCreatorDTO `json:"creators"`
+	ExternalIDs       map[string]string `json:"external_ids"`
+}
+
+type MediaResponseDTO struct {
+	ID                int               `json:"media_id"`
+	Type              string            `json:"type"`
+	Name              string            `json:"name"`
+	Year              int               `json:"year"`
+	Author            string            `json:"author"`
+	OriginalTitle     string            `json:"original_title,omitempty"`
+	AlternativeTitles []string          `json:"alternative_titles,omitempty"`
+	Description       string            `json:"description,omitempty"`
+	CoverURL          string            `json:"cover_url,omitempty"`
+	Genres            []string          `json:"genres,omitempty"`
+	Tags              []string          `json:"tags,omitempty"`
+	RuntimeMinutes    int               `json:"runtime_minutes,omitempty"`
+	EpisodeCount      int               `json:"episode_count,omitempty"`
+	PageCount         int               `json:"page_count,omitempty"`
+	Platforms         []string          `json:"platforms,omitempty"`
+	Creators          []MediaCreatorDTO `json:"creators"`
+	ExternalIDs       map[string]string `json:"external_ids,omitempty"`
+}
+
+type MediaRecommendationDTO struct {
+	ID int `json:"recommendation_id"`
+	// From - nil для рекомендаций от стертых аккаунтов
+	From      *UserSummaryDTO `json:"from,omitempty"`
+	To        UserSummaryDTO  `json:"to"`
+	Status    string          `json:"status"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+type MediaRatingsDTO struct {
+	AverageScore   *float64 `json:"average_score"`
+	ScoreCount     int64    `json:"score_count"`
+	LibraryCount   int64    `json:"library_count"`
+	CompletedCount int64    `json:"completed_count"`
+}
+
+// MediaLibraryStateDTO - запись медиа в библиотеке текущего пользователя.
+type MediaLibraryStateDTO struct {
+	Status       string    `json:"status"`
+	Progress     int       `json:"progress"`
+	ProgressUnit string    `json:"progress_unit"`
+	StartedOn    *string   `json:"started_on,omitempty"`
+	FinishedOn   *string   `json:"finished_on,omitempty"`
+	Score        *int      `json:"score,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// MediaDetailsResponseDTO - ответ GET /media/{mediaID}.
+type MediaDetailsResponseDTO struct {
+	Media                 MediaResponseDTO         `json:"media"`
+	Ratings               MediaRatingsDTO          `json:"ratings"`
+	RecommendedToCount    int64                    `json:"recommended_to_count"`
+	Library               *MediaLibraryStateDTO    `json:"library"`
+	FriendRecommendations []MediaRecommendationDTO `json:"friend_recommendations"`
+	SentByMe              []MediaRecommendationDTO `json:"sent_by_me"`
+	ReceivedByMe          []MediaRecommendationDTO `json:"received_by_me"`
+}
+
+type RankedMediaDTO struct {
+	Rank                int              `json:"rank"`
+	Media               MediaResponseDTO `json:"media"`
+	Score               float64          `json:"score"`
+	RecommendationCount int64            `json:"recommendation_count"`
+}
+
+// RankingResponseDTO - ответ /media/trending и /media/popular.
+type RankingResponseDTO struct {
+	PaginatedResponseDTO[RankedMediaDTO]
+	Ranking string `json:"ranking"`
+	Window  string `json:"window"`
+	// ComputedAt - когда рейтинг пересчитывался в последний раз
+	ComputedAt *time.Time `json:"computed_at"`
+}
@@ -0,0 +1,31 @@
+package dtos
+
+import "time"
+
+// MarkRecommendationsReadRequestDTO - пустой список отмечает прочитанными все непрочитанные
+type MarkRecommendationsReadRequestDTO struct {
+	RecommendationIDs []int64 `json:"recommendation_ids"`
+}
+
+type MarkRecommendationsReadResponseDTO struct {
+	Updated int64 `json:"updated"`
+}
+
+type UnreadCountResponseDTO struct {
+	Unread int64 `json:"unread"`
+}
+
+type SnoozeRecommendationRequestDTO struct {
+	Until time.Time `json:"until"`
+}
+
+// DismissRecommendationRequestDTO - тело запросов скрытия и отклонения; может отсутствовать
+type DismissRecommendationRequestDTO struct {
+	// DontRecommendAgain - запретить рекомендовать это медиа пользователю снова
+	DontRecommendAgain bool `json:"dont_recommend_again"`
+}
+
+type RecommendationOptOutResponseDTO struct {
+	Media     MediaResponseDTO `json:"media"`
+	CreatedAt time.Time        `json:"created_at"`
+}
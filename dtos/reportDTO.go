@@ -0,0 +1,55 @@
+package dtos
+
+import "time"
+
+type CreateReportDTO struct {
+	// TargetType - user, recommendation или media
+	TargetType string `json:"target_type"`
+	TargetID   int    `json:"target_id"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details"`
+}
+
+type ReportResponseDTO struct {
+	ID             int        `json:"report_id"`
+	ReporterID     *int       `json:"reporter_id,omitempty"`
+	TargetType     string     `json:"target_type"`
+	TargetID       int        `json:"target_id"`
+	Reason         string     `json:"reason"`
+	Details        string     `json:"details,omitempty"`
+	Status         string     `json:"status"`
+	AssigneeID     *int       `json:"assignee_id,omitempty"`
+	ResolutionNote string     `json:"resolution_note,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty"`
+}
+
+type ModerationActionResponseDTO struct {
+	ModeratorID int       `json:"moderator_id,omitempty"`
+	Action      string    `json:"action"`
+	TargetType  string    `json:"target_type"`
+	TargetID    int       `json:"target_id"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ReportDetailsResponseDTO - жалоба для модератора вместе с историей действий по ней.
+type ReportDetailsResponseDTO struct {
+	ReportResponseDTO
+	Actions []ModerationActionResponseDTO `json:"actions"`
+}
+
+type AssignReportDTO struct {
+	// AssigneeID можно не указывать - тогда жалоба назначается текущему модератору
+	AssigneeID int `json:"assignee_id"`
+}
+
+type ResolveReportDTO struct {
+	// Action - none, suspend_user или remove_content
+	Action string `json:"action"`
+	Note   string `json:"note"`
+}
+
+type DismissReportDTO struct {
+	Note string `json:"note"`
+}
@@ -0,0 +1,19 @@
+package dtos
+
+import "time"
+
+type SessionResponseDTO struct {
+	ID         string    `json:"id"`
+	Device     string    `json:"device"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// Current - сессия, с которой сделан запрос
+	Current bool `json:"current"`
+}
+
+type RevokedSessionsResponseDTO struct {
+	Revoked int64 `json:"revoked"`
+}
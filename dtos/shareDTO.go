@@ -0,0 +1,35 @@
+package dtos
+
+import "time"
+
+type CreateShareLinkRequestDTO struct {
+	MediaIDs     []int  `json:"media_ids"`
+	Note         string `json:"note"`
+	PromptFollow bool   `json:"prompt_follow"`
+	// ExpiresAt - необязательный срок ссылки; по умолчанию share.link_ttl
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// ShareLinkResponseDTO - ссылка для автора и для открывшего ее.
+// URL содержит подпись: с ним ссылку можно открыть и принять.
+type ShareLinkResponseDTO struct {
+	ID           string             `json:"share_id"`
+	URL          string             `json:"url"`
+	Owner        *UserSummaryDTO    `json:"owner,omitempty"`
+	Note         string             `json:"note"`
+	PromptFollow bool               `json:"prompt_follow"`
+	Media        []MediaResponseDTO `json:"media"`
+	ExpiresAt    time.Time          `json:"expires_at"`
+	CreatedAt    time.Time          `json:"created_at"`
+	RevokedAt    *time.Time         `json:"revoked_at,omitempty"`
+}
+
+type AcceptShareLinkRequestDTO struct {
+	// Follow - заодно подписаться на автора ссылки
+	Follow bool `json:"follow"`
+}
+
+type AcceptShareLinkResponseDTO struct {
+	RecommendationsCreated int  `json:"recommendations_created"`
+	Followed               bool `json:"followed"`
+}
@@ -0,0 +1,35 @@
+package dtos
+
+import "time"
+
+type TermResponseDTO struct {
+	Slug       string `json:"slug"`
+	Name       string `json:"name"`
+	MediaCount int64  `json:"media_count"`
+}
+
+type CreateGenreDTO struct {
+	Name string `json:"name"`
+}
+
+type SuggestTagDTO struct {
+	Name string `json:"name"`
+}
+
+// BrowseMediaResponseDTO - медиа в подборке по жанру или тегу.
+type BrowseMediaResponseDTO struct {
+	Media               MediaResponseDTO `json:"media"`
+	RecommendationCount int64            `json:"recommendation_count"`
+}
+
+type TagSuggestionResponseDTO struct {
+	ID         int        `json:"suggestion_id"`
+	MediaID    int        `json:"media_id"`
+	UserID     *int       `json:"user_id,omitempty"`
+	Name       string     `json:"name"`
+	Slug       string     `json:"slug"`
+	Status     string     `json:"status"`
+	ReviewerID *int       `json:"reviewer_id,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
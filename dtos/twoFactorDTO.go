@@ -0,0 +1,39 @@
+package dtos
+
+// LoginResponseDTO - результат входа. Если у пользователя включена 2FA, вместо
+// токена доступа выдается challenge_token для POST /login/2fa.
+type LoginResponseDTO struct {
+	Token             string `json:"token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+type TwoFactorLoginDTO struct {
+	ChallengeToken string `json:"challenge_token"`
+	// Code - код из приложения или один из кодов восстановления
+	Code string `json:"code"`
+}
+
+type TwoFactorCodeDTO struct {
+	Code string `json:"code"`
+}
+
+type DisableTwoFactorDTO struct {
+	// Password обязателен для аккаунтов со входом по паролю
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+type TwoFactorEnrollResponseDTO struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponseDTO struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorStatusResponseDTO struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
@@ -8,3 +8,16 @@ type UserResponseDTO struct {
 	Email     string    `json:"email"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// UserSummaryDTO - пользователь в чужих списках: только публичные поля.
+type UserSummaryDTO struct {
+	ID       int    `json:"user_id"`
+	UserName string `json:"user_name"`
+}
+
+// AccountDeletionResponseDTO - ответ на удаление аккаунта. До PurgeAfter
+// аккаунт можно восстановить, просто войдя в него.
+type AccountDeletionResponseDTO struct {
+	DeletedAt  time.Time `json:"deleted_at"`
+	PurgeAfter time.Time `json:"purge_after"`
+}
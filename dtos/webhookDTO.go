@@ -0,0 +1,51 @@
+package dtos
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// SaveWebhookRequestDTO - тело создания и изменения вебхука.
+type SaveWebhookRequestDTO struct {
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	// Active учитывается только при изменении; новый вебхук всегда включен
+	Active *bool `json:"active"`
+}
+
+type WebhookResponseDTO struct {
+	ID          int64    `json:"webhook_id"`
+	URL         string   `json:"url"`
+	Events      []string `json:"events"`
+	Description string   `json:"description"`
+	Active      bool     `json:"active"`
+	// Secret показывается один раз, в ответе на создание
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WebhookDeliveryResponseDTO struct {
+	ID             int64      `json:"delivery_id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	RedeliveryOf   *int64     `json:"redelivery_of,omitempty"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// WebhookPayloadDTO - тело запроса, который получает вебхук. ID события одинаков
+// во всех доставках и переотправках, по нему получатель отсеивает повторы.
+type WebhookPayloadDTO struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	UserID    int             `json:"user_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
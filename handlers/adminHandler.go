@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	s      *service.AdminService
+	logger *slog.Logger
+}
+
+func NewAdminHandler(s *service.AdminService, logger *slog.Logger) *AdminHandler {
+	return &AdminHandler{s: s, logger: logger}
+}
+
+// ListUsers - GET /admin/users?q=&role=&status=&page=&limit=
+func (h *AdminHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repo.UserFilter{
+		Query:  strings.TrimSpace(query.Get("q")),
+		Role:   models.Role(query.Get("role")),
+		Status: query.Get("status"),
+	}
+
+	users, err := h.s.ListUsers(r.Context(), filter, page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.AdminUserResponseDTO]{
+		Data:       make([]dtos.AdminUserResponseDTO, 0, len(users.Data)),
+		Total:      users.Total,
+		Page:       users.Page,
+		Limit:      users.Limit,
+		TotalPages: users.TotalPages,
+	}
+	for _, user := range users.Data {
+		response.Data = append(response.Data, adminUserToDTO(user))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.s.GetUser(r.Context(), userID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(adminUserToDTO(user))
+}
+
+func (h *AdminHandler) SuspendUser(w http.ResponseWriter, r *http.Request) {
+	actorID, actorRole, ok := currentActor(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	// Причина необязательна, поэтому пустое тело допустимо
+	var body dtos.SuspendUserDTO
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := h.s.SuspendUser(r.Context(), actorID, actorRole, userID, strings.TrimSpace(body.Reason)); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) UnsuspendUser(w http.ResponseWriter, r *http.Request) {
+	actorID, actorRole, ok := currentActor(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.UnsuspendUser(r.Context(), actorID, actorRole, userID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SetUserRole - PUT /admin/users/{userID}/role, доступен только администраторам.
+func (h *AdminHandler) SetUserRole(w http.ResponseWriter, r *http.Request) {
+	actorID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	var body dtos.SetUserRoleDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.SetUserRole(r.Context(), actorID, userID, models.Role(body.Role)); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) GetUserFollowers(w http.ResponseWriter, r *http.Request) {
+	h.listFollows(w, r, h.s.GetUserFollowers)
+}
+
+func (h *AdminHandler) GetUserFollowings(w http.ResponseWriter, r *http.Request) {
+	h.listFollows(w, r, h.s.GetUserFollowings)
+}
+
+func (h *AdminHandler) listFollows(w http.ResponseWriter, r *http.Request,
+	list func(ctx context.Context, userID, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error)) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	paginatedResponse, err := list(r.Context(), userID, page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(paginatedResponse)
+}
+
+// GetUserRecommendations - GET /admin/users/{userID}/recommendations?direction=sent|received
+func (h *AdminHandler) GetUserRecommendations(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return
+	}
+
+	recommendations, err := h.s.GetUserRecommendations(r.Context(), userID, r.URL.Query().Get("direction"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+	if recommendations == nil {
+		recommendations = []models.RecommendationDetails{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(recommendations)
+}
+
+func (h *AdminHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidRole), errors.Is(err, service.ErrInvalidUserStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrCannotModerateSelf), errors.Is(err, service.ErrInsufficientRole):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrUserAlreadySuspended), errors.Is(err, service.ErrUserNotSuspended):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Error("Admin operation failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func currentActor(r *http.Request) (int, models.Role, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		return 0, "", false
+	}
+	role, ok := middleware.GetRoleFromContext(r.Context())
+	return userID, role, ok
+}
+
+func adminUserToDTO(user models.User) dtos.AdminUserResponseDTO {
+	return dtos.AdminUserResponseDTO{
+		ID:               user.ID,
+		UserName:         user.UserName,
+		Email:            user.Email,
+		Role:             string(user.Role),
+		CreatedAt:        user.CreatedAt,
+		DeletedAt:        user.DeletedAt,
+		DeactivatedAt:    user.DeactivatedAt,
+		SuspendedAt:      user.SuspendedAt,
+		SuspensionReason: user.SuspensionReason,
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+)
+
+type AuditHandler struct {
+	s      *service.AuditService
+	logger *slog.Logger
+}
+
+func NewAuditHandler(s *service.AuditService, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{s: s, logger: logger}
+}
+
+// ListEvents - GET /admin/audit-events?user_id=&action=&from=&to=&page=&limit=
+// Границы интервала from/to передаются в RFC 3339.
+func (h *AuditHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repo.AuditFilter{Action: models.AuditAction(query.Get("action"))}
+	if userID := query.Get("user_id"); userID != "" {
+		if filter.UserID, err = strconv.Atoi(userID); err != nil {
+			http.Error(w, "invalid 'user_id' parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			http.Error(w, "invalid 'from' parameter: must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			http.Error(w, "invalid 'to' parameter: must be an RFC 3339 timestamp", http.StatusBadRequest)
+			return
+		}
+	}
+
+	events, err := h.s.ListEvents(r.Context(), filter, page, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTimeRange) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		h.logger.Error("Failed to list audit events", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.AuditEventResponseDTO]{
+		Data:       make([]dtos.AuditEventResponseDTO, 0, len(events.Data)),
+		Total:      events.Total,
+		Page:       events.Page,
+		Limit:      events.Limit,
+		TotalPages: events.TotalPages,
+	}
+	for _, event := range events.Data {
+		response.Data = append(response.Data, dtos.AuditEventResponseDTO{
+			ID:         event.ID,
+			OccurredAt: event.OccurredAt,
+			ActorID:    event.ActorID,
+			Action:     string(event.Action),
+			TargetType: event.TargetType,
+			TargetID:   event.TargetID,
+			IPAddress:  event.IPAddress,
+			RequestID:  event.RequestID,
+			Metadata:   event.Metadata,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type CircleHandler struct {
+	s      *service.CircleService
+	logger *slog.Logger
+}
+
+func NewCircleHandler(s *service.CircleService, logger *slog.Logger) *CircleHandler {
+	return &CircleHandler{s: s, logger: logger}
+}
+
+// CreateCircle - POST /circles
+func (h *CircleHandler) CreateCircle(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dtos.SaveCircleRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	circle, err := h.s.CreateCircle(r.Context(), currentUserID, req.Name, req.Description)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/circles/"+strconv.Itoa(circle.ID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(circleToDTO(circle, nil))
+}
+
+// GetMyCircles - GET /me/circles
+func (h *CircleHandler) GetMyCircles(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	circles, err := h.s.GetMyCircles(r.Context(), currentUserID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := make([]dtos.CircleResponseDTO, 0, len(circles))
+	for _, circle := range circles {
+		response = append(response, circleToDTO(circle, nil))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetCircle - GET /circles/{circleID}, круг с участниками
+func (h *CircleHandler) GetCircle(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+
+	circle, members, err := h.s.GetCircle(r.Context(), currentUserID, circleID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(circleToDTO(circle, members))
+}
+
+// UpdateCircle - PUT /circles/{circleID}
+func (h *CircleHandler) UpdateCircle(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.SaveCircleRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.UpdateCircle(r.Context(), currentUserID, circleID, req.Name, req.Description); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DeleteCircle - DELETE /circles/{circleID}
+func (h *CircleHandler) DeleteCircle(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.s.DeleteCircle(r.Context(), currentUserID, circleID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// AddMember - POST /circles/{circleID}/members
+func (h *CircleHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.AddCircleMemberRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.UserID <= 0 {
+		http.Error(w, "user_id must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.AddMember(r.Context(), currentUserID, circleID, req.UserID, models.CircleRole(req.Role)); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// SetMemberRole - PUT /circles/{circleID}/members/{userID}/role
+func (h *CircleHandler) SetMemberRole(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.SetCircleMemberRoleRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.SetMemberRole(r.Context(), currentUserID, circleID, userID, models.CircleRole(req.Role)); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveMember - DELETE /circles/{circleID}/members/{userID}; свой ID - выйти из круга
+func (h *CircleHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.Error(w, "invalid user id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.RemoveMember(r.Context(), currentUserID, circleID, userID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Recommend - POST /circles/{circleID}/recommendations
+func (h *CircleHandler) Recommend(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.CircleRecommendationRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.MediaID <= 0 {
+		http.Error(w, "media_id must be a positive integer", http.StatusBadRequest)
+		return
+	}
+
+	post, err := h.s.Recommend(r.Context(), currentUserID, circleID, req.MediaID, req.Note)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := circlePostToDTO(post)
+	response.RecipientCount = &post.RecipientCount
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetFeed - GET /circles/{circleID}/feed?page=&limit=
+func (h *CircleHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := h.s.GetFeed(r.Context(), currentUserID, circleID, page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.CirclePostResponseDTO]{
+		Data:       make([]dtos.CirclePostResponseDTO, 0, len(posts.Data)),
+		Total:      posts.Total,
+		Page:       posts.Page,
+		Limit:      posts.Limit,
+		TotalPages: posts.TotalPages,
+	}
+	for _, post := range posts.Data {
+		response.Data = append(response.Data, circlePostToDTO(post))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// React - PUT /circles/{circleID}/posts/{postID}/reaction
+func (h *CircleHandler) React(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid post id", http.StatusBadRequest)
+		return
+	}
+
+	var req dtos.SetCircleReactionRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.React(r.Context(), currentUserID, circleID, postID, models.CircleReaction(req.Reaction)); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Unreact - DELETE /circles/{circleID}/posts/{postID}/reaction
+func (h *CircleHandler) Unreact(w http.ResponseWriter, r *http.Request) {
+	currentUserID, circleID, ok := h.circleParams(w, r)
+	if !ok {
+		return
+	}
+	postID, err := strconv.ParseInt(chi.URLParam(r, "postID"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid post id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.Unreact(r.Context(), currentUserID, circleID, postID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// circleParams достает текущего пользователя и ID круга; при ошибке ответ уже записан.
+func (h *CircleHandler) circleParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return 0, 0, false
+	}
+
+	circleID, err := strconv.Atoi(chi.URLParam(r, "circleID"))
+	if err != nil {
+		http.Error(w, "invalid circle id", http.StatusBadRequest)
+		return 0, 0, false
+	}
+	return currentUserID, circleID, true
+}
+
+func circleToDTO(circle models.Circle, members []models.CircleMember) dtos.CircleResponseDTO {
+	response := dtos.CircleResponseDTO{
+		ID:          circle.ID,
+		Name:        circle.Name,
+		Description: circle.Description,
+		MemberCount: circle.MemberCount,
+		MyRole:      string(circle.MyRole),
+		CreatedAt:   circle.CreatedAt,
+	}
+	for _, member := range members {
+		response.Members = append(response.Members, dtos.CircleMemberDTO{
+			User:     dtos.UserSummaryDTO{ID: member.User.ID, UserName: member.User.UserName},
+			Role:     string(member.Role),
+			JoinedAt: member.JoinedAt,
+		})
+	}
+	return response
+}
+
+func circlePostToDTO(post models.CirclePost) dtos.CirclePostResponseDTO {
+	response := dtos.CirclePostResponseDTO{
+		ID:             post.ID,
+		Media:          mediaToDTO(post.Media),
+		Note:           post.Note,
+		CreatedAt:      post.CreatedAt,
+		Reactions:      make([]dtos.CircleReactionDTO, 0, len(post.Reactions)),
+		ReactionCounts: map[string]int{},
+	}
+	if post.Author.ID != 0 {
+		response.Author = &dtos.UserSummaryDTO{ID: post.Author.ID, UserName: post.Author.UserName}
+	}
+	for _, reaction := range post.Reactions {
+		response.Reactions = append(response.Reactions, dtos.CircleReactionDTO{
+			User:      dtos.UserSummaryDTO{ID: reaction.User.ID, UserName: reaction.User.UserName},
+			Reaction:  string(reaction.Reaction),
+			CreatedAt: reaction.CreatedAt,
+		})
+		response.ReactionCounts[string(reaction.Reaction)]++
+	}
+	return response
+}
+
+func (h *CircleHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidCircleName), errors.Is(err, service.ErrInvalidCircleNote),
+		errors.Is(err, service.ErrInvalidCircleRole), errors.Is(err, service.ErrInvalidReaction):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrCircleForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrCircleNotFound), errors.Is(err, service.ErrCircleMemberNotFound),
+		errors.Is(err, service.ErrCirclePostNotFound), errors.Is(err, service.ErrReactionNotFound),
+		errors.Is(err, service.ErrMediaNotFound), errors.Is(err, service.ErrTargetUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrAlreadyCircleMember), errors.Is(err, service.ErrCircleOwnerCannotLeave),
+		errors.Is(err, service.ErrCircleFull), errors.Is(err, service.ErrNotFriends),
+		errors.Is(err, service.ErrUserDeactivated):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Error("Failed to process circle request", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
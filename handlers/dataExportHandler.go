@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type DataExportHandler struct {
+	s      *service.DataExportService
+	logger *slog.Logger
+}
+
+func NewDataExportHandler(s *service.DataExportService, logger *slog.Logger) *DataExportHandler {
+	return &DataExportHandler{s: s, logger: logger}
+}
+
+// RequestExport ставит выгрузку данных в очередь. Архив собирается в фоне,
+// готовность проверяется через GetExport.
+func (h *DataExportHandler) RequestExport(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.s.RequestExport(r.Context(), currentUserID)
+	if err != nil {
+		h.logger.Error("Failed to request data export", "error", err, "user_id", currentUserID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/me/exports/"+export.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(export)
+}
+
+func (h *DataExportHandler) GetExport(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	export, err := h.s.GetExport(r.Context(), currentUserID, chi.URLParam(r, "exportID"))
+	if err != nil {
+		if errors.Is(err, service.ErrExportNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to get data export", "error", err, "user_id", currentUserID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(export)
+}
+
+// Download отдает архив по подписанной ссылке. Токен не нужен: ссылку можно
+// открыть прямо в браузере, а доступ ограничен подписью и сроком действия.
+func (h *DataExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	file, export, err := h.s.OpenDownload(r.Context(), chi.URLParam(r, "exportID"), query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidDownloadLink):
+			http.Error(w, err.Error(), http.StatusForbidden)
+		case errors.Is(err, service.ErrExportNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			h.logger.Error("Failed to open data export", "error", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer file.Close()
+
+	modTime := export.CreatedAt
+	if export.CompletedAt != nil {
+		modTime = *export.CompletedAt
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", `attachment; filename="recommendo-export-`+modTime.Format(time.DateOnly)+`.zip"`)
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.ServeContent(w, r, "", modTime, file)
+}
@@ -45,7 +45,14 @@ func (h *FollowHandler) CreateFollow(w http.ResponseWriter, r *http.Request) {
 
 	err = h.s.CreateFollow(r.Context(), currentUserID, requestBody.ToUserID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, service.ErrTargetUserNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		case errors.Is(err, service.ErrUserDeactivated):
+			http.Error(w, err.Error(), http.StatusConflict)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
@@ -69,7 +76,7 @@ func (h *FollowHandler) DeleteMyFollow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.s.DeleteFollow(r.Context(), currentUserID, targetUserID)
+	err = h.s.DeleteFollow(r.Context(), currentUserID, currentUserID, targetUserID)
 	if err != nil {
 		if errors.Is(err, service.ErrFollowNotFound) {
 			// Если пользователь пытается удалить подписчика, которого нет
@@ -100,7 +107,7 @@ func (h *FollowHandler) DeleteMeFollow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.s.DeleteFollow(r.Context(), targetUserID, currentUserID)
+	err = h.s.DeleteFollow(r.Context(), currentUserID, targetUserID, currentUserID)
 	if err != nil {
 		if errors.Is(err, service.ErrFollowNotFound) {
 			// Если пользователь пытается удалить подписчика, которого нет
@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+)
+
+// Pinger - то, что умеет проверить соединение с базой (например, *sql.DB).
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+type HealthHandler struct {
+	db       Pinger
+	draining atomic.Bool
+	logger   *slog.Logger
+}
+
+func NewHealthHandler(db Pinger, logger *slog.Logger) *HealthHandler {
+	return &HealthHandler{db: db, logger: logger}
+}
+
+// SetDraining переводит сервис в режим остановки: /readyz начинает отвечать 503,
+// чтобы балансировщик перестал присылать новые запросы.
+func (h *HealthHandler) SetDraining() {
+	h.draining.Store(true)
+}
+
+// Liveness: процесс жив и обрабатывает запросы. Зависимости не проверяются.
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.StatusResponseDTO{Status: "ok"})
+}
+
+// Readiness: сервис готов принимать трафик, база доступна.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if h.draining.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(dtos.StatusResponseDTO{Status: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := h.db.PingContext(ctx); err != nil {
+		h.logger.Warn("Readiness check failed", "error", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(dtos.StatusResponseDTO{Status: "database unavailable"})
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.StatusResponseDTO{Status: "ready"})
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cobrich/recommendo/jwt"
+)
+
+type JWKSHandler struct {
+	tokens *jwt.TokenManager
+}
+
+func NewJWKSHandler(tokens *jwt.TokenManager) *JWKSHandler {
+	return &JWKSHandler{tokens: tokens}
+}
+
+// GetJWKS отдает публичные ключи подписи, чтобы другие сервисы могли проверять наши токены.
+func (h *JWKSHandler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	// Клиенты могут кэшировать набор ключей, но недолго: после ротации появится новый kid
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.tokens.JWKS())
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type LibraryHandler struct {
+	s      *service.LibraryService
+	logger *slog.Logger
+}
+
+func NewLibraryHandler(s *service.LibraryService, logger *slog.Logger) *LibraryHandler {
+	return &LibraryHandler{s: s, logger: logger}
+}
+
+// GetMyLibrary - GET /me/library?status=&page=&limit=
+func (h *LibraryHandler) GetMyLibrary(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := models.LibraryStatus(r.URL.Query().Get("status"))
+	entries, err := h.s.GetLibrary(r.Context(), currentUserID, status, page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.LibraryEntryResponseDTO]{
+		Data:       make([]dtos.LibraryEntryResponseDTO, 0, len(entries.Data)),
+		Total:      entries.Total,
+		Page:       entries.Page,
+		Limit:      entries.Limit,
+		TotalPages: entries.TotalPages,
+	}
+	for _, entry := range entries.Data {
+		response.Data = append(response.Data, libraryEntryToDTO(entry))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *LibraryHandler) GetMyLibraryEntry(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "invalid media id", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.s.GetEntry(r.Context(), currentUserID, mediaID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(libraryEntryToDTO(entry))
+}
+
+// SaveMyLibraryEntry - PUT /me/library/{mediaID}, добавляет медиа в библиотеку или обновляет прогресс.
+func (h *LibraryHandler) SaveMyLibraryEntry(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "invalid media id", http.StatusBadRequest)
+		return
+	}
+
+	var body dtos.SaveLibraryEntryDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	entry, err := h.s.SaveEntry(r.Context(), currentUserID, mediaID, body)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(libraryEntryToDTO(entry))
+}
+
+func (h *LibraryHandler) DeleteMyLibraryEntry(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "invalid media id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.s.RemoveEntry(r.Context(), currentUserID, mediaID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *LibraryHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidLibraryStatus),
+		errors.Is(err, service.ErrInvalidProgress),
+		errors.Is(err, service.ErrInvalidScore),
+		errors.Is(err, service.ErrInvalidLibraryDate),
+		errors.Is(err, service.ErrInvalidLibraryDates):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrMediaNotFound), errors.Is(err, service.ErrLibraryEntryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		h.logger.Error("Library operation failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func libraryEntryToDTO(entry models.UserMedia) dtos.LibraryEntryResponseDTO {
+	return dtos.LibraryEntryResponseDTO{
+		Media:        mediaToDTO(entry.Media),
+		Status:       string(entry.Status),
+		Progress:     entry.Progress,
+		ProgressUnit: entry.Media.Type.ProgressUnit(),
+		StartedOn:    formatDate(entry.StartedOn),
+		FinishedOn:   formatDate(entry.FinishedOn),
+		Score:        entry.Score,
+		CreatedAt:    entry.CreatedAt,
+		UpdatedAt:    entry.UpdatedAt,
+	}
+}
+
+func formatDate(date *time.Time) *string {
+	if date == nil {
+		return nil
+	}
+	formatted := date.Format(time.DateOnly)
+	return &formatted
+}
@@ -2,10 +2,16 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
 
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
 	"github.com/cobrich/recommendo/service"
+	"github.com/go-chi/chi/v5"
 )
 
 type MediaHandler struct {
@@ -45,3 +51,152 @@ func (h *MediaHandler) GetMedia(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 }
+
+// GetMediaDetails - GET /media/{mediaID}, медиа с контекстом текущего пользователя.
+func (h *MediaHandler) GetMediaDetails(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "invalid media id", http.StatusBadRequest)
+		return
+	}
+
+	details, err := h.s.GetMediaDetails(r.Context(), currentUserID, mediaID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.MediaDetailsResponseDTO{
+		Media: mediaToDTO(details.Media),
+		Ratings: dtos.MediaRatingsDTO{
+			AverageScore:   details.Ratings.AverageScore,
+			ScoreCount:     details.Ratings.ScoreCount,
+			LibraryCount:   details.Ratings.LibraryCount,
+			CompletedCount: details.Ratings.CompletedCount,
+		},
+		RecommendedToCount:    details.RecommendedToCount,
+		FriendRecommendations: mediaRecommendationsToDTO(details.FriendRecommendations),
+		SentByMe:              mediaRecommendationsToDTO(details.SentByMe),
+		ReceivedByMe:          mediaRecommendationsToDTO(details.ReceivedByMe),
+	}
+	if entry := details.Library; entry != nil {
+		response.Library = &dtos.MediaLibraryStateDTO{
+			Status:       string(entry.Status),
+			Progress:     entry.Progress,
+			ProgressUnit: details.Media.Type.ProgressUnit(),
+			StartedOn:    formatDate(entry.StartedOn),
+			FinishedOn:   formatDate(entry.FinishedOn),
+			Score:        entry.Score,
+			UpdatedAt:    entry.UpdatedAt,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateMedia - POST /admin/media, добавить медиа в каталог.
+func (h *MediaHandler) CreateMedia(w http.ResponseWriter, r *http.Request) {
+	var body dtos.SaveMediaDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	media, err := h.s.CreateMedia(r.Context(), body)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(mediaToDTO(media))
+}
+
+// UpdateMedia - PUT /admin/media/{mediaID}, заменить сведения о медиа целиком.
+func (h *MediaHandler) UpdateMedia(w http.ResponseWriter, r *http.Request) {
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "invalid media id", http.StatusBadRequest)
+		return
+	}
+
+	var body dtos.SaveMediaDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	media, err := h.s.UpdateMedia(r.Context(), mediaID, body)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mediaToDTO(media))
+}
+
+func (h *MediaHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidMediaType), errors.Is(err, service.ErrInvalidMediaMetadata):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrMediaNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		h.logger.Error("Media operation failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func mediaToDTO(media models.MediaItem) dtos.MediaResponseDTO {
+	response := dtos.MediaResponseDTO{
+		ID:                media.ID,
+		Type:              string(media.Type),
+		Name:              media.Name,
+		Year:              media.Year,
+		Author:            media.Author,
+		OriginalTitle:     media.OriginalTitle,
+		AlternativeTitles: media.Metadata.AlternativeTitles,
+		Description:       media.Description,
+		CoverURL:          media.CoverURL,
+		Genres:            media.Genres,
+		Tags:              media.Tags,
+		RuntimeMinutes:    media.Metadata.RuntimeMinutes,
+		EpisodeCount:      media.Metadata.EpisodeCount,
+		PageCount:         media.Metadata.PageCount,
+		Platforms:         media.Metadata.Platforms,
+		Creators:          make([]dtos.MediaCreatorDTO, 0, len(media.Creators)),
+		ExternalIDs:       media.Metadata.ExternalIDs,
+	}
+	for _, creator := range media.Creators {
+		response.Creators = append(response.Creators, dtos.MediaCreatorDTO{Name: creator.Name, Role: string(creator.Role)})
+	}
+	return response
+}
+
+func mediaRecommendationsToDTO(recommendations []models.MediaRecommendation) []dtos.MediaRecommendationDTO {
+	response := make([]dtos.MediaRecommendationDTO, 0, len(recommendations))
+	for _, rec := range recommendations {
+		item := dtos.MediaRecommendationDTO{
+			ID:        rec.RecommendationID,
+			To:        dtos.UserSummaryDTO{ID: rec.To.ID, UserName: rec.To.UserName},
+			Status:    string(rec.Status),
+			CreatedAt: rec.CreatedAt,
+		}
+		if rec.From.ID != 0 {
+			item.From = &dtos.UserSummaryDTO{ID: rec.From.ID, UserName: rec.From.UserName}
+		}
+		response = append(response, item)
+	}
+	return response
+}
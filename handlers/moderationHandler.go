@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type ModerationHandler struct {
+	s      *service.ModerationService
+	logger *slog.Logger
+}
+
+func NewModerationHandler(s *service.ModerationService, logger *slog.Logger) *ModerationHandler {
+	return &ModerationHandler{s: s, logger: logger}
+}
+
+// CreateReport - POST /reports, жалоба от любого пользователя.
+func (h *ModerationHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var body dtos.CreateReportDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	body.Details = strings.TrimSpace(body.Details)
+
+	report, err := h.s.CreateReport(r.Context(), currentUserID, body)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(reportToDTO(report))
+}
+
+// ListReports - GET /admin/reports?status=&target_type=&assignee_id=&page=&limit=
+func (h *ModerationHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	filter := repo.ReportFilter{
+		Status:     models.ReportStatus(query.Get("status")),
+		TargetType: models.ReportTargetType(query.Get("target_type")),
+	}
+	if assignee := query.Get("assignee_id"); assignee != "" {
+		filter.AssigneeID, err = strconv.Atoi(assignee)
+		if err != nil {
+			http.Error(w, "invalid assignee_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	reports, err := h.s.ListReports(r.Context(), filter, page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.ReportResponseDTO]{
+		Data:       make([]dtos.ReportResponseDTO, 0, len(reports.Data)),
+		Total:      reports.Total,
+		Page:       reports.Page,
+		Limit:      reports.Limit,
+		TotalPages: reports.TotalPages,
+	}
+	for _, report := range reports.Data {
+		response.Data = append(response.Data, reportToDTO(report))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ModerationHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		http.Error(w, "invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	report, actions, err := h.s.GetReport(r.Context(), reportID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.ReportDetailsResponseDTO{
+		ReportResponseDTO: reportToDTO(report),
+		Actions:           make([]dtos.ModerationActionResponseDTO, 0, len(actions)),
+	}
+	for _, action := range actions {
+		response.Actions = append(response.Actions, dtos.ModerationActionResponseDTO{
+			ModeratorID: action.ModeratorID,
+			Action:      string(action.Action),
+			TargetType:  string(action.TargetType),
+			TargetID:    action.TargetID,
+			Note:        action.Note,
+			CreatedAt:   action.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *ModerationHandler) AssignReport(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		http.Error(w, "invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	// Пустое тело - взять жалобу себе
+	var body dtos.AssignReportDTO
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.s.AssignReport(r.Context(), currentUserID, reportID, body.AssigneeID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reportToDTO(report))
+}
+
+func (h *ModerationHandler) ResolveReport(w http.ResponseWriter, r *http.Request) {
+	actorID, actorRole, ok := currentActor(r)
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		http.Error(w, "invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	var body dtos.ResolveReportDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.s.ResolveReport(r.Context(), actorID, actorRole, reportID, body.Action, strings.TrimSpace(body.Note))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reportToDTO(report))
+}
+
+func (h *ModerationHandler) DismissReport(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	reportID, err := strconv.Atoi(chi.URLParam(r, "reportID"))
+	if err != nil {
+		http.Error(w, "invalid report id", http.StatusBadRequest)
+		return
+	}
+
+	var body dtos.DismissReportDTO
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.s.DismissReport(r.Context(), currentUserID, reportID, strings.TrimSpace(body.Note))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reportToDTO(report))
+}
+
+func (h *ModerationHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidReportTarget),
+		errors.Is(err, service.ErrInvalidReportReason),
+		errors.Is(err, service.ErrInvalidReportStatus),
+		errors.Is(err, service.ErrReportDetailsTooLong),
+		errors.Is(err, service.ErrCannotReportSelf),
+		errors.Is(err, service.ErrInvalidAssignee),
+		errors.Is(err, service.ErrInvalidReportAction):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrReportTargetNotFound),
+		errors.Is(err, service.ErrReportNotFound),
+		errors.Is(err, service.ErrRecommendationNotFound),
+		errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrAlreadyReported),
+		errors.Is(err, service.ErrReportClosed),
+		errors.Is(err, service.ErrUserAlreadySuspended):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrCannotModerateSelf), errors.Is(err, service.ErrInsufficientRole):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	default:
+		h.logger.Error("Moderation operation failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func reportToDTO(report models.Report) dtos.ReportResponseDTO {
+	return dtos.ReportResponseDTO{
+		ID:             report.ID,
+		ReporterID:     report.ReporterID,
+		TargetType:     string(report.TargetType),
+		TargetID:       report.TargetID,
+		Reason:         string(report.Reason),
+		Details:        report.Details,
+		Status:         string(report.Status),
+		AssigneeID:     report.AssigneeID,
+		ResolutionNote: report.ResolutionNote,
+		CreatedAt:      report.CreatedAt,
+		ClosedAt:       report.ClosedAt,
+	}
+}
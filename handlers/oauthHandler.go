@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/oauth"
+	"github.com/cobrich/recommendo/service"
+	"github.com/go-chi/chi/v5"
+)
+
+const oauthStateCookie = "oauth_state"
+
+type OAuthHandler struct {
+	s         *service.IdentityService
+	providers *oauth.Registry
+	states    *oauth.StateCodec
+	secure    bool
+	logger    *slog.Logger
+}
+
+// secureCookies включает флаг Secure у cookie состояния (нужно, когда API доступен по https).
+func NewOAuthHandler(s *service.IdentityService, providers *oauth.Registry, states *oauth.StateCodec, secureCookies bool, logger *slog.Logger) *OAuthHandler {
+	return &OAuthHandler{s: s, providers: providers, states: states, secure: secureCookies, logger: logger}
+}
+
+// StartLogin перенаправляет пользователя на страницу входа провайдера.
+func (h *OAuthHandler) StartLogin(w http.ResponseWriter, r *http.Request) {
+	authURL, err := h.startFlow(w, r, 0)
+	if err != nil {
+		h.writeStartError(w, err)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// StartLink начинает привязку провайдера к текущему пользователю. Возвращает адрес,
+// на который фронтенд должен перейти (редирект из XHR-запроса браузер бы не показал).
+func (h *OAuthHandler) StartLink(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	authURL, err := h.startFlow(w, r, currentUserID)
+	if err != nil {
+		h.writeStartError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.AuthorizationURLResponseDTO{AuthorizationURL: authURL})
+}
+
+// startFlow создает состояние входа, кладет его в подписанную cookie
+// и возвращает адрес страницы провайдера.
+func (h *OAuthHandler) startFlow(w http.ResponseWriter, r *http.Request, linkUserID int) (string, error) {
+	provider, err := h.providers.Get(chi.URLParam(r, "provider"))
+	if err != nil {
+		return "", err
+	}
+
+	state, err := h.states.NewState(provider.Name(), linkUserID)
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(r.Context(), state.State, state.Nonce, state.Verifier)
+	if err != nil {
+		return "", err
+	}
+
+	cookieValue, err := h.states.Encode(state)
+	if err != nil {
+		return "", err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    cookieValue,
+		Path:     "/auth/",
+		Expires:  state.ExpiresAt,
+		HttpOnly: true,
+		Secure:   h.secure,
+		// Lax нужен, чтобы cookie пришла с редиректом от провайдера
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return authURL, nil
+}
+
+func (h *OAuthHandler) writeStartError(w http.ResponseWriter, err error) {
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	h.logger.Error("Failed to start external login", "error", err)
+	http.Error(w, "Login provider is unavailable", http.StatusBadGateway)
+}
+
+// Callback принимает пользователя после входа у провайдера: выдает токен
+// или завершает привязку, если вход был начат через StartLink.
+func (h *OAuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	provider, err := h.providers.Get(chi.URLParam(r, "provider"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// 1. Cookie одноразовая: удаляем ее при любом исходе
+	cookie, err := r.Cookie(oauthStateCookie)
+	http.SetCookie(w, &http.Cookie{Name: oauthStateCookie, Path: "/auth/", MaxAge: -1, Expires: time.Unix(0, 0), HttpOnly: true, Secure: h.secure})
+	if err != nil {
+		http.Error(w, oauth.ErrInvalidState.Error(), http.StatusBadRequest)
+		return
+	}
+
+	state, err := h.states.Decode(cookie.Value)
+	if err != nil || state.Provider != provider.Name() || state.State != r.URL.Query().Get("state") {
+		http.Error(w, oauth.ErrInvalidState.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// 2. Провайдер мог вернуть ошибку вместо кода (например, пользователь отказался)
+	if providerErr := r.URL.Query().Get("error"); providerErr != "" {
+		http.Error(w, "Login was cancelled: "+providerErr, http.StatusUnauthorized)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), r.URL.Query().Get("code"), state.Nonce, state.Verifier)
+	if err != nil {
+		h.logger.Warn("External login failed", "error", err, "provider", provider.Name())
+		http.Error(w, "External login failed", http.StatusUnauthorized)
+		return
+	}
+
+	// 3. Привязка к существующему аккаунту
+	if state.LinkUserID != 0 {
+		linked, err := h.s.LinkIdentity(r.Context(), state.LinkUserID, identity)
+		if err != nil {
+			h.writeIdentityError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(identityToDTO(linked))
+		return
+	}
+
+	// 4. Вход (и регистрация при первом входе)
+	// Если у пользователя включена 2FA, вместо токена придет challenge_token
+	loginResponse, err := h.s.LoginWithIdentity(r.Context(), identity)
+	if err != nil {
+		h.writeIdentityError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(loginResponse)
+}
+
+func (h *OAuthHandler) writeIdentityError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrIdentityAlreadyLinked),
+		errors.Is(err, service.ErrProviderAlreadyLinked),
+		errors.Is(err, service.ErrIdentityEmailTaken),
+		errors.Is(err, service.ErrLastLoginMethod):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrUserSuspended):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrIdentityEmailUnverified):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrIdentityNotFound), errors.Is(err, service.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		h.logger.Error("External identity operation failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *OAuthHandler) GetCurrentUserIdentities(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	identities, err := h.s.GetUserIdentities(r.Context(), currentUserID)
+	if err != nil {
+		h.writeIdentityError(w, err)
+		return
+	}
+
+	response := make([]dtos.IdentityResponseDTO, 0, len(identities))
+	for _, identity := range identities {
+		response = append(response, identityToDTO(identity))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *OAuthHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.s.UnlinkIdentity(r.Context(), currentUserID, chi.URLParam(r, "provider")); err != nil {
+		h.writeIdentityError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func identityToDTO(identity models.UserIdentity) dtos.IdentityResponseDTO {
+	return dtos.IdentityResponseDTO{
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt,
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+)
+
+type RankingHandler struct {
+	s      *service.RankingService
+	logger *slog.Logger
+}
+
+func NewRankingHandler(s *service.RankingService, logger *slog.Logger) *RankingHandler {
+	return &RankingHandler{s: s, logger: logger}
+}
+
+// GetTrending - GET /media/trending?window=24h|7d|30d&type=&page=&limit=
+func (h *RankingHandler) GetTrending(w http.ResponseWriter, r *http.Request) {
+	h.getRanking(w, r, models.RankingTrending)
+}
+
+// GetPopular - GET /media/popular?window=24h|7d|30d|all&type=&page=&limit=
+func (h *RankingHandler) GetPopular(w http.ResponseWriter, r *http.Request) {
+	h.getRanking(w, r, models.RankingPopular)
+}
+
+func (h *RankingHandler) getRanking(w http.ResponseWriter, r *http.Request, kind models.RankingKind) {
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	window := models.RankingWindow(query.Get("window"))
+	if window == "" {
+		window = service.DefaultRankingWindow
+	}
+	items, computedAt, err := h.s.GetRanking(r.Context(), kind, window, models.MediaType(query.Get("type")), page, limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRankingWindow), errors.Is(err, service.ErrInvalidMediaType):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			h.logger.Error("Failed to get media ranking", "error", err, "ranking", kind)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	response := dtos.RankingResponseDTO{
+		PaginatedResponseDTO: dtos.PaginatedResponseDTO[dtos.RankedMediaDTO]{
+			Data:       make([]dtos.RankedMediaDTO, 0, len(items.Data)),
+			Total:      items.Total,
+			Page:       items.Page,
+			Limit:      items.Limit,
+			TotalPages: items.TotalPages,
+		},
+		Ranking:    string(kind),
+		Window:     string(window),
+		ComputedAt: computedAt,
+	}
+	for _, item := range items.Data {
+		response.Data = append(response.Data, dtos.RankedMediaDTO{
+			Rank:                item.Rank,
+			Media:               mediaToDTO(item.Media),
+			Score:               item.Score,
+			RecommendationCount: item.RecommendationCount,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
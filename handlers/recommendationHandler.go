@@ -1,14 +1,19 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/cobrich/recommendo/dtos"
 	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
 	"github.com/cobrich/recommendo/service"
 	"github.com/go-chi/chi/v5"
 )
@@ -53,7 +58,8 @@ func (h *RecommendationHandler) CreateRecommendation(w http.ResponseWriter, r *h
 		case errors.Is(err, service.ErrTargetUserNotFound) || errors.Is(err, service.ErrMediaNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound) // 404 Not Found
 			return
-		case errors.Is(err, service.ErrNotFriends) || errors.Is(err, service.ErrAlreadyRecommended):
+		case errors.Is(err, service.ErrNotFriends) || errors.Is(err, service.ErrAlreadyRecommended) || errors.Is(err, service.ErrUserDeactivated) ||
+			errors.Is(err, service.ErrMediaOptedOut):
 			http.Error(w, err.Error(), http.StatusConflict) // 409 Conflict
 			return
 		default:
@@ -70,6 +76,38 @@ func (h *RecommendationHandler) CreateRecommendation(w http.ResponseWriter, r *h
 	json.NewEncoder(w).Encode(map[string]string{"status": "recommendation created successfully"})
 }
 
+// parseRecommendationFilter разбирает фильтры списка рекомендаций:
+// ?type=&user_id=&from=&to=&status=&sort=date|name|year&order=asc|desc, from/to - в RFC 3339.
+func parseRecommendationFilter(r *http.Request) (repo.RecommendationFilter, error) {
+	query := r.URL.Query()
+	filter := repo.RecommendationFilter{
+		MediaType: models.MediaType(query.Get("type")),
+		Status:    models.RecommendationStatus(query.Get("status")),
+		Sort:      query.Get("sort"),
+		Order:     query.Get("order"),
+	}
+
+	var err error
+	if userID := query.Get("user_id"); userID != "" {
+		if filter.UserID, err = strconv.Atoi(userID); err != nil || filter.UserID <= 0 {
+			return filter, errors.New("invalid 'user_id' parameter")
+		}
+	}
+	if from := query.Get("from"); from != "" {
+		if filter.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return filter, errors.New("invalid 'from' parameter: must be an RFC 3339 timestamp")
+		}
+	}
+	if to := query.Get("to"); to != "" {
+		if filter.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return filter, errors.New("invalid 'to' parameter: must be an RFC 3339 timestamp")
+		}
+	}
+	return filter, nil
+}
+
+// GetCurrentUserRecommendations - GET /me/recommendations?direction=sent|received&state=
+// плюс фильтры и сортировка из parseRecommendationFilter.
 func (h *RecommendationHandler) GetCurrentUserRecommendations(w http.ResponseWriter, r *http.Request) {
 	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
@@ -79,12 +117,19 @@ func (h *RecommendationHandler) GetCurrentUserRecommendations(w http.ResponseWri
 		return
 	}
 
-	direction := r.URL.Query().Get("direction")
-	recommendations, err := h.s.GetRecommendations(r.Context(), currentUserID, direction)
+	filter, err := parseRecommendationFilter(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	filter.State = models.InboxState(r.URL.Query().Get("state"))
+
+	direction := r.URL.Query().Get("direction")
+	recommendations, err := h.s.GetMyRecommendations(r.Context(), currentUserID, direction, filter)
+	if err != nil {
+		h.writeError(w, err, 0)
+		return
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 
@@ -106,13 +151,19 @@ func (h *RecommendationHandler) GetUserRecommendations(w http.ResponseWriter, r
 		return
 	}
 	
-	direction := r.URL.Query().Get("direction")
-	recommendations, err := h.s.GetRecommendations(r.Context(), userID, direction)
+	filter, err := parseRecommendationFilter(r)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	direction := r.URL.Query().Get("direction")
+	recommendations, err := h.s.GetRecommendations(r.Context(), userID, direction, filter)
+	if err != nil {
+		h.writeError(w, err, 0)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 
 	if len(recommendations) == 0 {
@@ -126,6 +177,8 @@ func (h *RecommendationHandler) GetUserRecommendations(w http.ResponseWriter, r
 	}
 }
 
+// DeleteRecommendation - DELETE /me/recommendations/{recommendation_id}
+// Автор удаляет рекомендацию, получатель - скрывает у себя.
 func (h *RecommendationHandler) DeleteRecommendation(w http.ResponseWriter, r *http.Request) {
 	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
 	if !ok {
@@ -143,7 +196,7 @@ func (h *RecommendationHandler) DeleteRecommendation(w http.ResponseWriter, r *h
 	err = h.s.DeleteRecommendation(r.Context(), currentUserID, recomID)
 	if err != nil {
 		switch {
-		case errors.Is(err, service.ErrUserNotAuthor):
+		case errors.Is(err, service.ErrRecommendationNotFound):
 			http.Error(w, err.Error(), http.StatusNotFound)
 			return
 		case errors.Is(err, service.ErrUserNotAuthor):
@@ -158,3 +211,220 @@ func (h *RecommendationHandler) DeleteRecommendation(w http.ResponseWriter, r *h
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// GetUnreadCount - GET /me/recommendations/unread-count
+func (h *RecommendationHandler) GetUnreadCount(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	unread, err := h.s.CountUnread(r.Context(), currentUserID)
+	if err != nil {
+		h.writeError(w, err, 0)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.UnreadCountResponseDTO{Unread: unread})
+}
+
+// MarkRead - POST /me/recommendations/read
+func (h *RecommendationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dtos.MarkRecommendationsReadRequestDTO
+	// Пустое тело - отметить все
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	updated, err := h.s.MarkRead(r.Context(), currentUserID, req.RecommendationIDs)
+	if err != nil {
+		h.writeError(w, err, 0)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.MarkRecommendationsReadResponseDTO{Updated: updated})
+}
+
+// MarkUnread - POST /me/recommendations/{recommendation_id}/unread
+func (h *RecommendationHandler) MarkUnread(w http.ResponseWriter, r *http.Request) {
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.MarkUnread(r.Context(), userID, recomID)
+	})
+}
+
+// Archive - POST /me/recommendations/{recommendation_id}/archive
+func (h *RecommendationHandler) Archive(w http.ResponseWriter, r *http.Request) {
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.SetArchived(r.Context(), userID, recomID, true)
+	})
+}
+
+// Unarchive - DELETE /me/recommendations/{recommendation_id}/archive
+func (h *RecommendationHandler) Unarchive(w http.ResponseWriter, r *http.Request) {
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.SetArchived(r.Context(), userID, recomID, false)
+	})
+}
+
+// Snooze - POST /me/recommendations/{recommendation_id}/snooze
+func (h *RecommendationHandler) Snooze(w http.ResponseWriter, r *http.Request) {
+	var req dtos.SnoozeRecommendationRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.Snooze(r.Context(), userID, recomID, &req.Until)
+	})
+}
+
+// Unsnooze - DELETE /me/recommendations/{recommendation_id}/snooze
+func (h *RecommendationHandler) Unsnooze(w http.ResponseWriter, r *http.Request) {
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.Snooze(r.Context(), userID, recomID, nil)
+	})
+}
+
+// Hide - POST /me/recommendations/{recommendation_id}/hide
+func (h *RecommendationHandler) Hide(w http.ResponseWriter, r *http.Request) {
+	var req dtos.DismissRecommendationRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.Hide(r.Context(), userID, recomID, req.DontRecommendAgain)
+	})
+}
+
+// Unhide - DELETE /me/recommendations/{recommendation_id}/hide
+func (h *RecommendationHandler) Unhide(w http.ResponseWriter, r *http.Request) {
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.Unhide(r.Context(), userID, recomID)
+	})
+}
+
+// Decline - POST /me/recommendations/{recommendation_id}/decline
+func (h *RecommendationHandler) Decline(w http.ResponseWriter, r *http.Request) {
+	var req dtos.DismissRecommendationRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	h.updateInbox(w, r, func(userID, recomID int) error {
+		return h.s.Decline(r.Context(), userID, recomID, req.DontRecommendAgain)
+	})
+}
+
+// GetOptOuts - GET /me/recommendation-opt-outs
+func (h *RecommendationHandler) GetOptOuts(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	optOuts, err := h.s.GetOptOuts(r.Context(), currentUserID)
+	if err != nil {
+		h.writeError(w, err, 0)
+		return
+	}
+
+	response := make([]dtos.RecommendationOptOutResponseDTO, 0, len(optOuts))
+	for _, optOut := range optOuts {
+		response = append(response, dtos.RecommendationOptOutResponseDTO{
+			Media:     mediaToDTO(optOut.Media),
+			CreatedAt: optOut.CreatedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// AddOptOut - PUT /me/recommendation-opt-outs/{mediaID}
+func (h *RecommendationHandler) AddOptOut(w http.ResponseWriter, r *http.Request) {
+	h.updateOptOut(w, r, h.s.AddOptOut)
+}
+
+// RemoveOptOut - DELETE /me/recommendation-opt-outs/{mediaID}
+func (h *RecommendationHandler) RemoveOptOut(w http.ResponseWriter, r *http.Request) {
+	h.updateOptOut(w, r, h.s.RemoveOptOut)
+}
+
+func (h *RecommendationHandler) updateOptOut(w http.ResponseWriter, r *http.Request, update func(ctx context.Context, userID, mediaID int) error) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "invalid media id", http.StatusBadRequest)
+		return
+	}
+
+	if err := update(r.Context(), currentUserID, mediaID); err != nil {
+		h.writeError(w, err, 0)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateInbox разбирает ID рекомендации и меняет ее состояние во входящих текущего пользователя.
+func (h *RecommendationHandler) updateInbox(w http.ResponseWriter, r *http.Request, update func(userID, recomID int) error) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	recomID, err := strconv.Atoi(chi.URLParam(r, "recommendation_id"))
+	if err != nil {
+		http.Error(w, "invalid recommendation id", http.StatusBadRequest)
+		return
+	}
+
+	if err := update(currentUserID, recomID); err != nil {
+		h.writeError(w, err, recomID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *RecommendationHandler) writeError(w http.ResponseWriter, err error, recomID int) {
+	switch {
+	case errors.Is(err, service.ErrInvalidInboxState), errors.Is(err, service.ErrInboxStateForSent),
+		errors.Is(err, service.ErrInvalidSnooze), errors.Is(err, service.ErrTooManyRecommendationIDs),
+		errors.Is(err, service.ErrInvalidMediaType), errors.Is(err, service.ErrInvalidRecommendationStatus),
+		errors.Is(err, service.ErrInvalidRecommendationSort), errors.Is(err, service.ErrInvalidTimeRange):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrRecommendationNotFound), errors.Is(err, service.ErrMediaNotFound),
+		errors.Is(err, service.ErrOptOutNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrCannotDecline):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Error("Failed to process recommendations request", "error", err, "recommendationID", recomID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type SessionHandler struct {
+	s      *service.SessionService
+	logger *slog.Logger
+}
+
+func NewSessionHandler(s *service.SessionService, logger *slog.Logger) *SessionHandler {
+	return &SessionHandler{s: s, logger: logger}
+}
+
+func (h *SessionHandler) GetCurrentUserSessions(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentSessionID, _ := middleware.GetSessionIDFromContext(r.Context())
+
+	sessions, err := h.s.GetSessions(r.Context(), currentUserID)
+	if err != nil {
+		h.logger.Error("Failed to get sessions", "error", err, "user_id", currentUserID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	response := make([]dtos.SessionResponseDTO, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, dtos.SessionResponseDTO{
+			ID:         session.ID,
+			Device:     session.Device,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeSession завершает сессию по ID. Для текущей сессии это выход из аккаунта.
+func (h *SessionHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.s.RevokeSession(r.Context(), currentUserID, chi.URLParam(r, "sessionID")); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to revoke session", "error", err, "user_id", currentUserID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeOtherSessions выходит со всех устройств, кроме текущего.
+func (h *SessionHandler) RevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	currentSessionID, _ := middleware.GetSessionIDFromContext(r.Context())
+
+	revoked, err := h.s.RevokeOtherSessions(r.Context(), currentUserID, currentSessionID)
+	if err != nil {
+		h.logger.Error("Failed to revoke sessions", "error", err, "user_id", currentUserID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.RevokedSessionsResponseDTO{Revoked: revoked})
+}
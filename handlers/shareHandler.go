@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type ShareHandler struct {
+	s      *service.ShareService
+	logger *slog.Logger
+}
+
+func NewShareHandler(s *service.ShareService, logger *slog.Logger) *ShareHandler {
+	return &ShareHandler{s: s, logger: logger}
+}
+
+// CreateShareLink - POST /me/shares
+func (h *ShareHandler) CreateShareLink(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dtos.CreateShareLinkRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	link, err := h.s.CreateShareLink(r.Context(), currentUserID, req.MediaIDs, req.Note, req.PromptFollow, req.ExpiresAt)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := h.shareLinkToDTO(link)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", response.URL)
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetMyShareLinks - GET /me/shares
+func (h *ShareHandler) GetMyShareLinks(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	links, err := h.s.GetShareLinks(r.Context(), currentUserID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := make([]dtos.ShareLinkResponseDTO, 0, len(links))
+	for _, link := range links {
+		response = append(response, h.shareLinkToDTO(link))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// RevokeShareLink - DELETE /me/shares/{shareID}
+func (h *ShareHandler) RevokeShareLink(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.s.RevokeShareLink(r.Context(), currentUserID, chi.URLParam(r, "shareID")); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// OpenShareLink - GET /shares/{shareID}?expires=&signature=, без токена.
+func (h *ShareHandler) OpenShareLink(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	link, err := h.s.OpenShareLink(r.Context(), chi.URLParam(r, "shareID"), query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.shareLinkToDTO(link))
+}
+
+// AcceptShareLink - POST /shares/{shareID}/accept?expires=&signature=
+// Тело {"follow": true} необязательно.
+func (h *ShareHandler) AcceptShareLink(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req dtos.AcceptShareLinkRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	created, followed, err := h.s.AcceptShareLink(r.Context(), currentUserID, chi.URLParam(r, "shareID"),
+		query.Get("expires"), query.Get("signature"), req.Follow)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(dtos.AcceptShareLinkResponseDTO{RecommendationsCreated: created, Followed: followed})
+}
+
+func (h *ShareHandler) shareLinkToDTO(link models.ShareLink) dtos.ShareLinkResponseDTO {
+	response := dtos.ShareLinkResponseDTO{
+		ID:           link.ID,
+		URL:          h.s.LinkURL(link),
+		Note:         link.Note,
+		PromptFollow: link.PromptFollow,
+		Media:        make([]dtos.MediaResponseDTO, 0, len(link.Media)),
+		ExpiresAt:    link.ExpiresAt,
+		CreatedAt:    link.CreatedAt,
+		RevokedAt:    link.RevokedAt,
+	}
+	if link.Owner.ID != 0 {
+		response.Owner = &dtos.UserSummaryDTO{ID: link.Owner.ID, UserName: link.Owner.UserName}
+	}
+	for _, media := range link.Media {
+		response.Media = append(response.Media, mediaToDTO(media))
+	}
+	return response
+}
+
+func (h *ShareHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidShareMedia), errors.Is(err, service.ErrInvalidShareNote),
+		errors.Is(err, service.ErrInvalidShareExpiry):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrInvalidShareLink):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, service.ErrShareLinkNotFound), errors.Is(err, service.ErrMediaNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrOwnShareLink):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Error("Failed to process share link request", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
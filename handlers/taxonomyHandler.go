@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type TaxonomyHandler struct {
+	s      *service.TaxonomyService
+	logger *slog.Logger
+}
+
+func NewTaxonomyHandler(s *service.TaxonomyService, logger *slog.Logger) *TaxonomyHandler {
+	return &TaxonomyHandler{s: s, logger: logger}
+}
+
+// GetGenres - GET /genres?q=&sort=popular|name
+func (h *TaxonomyHandler) GetGenres(w http.ResponseWriter, r *http.Request) {
+	h.getTerms(w, r, models.TaxonomyGenre)
+}
+
+// GetTags - GET /tags?q=&sort=popular|name
+func (h *TaxonomyHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	h.getTerms(w, r, models.TaxonomyTag)
+}
+
+func (h *TaxonomyHandler) getTerms(w http.ResponseWriter, r *http.Request, kind models.TaxonomyKind) {
+	query := r.URL.Query()
+	terms, err := h.s.GetTerms(r.Context(), kind, query.Get("q"), query.Get("sort"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := make([]dtos.TermResponseDTO, 0, len(terms))
+	for _, term := range terms {
+		response = append(response, termToDTO(term))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// GetGenreMedia - GET /genres/{slug}/media?type=&sort=popular|recent&page=&limit=
+func (h *TaxonomyHandler) GetGenreMedia(w http.ResponseWriter, r *http.Request) {
+	h.getMediaByTerm(w, r, models.TaxonomyGenre)
+}
+
+// GetTagMedia - GET /tags/{slug}/media?type=&sort=popular|recent&page=&limit=
+func (h *TaxonomyHandler) GetTagMedia(w http.ResponseWriter, r *http.Request) {
+	h.getMediaByTerm(w, r, models.TaxonomyTag)
+}
+
+func (h *TaxonomyHandler) getMediaByTerm(w http.ResponseWriter, r *http.Request, kind models.TaxonomyKind) {
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	items, err := h.s.GetMediaByTerm(r.Context(), kind, chi.URLParam(r, "slug"), models.MediaType(query.Get("type")),
+		query.Get("sort"), page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.BrowseMediaResponseDTO]{
+		Data:       make([]dtos.BrowseMediaResponseDTO, 0, len(items.Data)),
+		Total:      items.Total,
+		Page:       items.Page,
+		Limit:      items.Limit,
+		TotalPages: items.TotalPages,
+	}
+	for _, item := range items.Data {
+		response.Data = append(response.Data, dtos.BrowseMediaResponseDTO{
+			Media:               mediaToDTO(item.Media),
+			RecommendationCount: item.RecommendationCount,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SuggestTag - POST /media/{mediaID}/tags, предложить тег. Тег появится после одобрения модератором.
+func (h *TaxonomyHandler) SuggestTag(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	mediaID, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		http.Error(w, "invalid media id", http.StatusBadRequest)
+		return
+	}
+
+	var body dtos.SuggestTagDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	suggestion, err := h.s.SuggestTag(r.Context(), currentUserID, mediaID, body.Name)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(tagSuggestionToDTO(suggestion))
+}
+
+// CreateGenre - POST /admin/genres
+func (h *TaxonomyHandler) CreateGenre(w http.ResponseWriter, r *http.Request) {
+	var body dtos.CreateGenreDTO
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	genre, err := h.s.CreateGenre(r.Context(), body.Name)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(termToDTO(genre))
+}
+
+// GetTagSuggestions - GET /admin/tag-suggestions?status=&page=&limit=
+func (h *TaxonomyHandler) GetTagSuggestions(w http.ResponseWriter, r *http.Request) {
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	status := models.TagSuggestionStatus(r.URL.Query().Get("status"))
+	suggestions, err := h.s.GetTagSuggestions(r.Context(), status, page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.TagSuggestionResponseDTO]{
+		Data:       make([]dtos.TagSuggestionResponseDTO, 0, len(suggestions.Data)),
+		Total:      suggestions.Total,
+		Page:       suggestions.Page,
+		Limit:      suggestions.Limit,
+		TotalPages: suggestions.TotalPages,
+	}
+	for _, suggestion := range suggestions.Data {
+		response.Data = append(response.Data, tagSuggestionToDTO(suggestion))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+func (h *TaxonomyHandler) ApproveTagSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.reviewTagSuggestion(w, r, h.s.ApproveTagSuggestion)
+}
+
+func (h *TaxonomyHandler) RejectTagSuggestion(w http.ResponseWriter, r *http.Request) {
+	h.reviewTagSuggestion(w, r, h.s.RejectTagSuggestion)
+}
+
+func (h *TaxonomyHandler) reviewTagSuggestion(w http.ResponseWriter, r *http.Request,
+	review func(ctx context.Context, reviewerID, suggestionID int) (models.TagSuggestion, error)) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	suggestionID, err := strconv.Atoi(chi.URLParam(r, "suggestionID"))
+	if err != nil {
+		http.Error(w, "invalid suggestion id", http.StatusBadRequest)
+		return
+	}
+
+	suggestion, err := review(r.Context(), currentUserID, suggestionID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(tagSuggestionToDTO(suggestion))
+}
+
+func (h *TaxonomyHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidTermName),
+		errors.Is(err, service.ErrInvalidSort),
+		errors.Is(err, service.ErrInvalidMediaType),
+		errors.Is(err, service.ErrInvalidTagSuggestionStatus):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrGenreNotFound),
+		errors.Is(err, service.ErrTagNotFound),
+		errors.Is(err, service.ErrMediaNotFound),
+		errors.Is(err, service.ErrTagSuggestionNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrGenreExists),
+		errors.Is(err, service.ErrTagAlreadyApplied),
+		errors.Is(err, service.ErrTagAlreadySuggested),
+		errors.Is(err, service.ErrTagSuggestionReviewed):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Error("Taxonomy operation failed", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func termToDTO(term models.Term) dtos.TermResponseDTO {
+	return dtos.TermResponseDTO{Slug: term.Slug, Name: term.Name, MediaCount: term.MediaCount}
+}
+
+func tagSuggestionToDTO(suggestion models.TagSuggestion) dtos.TagSuggestionResponseDTO {
+	return dtos.TagSuggestionResponseDTO{
+		ID:         suggestion.ID,
+		MediaID:    suggestion.MediaID,
+		UserID:     suggestion.UserID,
+		Name:       suggestion.Name,
+		Slug:       suggestion.Slug,
+		Status:     string(suggestion.Status),
+		ReviewerID: suggestion.ReviewerID,
+		CreatedAt:  suggestion.CreatedAt,
+		ReviewedAt: suggestion.ReviewedAt,
+	}
+}
@@ -72,11 +72,13 @@ func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
-	// 2. Creating token
-	token, err := h.s.Login(r.Context(), loginDTO)
+	// 2. Creating token (or a 2FA challenge if two-factor auth is enabled)
+	loginResponse, err := h.s.Login(r.Context(), loginDTO)
 	if err != nil {
 		if errors.Is(err, service.ErrInvalidCredentials) {
 			http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		} else if errors.Is(err, service.ErrUserSuspended) {
+			http.Error(w, err.Error(), http.StatusForbidden)
 		} else {
 			// Все остальные ошибки - это 500
 			http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -88,7 +90,7 @@ func (h *UserHandler) LoginUser(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 
 	// 3. Send token
-	if err := json.NewEncoder(w).Encode(dtos.TokenResponseDTO{Token: token}); err != nil {
+	if err := json.NewEncoder(w).Encode(loginResponse); err != nil {
 		http.Error(w, "Failed to encode token to JSON", http.StatusInternalServerError)
 	}
 }
@@ -349,13 +351,36 @@ func (h *UserHandler) DeleteCurrentUser(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err := h.s.DeleteUser(r.Context(), currentUserID)
+	// Аккаунт удаляется не сразу: до purge_after его можно восстановить входом
+	deletion, err := h.s.DeleteUser(r.Context(), currentUserID)
 	if err != nil {
 		// Здесь уже есть логгер из сервиса, можно добавить еще один в хендлере
 		http.Error(w, "Failed to delete user account", http.StatusInternalServerError)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(deletion)
+}
+
+// DeactivateCurrentUser временно скрывает аккаунт. Следующий вход снимает деактивацию.
+func (h *UserHandler) DeactivateCurrentUser(w http.ResponseWriter, r *http.Request) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.s.DeactivateUser(r.Context(), currentUserID); err != nil {
+		if errors.Is(err, service.ErrUserNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to deactivate user account", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookHandler обслуживает и вебхуки пользователя (/me/webhooks), и вебхуки
+// администраторов (/admin/webhooks): второй экземпляр создается с admin = true.
+type WebhookHandler struct {
+	s      *service.WebhookService
+	admin  bool
+	logger *slog.Logger
+}
+
+func NewWebhookHandler(s *service.WebhookService, admin bool, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{s: s, admin: admin, logger: logger}
+}
+
+// GetWebhooks - GET /me/webhooks
+func (h *WebhookHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.s.GetWebhooks(r.Context(), ownerID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := make([]dtos.WebhookResponseDTO, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		response = append(response, webhookToDTO(webhook, false))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// CreateWebhook - POST /me/webhooks. Секрет для проверки подписи есть только в этом ответе.
+func (h *WebhookHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	ownerID, ok := h.owner(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.SaveWebhookRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	webhook, err := h.s.CreateWebhook(r.Context(), ownerID, req.URL, req.Events, req.Description)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(webhookToDTO(webhook, true))
+}
+
+// GetWebhook - GET /me/webhooks/{webhookID}
+func (h *WebhookHandler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	ownerID, webhookID, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.s.GetWebhook(r.Context(), ownerID, webhookID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhookToDTO(webhook, false))
+}
+
+// UpdateWebhook - PUT /me/webhooks/{webhookID}. Без active вебхук остается включенным.
+func (h *WebhookHandler) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	ownerID, webhookID, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	var req dtos.SaveWebhookRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	active := req.Active == nil || *req.Active
+
+	webhook, err := h.s.UpdateWebhook(r.Context(), ownerID, webhookID, req.URL, req.Events, req.Description, active)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhookToDTO(webhook, false))
+}
+
+// DeleteWebhook - DELETE /me/webhooks/{webhookID}
+func (h *WebhookHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	ownerID, webhookID, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.s.DeleteWebhook(r.Context(), ownerID, webhookID); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDeliveries - GET /me/webhooks/{webhookID}/deliveries, журнал доставок
+func (h *WebhookHandler) GetDeliveries(w http.ResponseWriter, r *http.Request) {
+	ownerID, webhookID, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	page, limit, err := utils.ParsePaginationParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.s.GetDeliveries(r.Context(), ownerID, webhookID, page, limit)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	response := dtos.PaginatedResponseDTO[dtos.WebhookDeliveryResponseDTO]{
+		Data:       make([]dtos.WebhookDeliveryResponseDTO, 0, len(deliveries.Data)),
+		Total:      deliveries.Total,
+		Page:       deliveries.Page,
+		Limit:      deliveries.Limit,
+		TotalPages: deliveries.TotalPages,
+	}
+	for _, delivery := range deliveries.Data {
+		response.Data = append(response.Data, webhookDeliveryToDTO(delivery))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(response)
+}
+
+// Redeliver - POST /me/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	ownerID, webhookID, ok := h.webhookParams(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid delivery id", http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.s.Redeliver(r.Context(), ownerID, webhookID, deliveryID)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(webhookDeliveryToDTO(delivery))
+}
+
+// owner возвращает владельца вебхуков запроса: текущего пользователя или nil
+// для вебхуков администраторов (права проверены middleware роутера).
+func (h *WebhookHandler) owner(w http.ResponseWriter, r *http.Request) (*int, bool) {
+	currentUserID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return nil, false
+	}
+	if h.admin {
+		return nil, true
+	}
+	return &currentUserID, true
+}
+
+func (h *WebhookHandler) webhookParams(w http.ResponseWriter, r *http.Request) (*int, int64, bool) {
+	ownerID, ok := h.owner(w, r)
+	if !ok {
+		return nil, 0, false
+	}
+
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid webhook id", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	return ownerID, webhookID, true
+}
+
+func webhookToDTO(webhook models.Webhook, withSecret bool) dtos.WebhookResponseDTO {
+	response := dtos.WebhookResponseDTO{
+		ID:          webhook.ID,
+		URL:         webhook.URL,
+		Events:      make([]string, 0, len(webhook.Events)),
+		Description: webhook.Description,
+		Active:      webhook.Active,
+		CreatedAt:   webhook.CreatedAt,
+		UpdatedAt:   webhook.UpdatedAt,
+	}
+	for _, event := range webhook.Events {
+		response.Events = append(response.Events, string(event))
+	}
+	if withSecret {
+		response.Secret = webhook.Secret
+	}
+	return response
+}
+
+func webhookDeliveryToDTO(delivery models.WebhookDelivery) dtos.WebhookDeliveryResponseDTO {
+	response := dtos.WebhookDeliveryResponseDTO{
+		ID:             delivery.ID,
+		EventID:        delivery.Event.ID,
+		EventType:      string(delivery.Event.Type),
+		RedeliveryOf:   delivery.RedeliveryOf,
+		Status:         string(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == models.DeliveryPending {
+		response.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return response
+}
+
+func (h *WebhookHandler) writeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidWebhookURL), errors.Is(err, service.ErrInvalidWebhookEvents),
+		errors.Is(err, service.ErrInvalidWebhookDescription):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrWebhookNotFound), errors.Is(err, service.ErrWebhookDeliveryNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, service.ErrTooManyWebhooks), errors.Is(err, service.ErrWebhookDisabled):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		h.logger.Error("Failed to process webhook request", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -1,38 +1,188 @@
 package jwt
 
 import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/cobrich/recommendo/config"
 	"github.com/golang-jwt/jwt/v5"
-	// "github.com/joho/godotenv"
 )
 
+// LegacyKeyID - идентификатор ключа, созданного из одиночного jwt.secret.
+// Токены без заголовка kid (выданные до ротации ключей) проверяются этим ключом.
+const LegacyKeyID = "default"
+
+// PurposeTwoFactor - назначение промежуточного токена, который выдается после
+// проверки пароля, если у пользователя включена двухфакторная аутентификация.
+const PurposeTwoFactor = "2fa"
+
+var ErrWrongTokenPurpose = errors.New("token cannot be used for this operation")
+
 type Claims struct {
 	UserID int `json:"user_id"`
+	// SessionID связывает токен доступа с записью в user_sessions
+	SessionID string `json:"sid,omitempty"`
+	// Purpose пустой у обычных токенов доступа
+	Purpose string `json:"purpose,omitempty"`
+	// Role - роль пользователя на момент входа. При смене роли сессии отзываются,
+	// поэтому токен со старой ролью не переживает изменение.
+	Role string `json:"role,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// --- 1. Функция генерации токена ---
+// signingKey - один ключ из набора. Ключ без signKey используется только для
+// проверки подписи: так выводятся из оборота старые ключи после ротации.
+type signingKey struct {
+	id        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// TokenManager выпускает и проверяет JWT. Создается один раз при старте
+// и передается в сервисы и middleware.
+type TokenManager struct {
+	keys     map[string]*signingKey
+	active   *signingKey
+	methods  []string
+	issuer   string
+	audience string
+	ttl      time.Duration
+	leeway   time.Duration
+}
+
+// NewTokenManager загружает ключи из конфига. Поддерживаются HS256 (секрет),
+// RS256 и EdDSA (PEM-файлы). Алгоритм определяется по типу ключа.
+func NewTokenManager(cfg config.JWTConfig) (*TokenManager, error) {
+	m := &TokenManager{
+		keys:     make(map[string]*signingKey),
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		ttl:      cfg.TTL,
+		leeway:   cfg.Leeway,
+	}
+
+	if cfg.Secret != "" {
+		m.addKey(&signingKey{
+			id:        LegacyKeyID,
+			method:    jwt.SigningMethodHS256,
+			signKey:   []byte(cfg.Secret.Value()),
+			verifyKey: []byte(cfg.Secret.Value()),
+		})
+	}
+
+	for _, keyCfg := range cfg.Keys {
+		key, err := loadKey(keyCfg)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := m.keys[key.id]; exists {
+			return nil, fmt.Errorf("duplicate jwt key id %q", key.id)
+		}
+		m.addKey(key)
+	}
+
+	activeID := cfg.ActiveKeyID
+	if activeID == "" {
+		activeID = LegacyKeyID
+	}
+	active, ok := m.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active jwt key %q is not configured", activeID)
+	}
+	if active.signKey == nil {
+		return nil, fmt.Errorf("active jwt key %q has no private key", activeID)
+	}
+	m.active = active
+
+	return m, nil
+}
+
+func (m *TokenManager) addKey(key *signingKey) {
+	m.keys[key.id] = key
+	for _, alg := range m.methods {
+		if alg == key.method.Alg() {
+			return
+		}
+	}
+	m.methods = append(m.methods, key.method.Alg())
+}
+
+// loadKey читает PEM-файл. Приватный ключ годится и для подписи, и для проверки,
+// публичный - только для проверки.
+func loadKey(cfg config.JWTKeyConfig) (*signingKey, error) {
+	if cfg.Secret != "" {
+		return &signingKey{
+			id:        cfg.ID,
+			method:    jwt.SigningMethodHS256,
+			signKey:   []byte(cfg.Secret.Value()),
+			verifyKey: []byte(cfg.Secret.Value()),
+		}, nil
+	}
+
+	data, err := os.ReadFile(cfg.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwt key %q: %w", cfg.ID, err)
+	}
+
+	if priv, err := jwt.ParseRSAPrivateKeyFromPEM(data); err == nil {
+		return &signingKey{id: cfg.ID, method: jwt.SigningMethodRS256, signKey: priv, verifyKey: &priv.PublicKey}, nil
+	}
+	if priv, err := jwt.ParseEdPrivateKeyFromPEM(data); err == nil {
+		edPriv := priv.(ed25519.PrivateKey)
+		return &signingKey{id: cfg.ID, method: jwt.SigningMethodEdDSA, signKey: edPriv, verifyKey: edPriv.Public()}, nil
+	}
+	if pub, err := jwt.ParseRSAPublicKeyFromPEM(data); err == nil {
+		return &signingKey{id: cfg.ID, method: jwt.SigningMethodRS256, verifyKey: pub}, nil
+	}
+	if pub, err := jwt.ParseEdPublicKeyFromPEM(data); err == nil {
+		return &signingKey{id: cfg.ID, method: jwt.SigningMethodEdDSA, verifyKey: pub}, nil
+	}
+
+	return nil, fmt.Errorf("jwt key %q: %s is not an RSA or Ed25519 PEM key", cfg.ID, cfg.File)
+}
+
+// GenerateToken выпускает токен доступа активным ключом для сессии sessionID.
 // Вызывается после успешной аутентификации пользователя (проверки логина/пароля).
-func GenerateToken(userID int) (string, error) {
-	// Устанавливаем время жизни токена, например, 24 часа.
-	expirationTime := time.Now().Add(24 * time.Hour)
+func (m *TokenManager) GenerateToken(userID int, sessionID, role string) (string, error) {
+	return m.generate(&Claims{UserID: userID, SessionID: sessionID, Role: role}, m.ttl)
+}
 
-	// Создаем "заявки" (claims), включая ID пользователя и время истечения.
-	claims := &Claims{
-		UserID: userID,
-		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
-		},
+// TTL - срок жизни токена доступа (и сессии, которую он представляет).
+func (m *TokenManager) TTL() time.Duration {
+	return m.ttl
+}
+
+// GenerateChallengeToken выпускает короткоживущий токен второго шага входа.
+// С ним нельзя обращаться к API: он годится только для проверки кода 2FA.
+func (m *TokenManager) GenerateChallengeToken(userID int, ttl time.Duration) (string, error) {
+	return m.generate(&Claims{UserID: userID, Purpose: PurposeTwoFactor}, ttl)
+}
+
+func (m *TokenManager) generate(claims *Claims, ttl time.Duration) (string, error) {
+	now := time.Now()
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(claims.UserID),
+		Issuer:    m.issuer,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+	if m.audience != "" {
+		claims.Audience = jwt.ClaimStrings{m.audience}
 	}
 
-	// Создаем новый токен, указывая алгоритм подписи и "заявки".
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token := jwt.NewWithClaims(m.active.method, claims)
+	// kid позволяет проверяющей стороне выбрать ключ после ротации
+	token.Header["kid"] = m.active.id
 
-	jwtKey := os.Getenv("JWT_SECRET_KEY")
-	tokenString, err := token.SignedString([]byte(jwtKey))
+	tokenString, err := token.SignedString(m.active.signKey)
 	if err != nil {
 		return "", err
 	}
@@ -40,19 +190,46 @@ func GenerateToken(userID int) (string, error) {
 	return tokenString, nil
 }
 
-// ParseToken проверяет подпись токена и возвращает claims в случае успеха.
-func ParseToken(tokenString string) (*Claims, error) {
-	claims := &Claims{}
-	jwtKey := []byte(os.Getenv("JWT_SECRET_KEY"))
+// ParseToken проверяет токен доступа: подпись и стандартные claims (exp, nbf, iss, aud).
+// Токены с особым назначением (например, 2FA-challenge) отклоняются.
+func (m *TokenManager) ParseToken(tokenString string) (*Claims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != "" {
+		return nil, ErrWrongTokenPurpose
+	}
+	return claims, nil
+}
 
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		// Убеждаемся, что алгоритм подписи тот, который мы ожидаем (HS256)
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtKey, nil
-	})
+// ParseChallengeToken проверяет промежуточный токен второго шага входа.
+func (m *TokenManager) ParseChallengeToken(tokenString string) (*Claims, error) {
+	claims, err := m.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != PurposeTwoFactor {
+		return nil, ErrWrongTokenPurpose
+	}
+	return claims, nil
+}
 
+func (m *TokenManager) parse(tokenString string) (*Claims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(m.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(m.leeway),
+	}
+	if m.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(m.issuer))
+	}
+	if m.audience != "" {
+		opts = append(opts, jwt.WithAudience(m.audience))
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, m.keyFunc, opts...)
 	if err != nil {
 		return nil, err // Ошибка может быть из-за истекшего срока или неверной подписи
 	}
@@ -63,3 +240,33 @@ func ParseToken(tokenString string) (*Claims, error) {
 
 	return claims, nil
 }
+
+// keyFunc выбирает ключ по kid и проверяет, что алгоритм токена совпадает
+// с алгоритмом ключа (иначе возможна подмена RS256 на HS256).
+func (m *TokenManager) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = LegacyKeyID
+	}
+
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, errors.New("signing method does not match key")
+	}
+
+	return key.verifyKey, nil
+}
+
+// publicKey возвращает публичную часть ключа. Для HMAC-ключей возвращает nil:
+// общий секрет нельзя публиковать.
+func (k *signingKey) publicKey() interface{} {
+	switch pub := k.verifyKey.(type) {
+	case *rsa.PublicKey, ed25519.PublicKey:
+		return pub
+	default:
+		return nil
+	}
+}
@@ -1,68 +1,355 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/cobrich/recommendo/config"
 	"github.com/cobrich/recommendo/handlers"
+	"github.com/cobrich/recommendo/jwt"
+	"github.com/cobrich/recommendo/oauth"
 	"github.com/cobrich/recommendo/repo"
 	"github.com/cobrich/recommendo/router"
 	"github.com/cobrich/recommendo/service"
+	"github.com/cobrich/recommendo/tracing"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/cobrich/recommendo/worker"
 
 	"github.com/jackc/pgx/v5/stdlib"
 )
 
 func main() {
+	configPath := flag.String("config", os.Getenv("CONFIG_FILE"), "path to a YAML or TOML config file")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [-config file] [serve | config print]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	switch args := flag.Args(); {
+	case len(args) == 0 || (len(args) == 1 && args[0] == "serve"):
+		cfg, err := config.Load(*configPath)
+		if err != nil {
+			log.Fatalf("Unable to load config: %v", err)
+		}
+		serve(cfg)
+	case len(args) == 2 && args[0] == "config" && args[1] == "print":
+		printConfig(*configPath)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// printConfig выводит итоговую конфигурацию, даже если она не проходит проверку:
+// ошибки проверки печатаются после нее, и команда завершается с кодом 1.
+func printConfig(path string) {
+	cfg, err := config.Read(path)
+	if err != nil {
+		log.Fatalf("Unable to load config: %v", err)
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		log.Fatal(err)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func serve(cfg *config.Config) {
 	// Create logger
-	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: parseLogLevel(cfg.Log.Level)}))
+	logger.Info("Configuration loaded", "config", cfg)
+
+	// Apply settings to packages that are configured once at startup
+	utils.ConfigurePasswordHashing(cfg.Auth.BcryptCost)
+	utils.ConfigurePagination(cfg.Pagination.DefaultLimit, cfg.Pagination.MaxLimit)
+
+	// Token signer/verifier shared by the user service and the auth middleware
+	tokens, err := jwt.NewTokenManager(cfg.JWT)
+	if err != nil {
+		log.Fatalf("Unable to load JWT keys: %v", err)
+	}
 
-	// Get config
-	cfg := config.GetConfig()
+	// External login providers
+	oauthProviders, err := oauth.NewRegistry(cfg.OAuth)
+	if err != nil {
+		log.Fatalf("Unable to configure login providers: %v", err)
+	}
+	oauthStates := oauth.NewStateCodec([]byte(cfg.OAuth.StateSecret.Value()), cfg.OAuth.StateTTL)
+
+	// Context is cancelled on SIGINT/SIGTERM and starts graceful shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// Set up tracing
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
+	if err != nil {
+		log.Fatalf("Unable to set up tracing: %v", err)
+	}
 
 	// Create connector
-	connector := stdlib.GetConnector(*cfg)
+	connConfig, err := cfg.Database.ConnConfig()
+	if err != nil {
+		log.Fatalf("Unable to configure database: %v", err)
+	}
+	connector := stdlib.GetConnector(*connConfig)
 
 	// Open db with connector that saves instructions for how creating
 	db := sql.OpenDB(connector)
 
-	// Close db for free space in memory
-	defer db.Close()
-
 	// Check connecting to db
-	err := db.Ping()
+	err = db.PingContext(ctx)
 	if err != nil {
 		log.Fatalf("Unable to connect to database: %v", err)
 	}
 	fmt.Println("Successfully connected to database!")
 
+	// Background workers
+	workers := worker.NewGroup(logger)
+
 	// Repos
 	userRepo := repo.NewUserRepo(db)
 	followRepo := repo.NewFollowRepo(db)
 	mediaRepo := repo.NewMediaRepo(db)
 	recommendationRepo := repo.NewRecommendationRepo(db)
+	identityRepo := repo.NewIdentityRepo(db)
+	twoFactorRepo := repo.NewTwoFactorRepo(db)
+	sessionRepo := repo.NewSessionRepo(db)
+	dataExportRepo := repo.NewDataExportRepo(db)
+	moderationRepo := repo.NewModerationRepo(db)
+	auditRepo := repo.NewAuditRepo(db)
+	libraryRepo := repo.NewLibraryRepo(db)
+	taxonomyRepo := repo.NewTaxonomyRepo(db)
+	rankingRepo := repo.NewRankingRepo(db)
+	circleRepo := repo.NewCircleRepo(db)
+	shareRepo := repo.NewShareRepo(db)
+	webhookRepo := repo.NewWebhookRepo(db)
+	jobRepo := repo.NewJobRepo(db)
 
 	// Services
-	userService := service.NewUserService(db, userRepo, followRepo, recommendationRepo, logger)
-	followService := service.NewFollowService(followRepo, logger)
-	mediaService := service.NewMediaService(mediaRepo, logger)
-	recommendationService := service.NewRecommendationService(recommendationRepo, mediaRepo, userService, followService, logger)
+	auditService := service.NewAuditService(auditRepo, logger)
+	jobService := service.NewJobService(db, jobRepo, service.JobSettings{
+		Workers:        cfg.Jobs.Workers,
+		PollInterval:   cfg.Jobs.PollInterval,
+		Timeout:        cfg.Jobs.Timeout,
+		MaxAttempts:    cfg.Jobs.MaxAttempts,
+		RetryBaseDelay: cfg.Jobs.RetryBaseDelay,
+		MaxRetryDelay:  cfg.Jobs.MaxRetryDelay,
+		Retention:      cfg.Jobs.Retention,
+	}, logger)
+	webhookService := service.NewWebhookService(db, webhookRepo, userRepo, followRepo, jobService, service.WebhookSettings{
+		Timeout:              cfg.Webhooks.Timeout,
+		RetryBaseDelay:       cfg.Webhooks.RetryBaseDelay,
+		MaxRetryDelay:        cfg.Webhooks.MaxRetryDelay,
+		Retention:            cfg.Webhooks.Retention,
+		MaxPerOwner:          cfg.Webhooks.MaxPerUser,
+		AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+	}, logger)
+	sessionService := service.NewSessionService(db, sessionRepo, userRepo, tokens, auditService, logger)
+	twoFactorService := service.NewTwoFactorService(db, twoFactorRepo, userRepo, sessionService, tokens, auditService, service.TwoFactorSettings{
+		Issuer:       cfg.Auth.TOTPIssuer,
+		ChallengeTTL: cfg.Auth.TwoFactorChallengeTTL,
+		MaxAttempts:  cfg.Auth.TwoFactorMaxAttempts,
+		Lockout:      cfg.Auth.TwoFactorLockout,
+	}, logger)
+	accountSettings := service.AccountSettings{
+		DeletionGracePeriod:      cfg.Account.DeletionGracePeriod,
+		AnonymizeRecommendations: cfg.Account.AnonymizeRecommendations,
+	}
+	userService := service.NewUserService(db, userRepo, twoFactorService, sessionService, auditService, jobService, accountSettings, logger)
+	followService := service.NewFollowService(db, followRepo, userService, auditService, webhookService, logger)
+	taxonomyService := service.NewTaxonomyService(db, taxonomyRepo, mediaRepo, logger)
+	mediaService := service.NewMediaService(db, mediaRepo, recommendationRepo, libraryRepo, taxonomyService, logger)
+	recommendationService := service.NewRecommendationService(db, recommendationRepo, mediaRepo, userService, followService, auditService, webhookService, logger)
+	identityService := service.NewIdentityService(db, identityRepo, userRepo, userService, logger)
+	dataExportService := service.NewDataExportService(db, dataExportRepo, userRepo, followRepo, recommendationRepo, identityRepo, libraryRepo, jobService, service.DataExportSettings{
+		Dir:        cfg.Export.Dir,
+		LinkSecret: []byte(cfg.Export.LinkSecret.Value()),
+		LinkTTL:    cfg.Export.LinkTTL,
+		Retention:  cfg.Export.Retention,
+	}, logger)
+	accountPurgeService := service.NewAccountPurgeService(db, userRepo, followRepo, recommendationRepo, dataExportRepo, circleRepo, accountSettings, logger)
+	adminService := service.NewAdminService(db, userRepo, recommendationRepo, moderationRepo, sessionService, logger)
+	moderationService := service.NewModerationService(db, moderationRepo, userRepo, mediaRepo, recommendationRepo,
+		adminService, recommendationService, logger)
+	libraryService := service.NewLibraryService(db, libraryRepo, mediaRepo, recommendationRepo, logger)
+	rankingService := service.NewRankingService(db, rankingRepo, cfg.Rankings.Size, logger)
+	shareService := service.NewShareService(db, shareRepo, recommendationRepo, followRepo, mediaRepo, webhookService, service.ShareSettings{
+		LinkSecret: []byte(cfg.Share.LinkSecret.Value()),
+		LinkTTL:    cfg.Share.LinkTTL,
+		MaxLinkTTL: cfg.Share.MaxLinkTTL,
+	}, logger)
+	circleService := service.NewCircleService(db, circleRepo, followRepo, mediaRepo, userService, webhookService, logger)
+
+	// Background jobs
+	jobService.Register(service.JobBuildDataExport, service.JobOptions{MaxAttempts: 3, OnFailure: dataExportService.FailExport},
+		dataExportService.BuildExport)
+	jobService.Register(service.JobPurgeAccount, service.JobOptions{}, accountPurgeService.PurgeAccount)
+	jobService.Register(service.JobDispatchWebhookEvent, service.JobOptions{MaxAttempts: cfg.Webhooks.MaxAttempts},
+		webhookService.DispatchEvent)
+	jobService.Register(service.JobDeliverWebhook, service.JobOptions{
+		// Запас на подключение: сам запрос ограничен webhooks.timeout
+		Timeout:        2 * cfg.Webhooks.Timeout,
+		MaxAttempts:    cfg.Webhooks.MaxAttempts,
+		RetryBaseDelay: cfg.Webhooks.RetryBaseDelay,
+		MaxRetryDelay:  cfg.Webhooks.MaxRetryDelay,
+		OnFailure:      webhookService.FailDelivery,
+	}, webhookService.Deliver)
+	schedules := []struct {
+		name, spec string
+		fn         func(ctx context.Context)
+	}{
+		{"account-purge", "@every " + cfg.Account.PurgeInterval.String(), accountPurgeService.PurgeDeletedUsers},
+		{"media-rankings", "@every " + cfg.Rankings.RefreshInterval.String(), rankingService.RefreshRankings},
+		{"data-export-cleanup", "@hourly", dataExportService.RemoveExpired},
+		{"webhook-cleanup", "@hourly", webhookService.RemoveOldEvents},
+		{"job-cleanup", "@daily", jobService.RemoveFinishedJobs},
+	}
+	for _, schedule := range schedules {
+		if err := jobService.Schedule(schedule.name, schedule.spec, schedule.fn); err != nil {
+			log.Fatalf("Invalid job schedule: %v", err)
+		}
+	}
+	workers.Go("jobs", jobService.Run)
 
 	// Handlers
 	userHandler := handlers.NewUserHandler(userService, logger)
 	friendshipHandler := handlers.NewFriendshiphandler(followService, logger)
 	mediaHandler := handlers.NewMediaHandler(mediaService, logger)
 	recommendationHandler := handlers.NewRecommendationHandler(recommendationService, logger)
-
-	fmt.Println("Сервер запущен на http://localhost:8080")
+	healthHandler := handlers.NewHealthHandler(db, logger)
+	jwksHandler := handlers.NewJWKSHandler(tokens)
+	oauthHandler := handlers.NewOAuthHandler(identityService, oauthProviders, oauthStates,
+		strings.HasPrefix(cfg.OAuth.RedirectBaseURL, "https://"), logger)
+	twoFactorHandler := handlers.NewTwoFactorHandler(twoFactorService, logger)
+	sessionHandler := handlers.NewSessionHandler(sessionService, logger)
+	dataExportHandler := handlers.NewDataExportHandler(dataExportService, logger)
+	adminHandler := handlers.NewAdminHandler(adminService, logger)
+	moderationHandler := handlers.NewModerationHandler(moderationService, logger)
+	auditHandler := handlers.NewAuditHandler(auditService, logger)
+	libraryHandler := handlers.NewLibraryHandler(libraryService, logger)
+	taxonomyHandler := handlers.NewTaxonomyHandler(taxonomyService, logger)
+	rankingHandler := handlers.NewRankingHandler(rankingService, logger)
+	shareHandler := handlers.NewShareHandler(shareService, logger)
+	circleHandler := handlers.NewCircleHandler(circleService, logger)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, false, logger)
+	adminWebhookHandler := handlers.NewWebhookHandler(webhookService, true, logger)
 
 	// Create router and set
-	router := router.NewRouter(userHandler, friendshipHandler, mediaHandler, recommendationHandler, logger)
+	router := router.NewRouter(router.Handlers{
+		User:           userHandler,
+		Follow:         friendshipHandler,
+		Media:          mediaHandler,
+		Recommendation: recommendationHandler,
+		Health:         healthHandler,
+		JWKS:           jwksHandler,
+		OAuth:          oauthHandler,
+		TwoFactor:      twoFactorHandler,
+		Session:        sessionHandler,
+		DataExport:     dataExportHandler,
+		Admin:          adminHandler,
+		Moderation:     moderationHandler,
+		Audit:          auditHandler,
+		Library:        libraryHandler,
+		Taxonomy:       taxonomyHandler,
+		Ranking:        rankingHandler,
+		Share:          shareHandler,
+		Circle:         circleHandler,
+		Webhook:        webhookHandler,
+		AdminWebhook:   adminWebhookHandler,
+	}, tokens, sessionService, cfg.Server, cfg.CORS, logger)
+
+	server := &http.Server{
+		Addr:         cfg.Server.Addr(),
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
+
+	// Run server in background so main can wait for a signal
+	serverErr := make(chan error, 1)
+	go func() {
+		logger.Info("Server started", "addr", cfg.Server.Addr())
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	drainDelay := cfg.Server.DrainDelay
+	select {
+	case err := <-serverErr:
+		if err != nil {
+			logger.Error("Server failed", "error", err)
+		}
+		// Сервер уже не принимает соединения: ждать балансировщик незачем
+		drainDelay = 0
+	case <-ctx.Done():
+		logger.Info("Shutdown signal received, draining requests", "timeout", cfg.Server.ShutdownTimeout)
+	}
+
+	// Stop receiving signals: a second Ctrl+C kills the process immediately
+	stop()
+
+	shutdown(server, healthHandler, workers, db, shutdownTracing, drainDelay, cfg.Server.ShutdownTimeout, logger)
+}
+
+// shutdown останавливает компоненты в обратном порядке: сначала перестаем
+// принимать трафик и дожидаемся текущих запросов, потом фоновые задачи,
+// и только затем закрываем пул соединений и экспортер трасс.
+func shutdown(server *http.Server, health *handlers.HealthHandler, workers *worker.Group, db *sql.DB,
+	shutdownTracing tracing.ShutdownFunc, drainDelay, timeout time.Duration, logger *slog.Logger) {
+	// Пока балансировщик не заметил 503 на /readyz, он продолжает присылать запросы:
+	// сервер их обслуживает и закрывает порт только после drainDelay
+	health.SetDraining()
+	logger.Info("Draining before shutdown", "delay", drainDelay)
+	time.Sleep(drainDelay)
 
-	// Run server in port 8080
-	log.Fatal(http.ListenAndServe(":8080", router))
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		logger.Error("HTTP server did not drain in time", "error", err)
+	}
+
+	if err := workers.Shutdown(ctx); err != nil {
+		logger.Error("Background workers did not stop in time", "error", err)
+	}
+
+	if err := db.Close(); err != nil {
+		logger.Error("Failed to close database pool", "error", err)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("Failed to flush traces", "error", err)
+	}
+
+	logger.Info("Server stopped")
+}
+
+func parseLogLevel(level string) slog.Level {
+	switch level {
+	case "debug":
+		return slog.LevelDebug
+	case "warn":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
 }
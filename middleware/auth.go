@@ -7,14 +7,31 @@ import (
 	"strings"
 
 	"github.com/cobrich/recommendo/jwt" // Middleware использует jwt
+	"github.com/cobrich/recommendo/models"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 )
 
 // Определяем кастомный ключ для контекста. Это предотвращает случайные коллизии.
 type contextKey string
 const UserIDKey contextKey = "userID"
+const SessionIDKey contextKey = "sessionID"
+const RoleKey contextKey = "role"
 
-// JWTAuthenticator - это middleware для проверки JWT токена.
-func JWTAuthenticator(next http.Handler) http.Handler {
+// SessionValidator проверяет, что сессия, к которой привязан токен, еще активна.
+// Ошибка означает сбой проверки (например, недоступна БД), а не отозванную сессию.
+type SessionValidator interface {
+	IsSessionActive(ctx context.Context, userID int, sessionID string) (bool, error)
+}
+
+// NewJWTAuthenticator создает middleware для проверки JWT токена и его сессии.
+func NewJWTAuthenticator(tokens *jwt.TokenManager, sessions SessionValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return jwtAuthenticator(tokens, sessions, next)
+	}
+}
+
+func jwtAuthenticator(tokens *jwt.TokenManager, sessions SessionValidator, next http.Handler) http.Handler {
 	// http.HandlerFunc - это адаптер, позволяющий использовать обычные функции как http.Handler
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// 1. Получаем заголовок Authorization
@@ -33,18 +50,41 @@ func JWTAuthenticator(next http.Handler) http.Handler {
 		
 		tokenString := headerParts[1]
 
-		// 3. Парсим и валидируем токен с помощью нашего пакета jwt
-		claims, err := jwt.ParseToken(tokenString)
+		// 3. Парсим и валидируем токен с помощью TokenManager
+		_, span := otel.Tracer(tracerName).Start(r.Context(), "jwt.ParseToken")
+		claims, err := tokens.ParseToken(tokenString)
 		if err != nil {
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if err != nil {
+			http.Error(w, "Invalid token", http.StatusUnauthorized)
+			return
+		}
+
+		// 4. Токен должен принадлежать активной сессии: после выхода или отзыва
+		// сессии он перестает работать, не дожидаясь истечения срока
+		if claims.SessionID == "" {
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
+		active, err := sessions.IsSessionActive(r.Context(), claims.UserID, claims.SessionID)
+		if err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if !active {
+			http.Error(w, "Session has expired or was revoked", http.StatusUnauthorized)
+			return
+		}
 
-		// 4. (САМЫЙ ВАЖНЫЙ ШАГ!) Добавляем ID пользователя в контекст запроса.
+		// 5. (САМЫЙ ВАЖНЫЙ ШАГ!) Добавляем ID пользователя в контекст запроса.
 		// Теперь все последующие хендлеры в цепочке смогут получить этот ID.
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
+		ctx = context.WithValue(ctx, RoleKey, models.Role(claims.Role))
 
-		// 5. Вызываем следующий хендлер в цепочке с обновленным контекстом
+		// 6. Вызываем следующий хендлер в цепочке с обновленным контекстом
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -54,4 +94,10 @@ func JWTAuthenticator(next http.Handler) http.Handler {
 func GetUserIDFromContext(ctx context.Context) (int, bool) {
     userID, ok := ctx.Value(UserIDKey).(int)
     return userID, ok
-}
\ No newline at end of file
+}
+
+// GetSessionIDFromContext извлекает ID текущей сессии из контекста.
+func GetSessionIDFromContext(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(SessionIDKey).(string)
+	return sessionID, ok
+}
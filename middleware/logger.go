@@ -4,14 +4,17 @@ import (
 	"log/slog"
 	"net/http"
 	"time"
+
+	"github.com/cobrich/recommendo/requestmeta"
 )
 
 func NewLogger(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
+			requestID := requestmeta.FromContext(r.Context()).RequestID
 
-			logger.Info("Request started", "method", r.Method, "path", r.URL.Path)
+			logger.Info("Request started", "method", r.Method, "path", r.URL.Path, "request_id", requestID)
 
 			// Вызываем следующий хендлер в цепочке
 			next.ServeHTTP(w, r)
@@ -21,6 +24,7 @@ func NewLogger(logger *slog.Logger) func(http.Handler) http.Handler {
 				"method", r.Method,
 				"path", r.URL.Path,
 				"duration", time.Since(start),
+				"request_id", requestID,
 			)
 		})
 	}
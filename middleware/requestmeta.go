@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/cobrich/recommendo/requestmeta"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader - заголовок с идентификатором запроса. Возвращается клиенту в ответе.
+const RequestIDHeader = "X-Request-ID"
+
+// NewRequestMeta кладет в контекст IP и User-Agent клиента и идентификатор запроса.
+// trustProxy разрешает брать IP из X-Forwarded-For / X-Real-IP и идентификатор
+// из X-Request-ID: включать только за обратным прокси, иначе клиент может
+// подставить любые значения.
+func NewRequestMeta(trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			meta := requestmeta.Meta{
+				IP:        clientIP(r, trustProxy),
+				UserAgent: r.UserAgent(),
+				RequestID: requestID(r, trustProxy),
+			}
+			w.Header().Set(RequestIDHeader, meta.RequestID)
+			next.ServeHTTP(w, r.WithContext(requestmeta.NewContext(r.Context(), meta)))
+		})
+	}
+}
+
+// requestID берет идентификатор, присвоенный прокси, или создает новый.
+func requestID(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		if id := r.Header.Get(RequestIDHeader); validRequestID(id) {
+			return id
+		}
+	}
+	return uuid.NewString()
+}
+
+// validRequestID пропускает только короткие идентификаторы из безопасных символов,
+// чтобы чужое значение не испортило логи и журнал аудита.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		isAlnum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlnum && c != '-' && c != '_' && c != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+func clientIP(r *http.Request, trustProxy bool) string {
+	if trustProxy {
+		// Первый адрес в X-Forwarded-For - исходный клиент
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			first, _, _ := strings.Cut(forwarded, ",")
+			return strings.TrimSpace(first)
+		}
+		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+			return strings.TrimSpace(realIP)
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,16 @@
+-- Внешние учетные записи (OIDC/OAuth2), привязанные к пользователю.
+-- Один пользователь может привязать не более одной учетной записи каждого провайдера.
+CREATE TABLE user_identities (
+    identity_id SERIAL PRIMARY KEY,
+    user_id     INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    provider    TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    email       TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (provider, subject),
+    UNIQUE (user_id, provider)
+);
+
+-- Пользователи, зарегистрированные через провайдера, не знают своего пароля:
+-- в password_hash лежит хэш случайной строки, а вход по паролю для них выключен.
+ALTER TABLE users ADD COLUMN password_login_enabled BOOLEAN NOT NULL DEFAULT TRUE;
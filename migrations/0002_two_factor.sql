@@ -0,0 +1,22 @@
+-- TOTP (RFC 6238). Запись без confirmed_at - начатая, но не подтвержденная настройка.
+CREATE TABLE user_totp (
+    user_id         INTEGER PRIMARY KEY REFERENCES users (user_id) ON DELETE CASCADE,
+    secret          TEXT NOT NULL,
+    confirmed_at    TIMESTAMPTZ,
+    -- Номер последнего принятого 30-секундного интервала: один код нельзя использовать дважды
+    last_used_step  BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until    TIMESTAMPTZ,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Одноразовые коды восстановления. Хранится только SHA-256 от кода.
+CREATE TABLE user_recovery_codes (
+    code_id    SERIAL PRIMARY KEY,
+    user_id    INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    code_hash  TEXT NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX user_recovery_codes_user_id_idx ON user_recovery_codes (user_id);
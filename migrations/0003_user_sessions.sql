@@ -0,0 +1,15 @@
+-- Сессии входа. Идентификатор сессии записывается в JWT (claim sid),
+-- поэтому отзыв сессии сразу делает ее токен недействительным.
+CREATE TABLE user_sessions (
+    session_id   UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    device       TEXT NOT NULL DEFAULT '',
+    user_agent   TEXT NOT NULL DEFAULT '',
+    ip_address   TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX user_sessions_user_id_idx ON user_sessions (user_id);
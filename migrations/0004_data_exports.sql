@@ -0,0 +1,15 @@
+-- Выгрузки персональных данных. Архив собирается фоновой задачей
+-- и хранится на диске до expires_at.
+CREATE TABLE data_exports (
+    export_id    UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    status       TEXT NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'running', 'ready', 'failed', 'expired')),
+    file_path    TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    completed_at TIMESTAMPTZ,
+    expires_at   TIMESTAMPTZ
+);
+
+CREATE INDEX data_exports_user_id_idx ON data_exports (user_id);
+CREATE INDEX data_exports_status_idx ON data_exports (status);
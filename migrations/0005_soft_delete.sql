@@ -0,0 +1,7 @@
+-- Мягкое удаление: аккаунт скрыт и может быть восстановлен входом,
+-- пока фоновая задача не сотрет его после периода ожидания.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
+CREATE INDEX users_deleted_at_idx ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- При стирании аккаунта отправленные им рекомендации могут остаться у получателей без автора
+ALTER TABLE recommendations ALTER COLUMN from_user_id DROP NOT NULL;
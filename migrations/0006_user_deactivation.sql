@@ -0,0 +1,3 @@
+-- Временная деактивация: аккаунт скрыт от других, входящие подписки и
+-- рекомендации приостановлены. Вход снимает деактивацию.
+ALTER TABLE users ADD COLUMN deactivated_at TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- Роли пользователей и блокировка аккаунтов модераторами.
+-- Первого администратора назначают вручную:
+--   UPDATE users SET role = 'admin' WHERE email = '...';
+ALTER TABLE users
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'moderator', 'admin')),
+    ADD COLUMN suspended_at TIMESTAMPTZ,
+    ADD COLUMN suspension_reason TEXT;
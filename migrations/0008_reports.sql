@@ -0,0 +1,35 @@
+-- Жалобы пользователей на пользователей, рекомендации и медиа.
+-- Жалоба попадает в очередь модераторов и закрывается решением (resolved) или отклонением (dismissed).
+CREATE TABLE reports (
+    report_id       SERIAL PRIMARY KEY,
+    reporter_id     INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    target_type     TEXT NOT NULL CHECK (target_type IN ('user', 'recommendation', 'media')),
+    target_id       INTEGER NOT NULL,
+    reason          TEXT NOT NULL
+                    CHECK (reason IN ('spam', 'harassment', 'inappropriate', 'impersonation', 'other')),
+    details         TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL DEFAULT 'open' CHECK (status IN ('open', 'resolved', 'dismissed')),
+    assignee_id     INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    resolution_note TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now(),
+    closed_at       TIMESTAMPTZ
+);
+
+CREATE INDEX reports_status_idx ON reports (status, created_at);
+-- Повторная жалоба того же пользователя на тот же объект, пока первая открыта, не нужна
+CREATE UNIQUE INDEX reports_open_unique_idx ON reports (reporter_id, target_type, target_id) WHERE status = 'open';
+
+-- Журнал действий модераторов: работа с жалобами, блокировки, удаление контента, смена ролей.
+CREATE TABLE moderation_actions (
+    action_id    SERIAL PRIMARY KEY,
+    moderator_id INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    report_id    INTEGER REFERENCES reports (report_id) ON DELETE SET NULL,
+    action       TEXT NOT NULL,
+    target_type  TEXT NOT NULL,
+    target_id    INTEGER NOT NULL,
+    note         TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX moderation_actions_report_id_idx ON moderation_actions (report_id);
+CREATE INDEX moderation_actions_target_idx ON moderation_actions (target_type, target_id);
@@ -0,0 +1,33 @@
+-- Журнал событий безопасности: вход, смена пароля, удаление аккаунта и т.д.
+-- Записи только добавляются: изменение и удаление запрещены триггером.
+-- Внешних ключей нет намеренно - журнал должен пережить стирание аккаунта.
+CREATE TABLE audit_events (
+    event_id    BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    actor_id    INTEGER,
+    action      TEXT NOT NULL,
+    target_type TEXT NOT NULL DEFAULT '',
+    target_id   INTEGER,
+    ip_address  TEXT NOT NULL DEFAULT '',
+    request_id  TEXT NOT NULL DEFAULT '',
+    metadata    JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX audit_events_occurred_at_idx ON audit_events (occurred_at);
+CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, occurred_at);
+CREATE INDEX audit_events_target_idx ON audit_events (target_type, target_id, occurred_at);
+CREATE INDEX audit_events_action_idx ON audit_events (action, occurred_at);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update_delete
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+    BEFORE TRUNCATE ON audit_events
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,22 @@
+-- Личная библиотека: что пользователь посмотрел, прочитал или прошел.
+-- progress измеряется в единицах, зависящих от типа медиа (серии, страницы, часы, минуты).
+CREATE TABLE user_media (
+    user_id     INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    media_id    INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    status      TEXT NOT NULL CHECK (status IN ('planned', 'in_progress', 'completed', 'dropped')),
+    progress    INTEGER NOT NULL DEFAULT 0 CHECK (progress >= 0),
+    started_on  DATE,
+    finished_on DATE,
+    score       SMALLINT CHECK (score BETWEEN 1 AND 10),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, media_id),
+    CHECK (finished_on IS NULL OR started_on IS NULL OR started_on <= finished_on)
+);
+
+CREATE INDEX user_media_user_status_idx ON user_media (user_id, status, updated_at);
+
+-- Рекомендация считается выполненной, когда получатель отметил медиа как завершенное
+ALTER TABLE recommendations
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed')),
+    ADD COLUMN completed_at TIMESTAMPTZ;
@@ -0,0 +1,34 @@
+-- Подробные сведения о медиа. Общие поля - колонками, поля, зависящие от типа
+-- (длительность, серии, страницы, платформы), жанры, теги и внешние идентификаторы - в metadata.
+ALTER TABLE media_items
+    ADD COLUMN original_title TEXT NOT NULL DEFAULT '',
+    ADD COLUMN description    TEXT NOT NULL DEFAULT '',
+    ADD COLUMN cover_url      TEXT NOT NULL DEFAULT '',
+    ADD COLUMN metadata       JSONB NOT NULL DEFAULT '{}' CHECK (jsonb_typeof(metadata) = 'object');
+
+-- Поиск по внешним идентификаторам: metadata @> '{"external_ids": {"imdb": "tt0133093"}}'
+CREATE INDEX media_items_metadata_idx ON media_items USING GIN (metadata jsonb_path_ops);
+
+-- Создатели с ролями; author в media_items остается основным создателем одной строкой
+CREATE TABLE media_creators (
+    media_id INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL,
+    name     TEXT NOT NULL,
+    role     TEXT NOT NULL,
+    PRIMARY KEY (media_id, position)
+);
+
+CREATE INDEX media_creators_name_idx ON media_creators (lower(name));
+
+-- Переносим существующих авторов в список создателей с основной ролью для типа
+INSERT INTO media_creators (media_id, position, name, role)
+SELECT media_id, 0, author,
+       CASE item_type
+           WHEN 'film' THEN 'director'
+           WHEN 'anime' THEN 'studio'
+           WHEN 'series' THEN 'showrunner'
+           WHEN 'book' THEN 'author'
+           WHEN 'game' THEN 'developer'
+       END
+FROM media_items
+WHERE author <> '' AND item_type IN ('film', 'anime', 'series', 'book', 'game');
@@ -0,0 +1,103 @@
+-- Жанры и теги вместо списков в media_items.metadata.
+-- Жанры - закрытый список, который ведут модераторы; теги пользователи предлагают
+-- через tag_suggestions, и тег появляется у медиа после одобрения.
+CREATE TABLE genres (
+    genre_id   SERIAL PRIMARY KEY,
+    slug       TEXT NOT NULL UNIQUE,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE media_genres (
+    media_id INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    genre_id INTEGER NOT NULL REFERENCES genres (genre_id) ON DELETE CASCADE,
+    PRIMARY KEY (media_id, genre_id)
+);
+
+CREATE INDEX media_genres_genre_id_idx ON media_genres (genre_id, media_id);
+
+CREATE TABLE tags (
+    tag_id     SERIAL PRIMARY KEY,
+    slug       TEXT NOT NULL UNIQUE,
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE media_tags (
+    media_id INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    tag_id   INTEGER NOT NULL REFERENCES tags (tag_id) ON DELETE CASCADE,
+    PRIMARY KEY (media_id, tag_id)
+);
+
+CREATE INDEX media_tags_tag_id_idx ON media_tags (tag_id, media_id);
+
+CREATE TABLE tag_suggestions (
+    suggestion_id SERIAL PRIMARY KEY,
+    media_id      INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    user_id       INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    name          TEXT NOT NULL,
+    slug          TEXT NOT NULL,
+    status        TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    reviewer_id   INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    reviewed_at   TIMESTAMPTZ
+);
+
+CREATE INDEX tag_suggestions_status_idx ON tag_suggestions (status, created_at);
+-- Один и тот же тег для медиа ждет решения только один раз
+CREATE UNIQUE INDEX tag_suggestions_pending_unique_idx ON tag_suggestions (media_id, slug) WHERE status = 'pending';
+
+INSERT INTO genres (slug, name) VALUES
+    ('action', 'Action'),
+    ('adventure', 'Adventure'),
+    ('comedy', 'Comedy'),
+    ('crime', 'Crime'),
+    ('documentary', 'Documentary'),
+    ('drama', 'Drama'),
+    ('fantasy', 'Fantasy'),
+    ('historical', 'Historical'),
+    ('horror', 'Horror'),
+    ('mystery', 'Mystery'),
+    ('romance', 'Romance'),
+    ('science-fiction', 'Science Fiction'),
+    ('slice-of-life', 'Slice of Life'),
+    ('thriller', 'Thriller'),
+    ('non-fiction', 'Non-fiction'),
+    ('role-playing', 'Role-playing'),
+    ('strategy', 'Strategy'),
+    ('puzzle', 'Puzzle');
+
+-- Переносим жанры и теги, уже записанные в metadata. Слаг строится так же, как utils.Slugify.
+INSERT INTO genres (slug, name)
+SELECT DISTINCT ON (slug) slug, name
+FROM (
+    SELECT g.name, trim(both '-' from regexp_replace(lower(g.name), '[^[:alnum:]]+', '-', 'g')) AS slug
+    FROM media_items m
+    CROSS JOIN LATERAL jsonb_array_elements_text(m.metadata -> 'genres') AS g(name)
+) AS media_genre_names
+WHERE slug <> ''
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO media_genres (media_id, genre_id)
+SELECT DISTINCT m.media_id, gr.genre_id
+FROM media_items m
+CROSS JOIN LATERAL jsonb_array_elements_text(m.metadata -> 'genres') AS g(name)
+JOIN genres gr ON gr.slug = trim(both '-' from regexp_replace(lower(g.name), '[^[:alnum:]]+', '-', 'g'));
+
+INSERT INTO tags (slug, name)
+SELECT DISTINCT ON (slug) slug, name
+FROM (
+    SELECT t.name, trim(both '-' from regexp_replace(lower(t.name), '[^[:alnum:]]+', '-', 'g')) AS slug
+    FROM media_items m
+    CROSS JOIN LATERAL jsonb_array_elements_text(m.metadata -> 'tags') AS t(name)
+) AS media_tag_names
+WHERE slug <> ''
+ON CONFLICT (slug) DO NOTHING;
+
+INSERT INTO media_tags (media_id, tag_id)
+SELECT DISTINCT m.media_id, tg.tag_id
+FROM media_items m
+CROSS JOIN LATERAL jsonb_array_elements_text(m.metadata -> 'tags') AS t(name)
+JOIN tags tg ON tg.slug = trim(both '-' from regexp_replace(lower(t.name), '[^[:alnum:]]+', '-', 'g'));
+
+UPDATE media_items SET metadata = metadata - 'genres' - 'tags' WHERE metadata ?| ARRAY['genres', 'tags'];
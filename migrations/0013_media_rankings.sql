@@ -0,0 +1,19 @@
+-- Рейтинги медиа для /media/trending и /media/popular. Считаются по таблице
+-- recommendations фоновой задачей и перезаписываются целиком, чтобы запросы
+-- к рейтингу не агрегировали рекомендации на лету.
+CREATE TABLE media_rankings (
+    ranking              TEXT NOT NULL CHECK (ranking IN ('trending', 'popular')),
+    time_window          TEXT NOT NULL CHECK (time_window IN ('24h', '7d', '30d', 'all')),
+    media_id             INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    item_type            TEXT NOT NULL,
+    -- overall_rank - место среди всех типов, type_rank - среди медиа того же типа
+    overall_rank         INTEGER NOT NULL,
+    type_rank            INTEGER NOT NULL,
+    score                DOUBLE PRECISION NOT NULL,
+    recommendation_count BIGINT NOT NULL,
+    computed_at          TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (ranking, time_window, media_id)
+);
+
+CREATE INDEX media_rankings_overall_idx ON media_rankings (ranking, time_window, overall_rank);
+CREATE INDEX media_rankings_type_idx ON media_rankings (ranking, time_window, item_type, type_rank);
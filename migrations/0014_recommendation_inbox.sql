@@ -0,0 +1,10 @@
+-- Состояние полученной рекомендации во "входящих" получателя.
+-- У рекомендации один получатель, поэтому состояние хранится прямо в строке.
+-- snoozed_until: до этого момента рекомендация скрыта из входящих, потом возвращается непрочитанной.
+ALTER TABLE recommendations
+    ADD COLUMN read_at       TIMESTAMPTZ,
+    ADD COLUMN archived_at   TIMESTAMPTZ,
+    ADD COLUMN snoozed_until TIMESTAMPTZ;
+
+-- Для счетчика непрочитанных и списка входящих
+CREATE INDEX recommendations_inbox_idx ON recommendations (to_user_id, created_at DESC) WHERE archived_at IS NULL;
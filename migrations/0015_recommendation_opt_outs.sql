@@ -0,0 +1,18 @@
+-- Получатель может скрыть рекомендацию или отклонить ее.
+-- Скрытая пропадает только у получателя, у отправителя она остается в списке отправленных.
+-- Отклоненная тоже скрывается, а отправитель видит статус declined.
+ALTER TABLE recommendations
+    ADD COLUMN hidden_at   TIMESTAMPTZ,
+    ADD COLUMN declined_at TIMESTAMPTZ;
+
+ALTER TABLE recommendations DROP CONSTRAINT recommendations_status_check;
+ALTER TABLE recommendations
+    ADD CONSTRAINT recommendations_status_check CHECK (status IN ('pending', 'completed', 'declined'));
+
+-- "Больше не рекомендуйте мне это": новые рекомендации медиа пользователю запрещены
+CREATE TABLE recommendation_opt_outs (
+    user_id    INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    media_id   INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, media_id)
+);
@@ -0,0 +1,26 @@
+-- Ссылки, которыми пользователь делится медиа (одним или списком) с кем угодно,
+-- в том числе с теми, кто не в друзьях. Ссылка подписана, срок есть и в подписи, и здесь.
+CREATE TABLE share_links (
+    share_id      UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id      INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    note          TEXT NOT NULL DEFAULT '',
+    -- prompt_follow: предложить открывшему подписаться на автора
+    prompt_follow BOOLEAN NOT NULL DEFAULT FALSE,
+    expires_at    TIMESTAMPTZ NOT NULL,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at    TIMESTAMPTZ
+);
+
+CREATE INDEX share_links_owner_idx ON share_links (owner_id, created_at DESC);
+
+CREATE TABLE share_link_media (
+    share_id UUID NOT NULL REFERENCES share_links (share_id) ON DELETE CASCADE,
+    media_id INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (share_id, media_id)
+);
+
+-- Принятая ссылка становится обычной полученной рекомендацией с заметкой автора
+ALTER TABLE recommendations
+    ADD COLUMN note     TEXT NOT NULL DEFAULT '',
+    ADD COLUMN share_id UUID REFERENCES share_links (share_id) ON DELETE SET NULL;
@@ -0,0 +1,46 @@
+-- Круги - именованные группы друзей ("Аниме-клуб", "Семья").
+-- Рекомендация в круг создает пост в ленте круга и расходится участникам
+-- обычными полученными рекомендациями.
+CREATE TABLE circles (
+    circle_id   SERIAL PRIMARY KEY,
+    name        TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Ровно один owner на круг; admin управляет участниками, member публикует и реагирует
+CREATE TABLE circle_members (
+    circle_id INTEGER NOT NULL REFERENCES circles (circle_id) ON DELETE CASCADE,
+    user_id   INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    role      TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (circle_id, user_id)
+);
+
+CREATE INDEX circle_members_user_idx ON circle_members (user_id);
+CREATE UNIQUE INDEX circle_members_owner_idx ON circle_members (circle_id) WHERE role = 'owner';
+
+CREATE TABLE circle_posts (
+    post_id    BIGSERIAL PRIMARY KEY,
+    circle_id  INTEGER NOT NULL REFERENCES circles (circle_id) ON DELETE CASCADE,
+    -- NULL у постов стертых аккаунтов
+    author_id  INTEGER REFERENCES users (user_id) ON DELETE SET NULL,
+    media_id   INTEGER NOT NULL REFERENCES media_items (media_id) ON DELETE CASCADE,
+    note       TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX circle_posts_feed_idx ON circle_posts (circle_id, created_at DESC);
+
+-- Одна реакция участника на пост
+CREATE TABLE circle_reactions (
+    post_id    BIGINT NOT NULL REFERENCES circle_posts (post_id) ON DELETE CASCADE,
+    user_id    INTEGER NOT NULL REFERENCES users (user_id) ON DELETE CASCADE,
+    reaction   TEXT NOT NULL CHECK (reaction IN ('like', 'love', 'interested', 'seen', 'not_for_me')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (post_id, user_id)
+);
+
+-- Рекомендации, разошедшиеся из поста в круге
+ALTER TABLE recommendations
+    ADD COLUMN circle_post_id BIGINT REFERENCES circle_posts (post_id) ON DELETE SET NULL;
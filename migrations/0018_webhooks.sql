@@ -0,0 +1,53 @@
+-- Вебхуки для внешних интеграций (боты, умный дом). owner_id NULL - вебхук
+-- администратора: получает события всех пользователей.
+CREATE TABLE webhooks (
+    webhook_id  BIGSERIAL PRIMARY KEY,
+    owner_id    INTEGER REFERENCES users (user_id) ON DELETE CASCADE,
+    url         TEXT NOT NULL,
+    -- secret подписывает тело запроса (HMAC-SHA256), поэтому хранится как есть
+    secret      TEXT NOT NULL,
+    events      TEXT[] NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    active      BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhooks_owner_idx ON webhooks (owner_id);
+
+-- Outbox: события пишутся в той же транзакции, что и действие, и раздаются
+-- вебхукам фоновой задачей. Так событие не теряется при падении процесса.
+CREATE TABLE webhook_events (
+    event_id      BIGSERIAL PRIMARY KEY,
+    event_type    TEXT NOT NULL,
+    -- user_id - чье это событие; по нему выбираются вебхуки пользователя.
+    -- Внешнего ключа нет: вебхуки администраторов получают событие и после стирания аккаунта
+    user_id       INTEGER NOT NULL,
+    payload       JSONB NOT NULL DEFAULT '{}',
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now(),
+    dispatched_at TIMESTAMPTZ
+);
+
+CREATE INDEX webhook_events_pending_idx ON webhook_events (event_id) WHERE dispatched_at IS NULL;
+CREATE INDEX webhook_events_created_at_idx ON webhook_events (created_at);
+
+-- Журнал доставок: одна строка на пару вебхук-событие (и на каждую ручную переотправку)
+CREATE TABLE webhook_deliveries (
+    delivery_id     BIGSERIAL PRIMARY KEY,
+    webhook_id      BIGINT NOT NULL REFERENCES webhooks (webhook_id) ON DELETE CASCADE,
+    event_id        BIGINT NOT NULL REFERENCES webhook_events (event_id) ON DELETE CASCADE,
+    redelivery_of   BIGINT REFERENCES webhook_deliveries (delivery_id) ON DELETE SET NULL,
+    status          TEXT NOT NULL DEFAULT 'pending'
+                    CHECK (status IN ('pending', 'succeeded', 'failed')),
+    attempts        INTEGER NOT NULL DEFAULT 0,
+    -- next_attempt_at у взятой в работу доставки сдвигается на время аренды:
+    -- если процесс упадет во время отправки, доставка повторится
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_attempt_at TIMESTAMPTZ,
+    response_status INTEGER,
+    last_error      TEXT NOT NULL DEFAULT '',
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX webhook_deliveries_webhook_idx ON webhook_deliveries (webhook_id, created_at DESC);
@@ -0,0 +1,60 @@
+-- Фоновые задачи. Сервисы ставят задачу в той же транзакции, что и действие,
+-- поэтому задача появляется только вместе с результатом действия (transactional outbox).
+-- Обработчики берут задачи через FOR UPDATE SKIP LOCKED и могут работать в нескольких экземплярах.
+CREATE TABLE jobs (
+    job_id       BIGSERIAL PRIMARY KEY,
+    kind         TEXT NOT NULL,
+    payload      JSONB NOT NULL DEFAULT '{}',
+    status       TEXT NOT NULL DEFAULT 'pending'
+                 CHECK (status IN ('pending', 'running', 'succeeded', 'failed')),
+    attempts     INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    run_at       TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- locked_until - до какого момента задача принадлежит взявшему ее обработчику;
+    -- если он упадет, после этого срока задачу возьмет другой
+    locked_until TIMESTAMPTZ,
+    last_error   TEXT NOT NULL DEFAULT '',
+    -- unique_key не дает поставить вторую незаконченную задачу того же вида
+    -- (например, повторный запуск периодической задачи, пока идет предыдущий)
+    unique_key   TEXT,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    finished_at  TIMESTAMPTZ
+);
+
+CREATE INDEX jobs_due_idx ON jobs (run_at) WHERE status = 'pending';
+
+-- Раздача и доставка вебхуков тоже идут задачами. Обоим видам max_attempts -
+-- значение webhooks.max_attempts по умолчанию (8); попытки доставок сохраняются,
+-- и у доставки, уже исчерпавшей их, остается еще одна
+INSERT INTO jobs (kind, payload, max_attempts)
+SELECT 'webhook.dispatch', jsonb_build_object('event_id', event_id), 8
+FROM webhook_events
+WHERE dispatched_at IS NULL;
+INSERT INTO jobs (kind, payload, attempts, max_attempts, run_at)
+SELECT 'webhook.deliver', jsonb_build_object('delivery_id', delivery_id), attempts, GREATEST(attempts + 1, 8), next_attempt_at
+FROM webhook_deliveries
+WHERE status = 'pending';
+
+DROP INDEX webhook_events_pending_idx;
+DROP INDEX webhook_deliveries_due_idx;
+
+CREATE INDEX jobs_locked_idx ON jobs (locked_until) WHERE status = 'running';
+CREATE INDEX jobs_finished_idx ON jobs (finished_at) WHERE finished_at IS NOT NULL;
+CREATE UNIQUE INDEX jobs_unique_key_idx ON jobs (kind, unique_key)
+    WHERE unique_key IS NOT NULL AND status IN ('pending', 'running');
+
+-- Периодические задачи по расписанию cron. Строка блокируется при постановке
+-- очередного запуска, поэтому при нескольких экземплярах задача ставится один раз.
+CREATE TABLE job_schedules (
+    name        TEXT PRIMARY KEY,
+    spec        TEXT NOT NULL,
+    next_run_at TIMESTAMPTZ NOT NULL,
+    last_run_at TIMESTAMPTZ
+);
+
+-- Выгрузки теперь собираются задачами; незаконченные переносим в очередь задач
+UPDATE data_exports SET status = 'pending' WHERE status = 'running';
+INSERT INTO jobs (kind, payload, max_attempts)
+SELECT 'data_export.build', jsonb_build_object('export_id', export_id), 3
+FROM data_exports
+WHERE status = 'pending';
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// AuditAction - тип события в журнале безопасности.
+type AuditAction string
+
+const (
+	AuditUserRegistered        AuditAction = "user.registered"
+	AuditLoginSucceeded        AuditAction = "auth.login_succeeded"
+	AuditLoginFailed           AuditAction = "auth.login_failed"
+	AuditPasswordChanged       AuditAction = "user.password_changed"
+	AuditAccountDeleted        AuditAction = "user.deleted"
+	AuditAccountDeactivated    AuditAction = "user.deactivated"
+	AuditFollowDeleted         AuditAction = "follow.deleted"
+	AuditRecommendationDeleted AuditAction = "recommendation.deleted"
+)
+
+// Типы объектов, над которыми совершено действие.
+const (
+	AuditTargetUser           = "user"
+	AuditTargetRecommendation = "recommendation"
+)
+
+type AuditEvent struct {
+	ID         int64       `db:"event_id"`
+	OccurredAt time.Time   `db:"occurred_at"`
+	ActorID    *int        `db:"actor_id"`
+	Action     AuditAction `db:"action"`
+	TargetType string      `db:"target_type"`
+	TargetID   *int        `db:"target_id"`
+	IPAddress  string      `db:"ip_address"`
+	RequestID  string      `db:"request_id"`
+	// Metadata - подробности события, хранится в jsonb
+	Metadata map[string]any `db:"metadata"`
+}
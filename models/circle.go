@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// CircleRole - роль участника круга.
+type CircleRole string
+
+const (
+	// CircleRoleOwner - создатель круга; единственный может удалить круг и менять роли
+	CircleRoleOwner CircleRole = "owner"
+	// CircleRoleAdmin управляет участниками и описанием круга
+	CircleRoleAdmin  CircleRole = "admin"
+	CircleRoleMember CircleRole = "member"
+)
+
+// Valid сообщает, известна ли роль.
+func (r CircleRole) Valid() bool {
+	switch r {
+	case CircleRoleOwner, CircleRoleAdmin, CircleRoleMember:
+		return true
+	}
+	return false
+}
+
+// AtLeast сообщает, дает ли роль права не ниже min.
+func (r CircleRole) AtLeast(min CircleRole) bool {
+	return r.rank() >= min.rank()
+}
+
+func (r CircleRole) rank() int {
+	switch r {
+	case CircleRoleOwner:
+		return 3
+	case CircleRoleAdmin:
+		return 2
+	case CircleRoleMember:
+		return 1
+	}
+	return 0
+}
+
+// CircleReaction - реакция участника на пост в круге.
+type CircleReaction string
+
+const (
+	ReactionLike       CircleReaction = "like"
+	ReactionLove       CircleReaction = "love"
+	ReactionInterested CircleReaction = "interested"
+	ReactionSeen       CircleReaction = "seen"
+	ReactionNotForMe   CircleReaction = "not_for_me"
+)
+
+// Valid сообщает, известна ли реакция.
+func (r CircleReaction) Valid() bool {
+	switch r {
+	case ReactionLike, ReactionLove, ReactionInterested, ReactionSeen, ReactionNotForMe:
+		return true
+	}
+	return false
+}
+
+type Circle struct {
+	ID          int       `db:"circle_id"`
+	Name        string    `db:"name"`
+	Description string    `db:"description"`
+	CreatedAt   time.Time `db:"created_at"`
+	// MemberCount и MyRole заполняются в списках кругов пользователя
+	MemberCount int        `db:"member_count"`
+	MyRole      CircleRole `db:"role"`
+}
+
+type CircleMember struct {
+	User     User
+	Role     CircleRole `db:"role"`
+	JoinedAt time.Time  `db:"joined_at"`
+}
+
+// CirclePost - рекомендация медиа всему кругу, элемент ленты круга.
+type CirclePost struct {
+	ID       int64 `db:"post_id"`
+	CircleID int   `db:"circle_id"`
+	// Author пустой (ID = 0) у постов стертых аккаунтов
+	Author    User
+	Media     MediaItem
+	Note      string    `db:"note"`
+	CreatedAt time.Time `db:"created_at"`
+	Reactions []CirclePostReaction
+	// RecipientCount - скольким участникам разошлась рекомендация; заполняется при публикации
+	RecipientCount int64
+}
+
+type CirclePostReaction struct {
+	User      User
+	Reaction  CircleReaction `db:"reaction"`
+	CreatedAt time.Time      `db:"created_at"`
+}
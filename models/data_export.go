@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+type ExportStatus string
+
+const (
+	ExportPending ExportStatus = "pending"
+	ExportRunning ExportStatus = "running"
+	ExportReady   ExportStatus = "ready"
+	ExportFailed  ExportStatus = "failed"
+	// ExportExpired - архив удален по истечении срока хранения
+	ExportExpired ExportStatus = "expired"
+)
+
+// DataExport - запрос пользователя на выгрузку его данных.
+type DataExport struct {
+	ID          string       `db:"export_id"`
+	UserID      int          `db:"user_id"`
+	Status      ExportStatus `db:"status"`
+	FilePath    string       `db:"file_path"`
+	CreatedAt   time.Time    `db:"created_at"`
+	CompletedAt *time.Time   `db:"completed_at"`
+	ExpiresAt   *time.Time   `db:"expires_at"`
+}
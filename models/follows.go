@@ -8,3 +8,9 @@ type Follow struct {
 	FollowingID int       `db:"following_id"`
 	CreatedAt   time.Time `db:"created_at"`
 }
+
+// FollowDetails - подписка вместе с данными второго пользователя.
+type FollowDetails struct {
+	User      User
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// UserIdentity - учетная запись внешнего провайдера (Google, GitHub, OIDC), привязанная к пользователю.
+type UserIdentity struct {
+	ID        int       `db:"identity_id"`
+	UserID    int       `db:"user_id"`
+	Provider  string    `db:"provider"`
+	Subject   string    `db:"subject"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
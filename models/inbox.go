@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// InboxState - фильтр полученных рекомендаций по состоянию во входящих.
+type InboxState string
+
+const (
+	// InboxActive - все, что сейчас во входящих: не в архиве и не отложено
+	InboxActive   InboxState = "inbox"
+	InboxUnread   InboxState = "unread"
+	InboxRead     InboxState = "read"
+	InboxSnoozed  InboxState = "snoozed"
+	InboxArchived InboxState = "archived"
+	// InboxHidden - скрытые и отклоненные получателем; в остальные состояния не попадают
+	InboxHidden InboxState = "hidden"
+)
+
+// Valid сообщает, известно ли состояние.
+func (s InboxState) Valid() bool {
+	switch s {
+	case InboxActive, InboxUnread, InboxRead, InboxSnoozed, InboxArchived, InboxHidden:
+		return true
+	}
+	return false
+}
+
+// RecommendationOptOut - правило "больше не рекомендуйте мне это медиа".
+type RecommendationOptOut struct {
+	Media     MediaItem
+	CreatedAt time.Time `db:"created_at"`
+}
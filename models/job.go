@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	// JobFailed - попытки закончились
+	JobFailed JobStatus = "failed"
+)
+
+// Job - фоновая задача из таблицы jobs.
+type Job struct {
+	ID      int64     `db:"job_id"`
+	Kind    string    `db:"kind"`
+	Payload []byte    `db:"payload"`
+	Status  JobStatus `db:"status"`
+	// Attempts - номер текущей попытки, считая ее саму
+	Attempts    int       `db:"attempts"`
+	MaxAttempts int       `db:"max_attempts"`
+	RunAt       time.Time `db:"run_at"`
+	LastError   string    `db:"last_error"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// LastAttempt сообщает, что после неудачи этой попытки задача больше не повторится.
+func (j Job) LastAttempt() bool {
+	return j.Attempts >= j.MaxAttempts
+}
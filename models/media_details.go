@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// MediaRecommendation - рекомендация медиа с отправителем и получателем.
+type MediaRecommendation struct {
+	RecommendationID int  `db:"recommendation_id"`
+	From             User // Пустой (ID = 0) для анонимизированных рекомендаций
+	To               User
+	Status           RecommendationStatus `db:"status"`
+	CreatedAt        time.Time            `db:"created_at"`
+}
+
+// MediaDetails - страница медиа глазами конкретного пользователя.
+type MediaDetails struct {
+	Media MediaItem
+	// FriendRecommendations - кому рекомендовали это медиа друзья пользователя
+	FriendRecommendations []MediaRecommendation
+	SentByMe              []MediaRecommendation
+	ReceivedByMe          []MediaRecommendation
+	// Library - запись в библиотеке пользователя, nil если медиа там нет
+	Library *UserMedia
+	Ratings MediaRatings
+	// RecommendedToCount - скольким пользователям рекомендовали медиа
+	RecommendedToCount int64
+}
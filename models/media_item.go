@@ -1,12 +1,96 @@
 package models
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type MediaItem struct {
-	ID        int       `db:"media_id"`
-	Type      MediaType `db:"item_type"`
-	Name      string    `db:"name"`
-	Year      int       `db:"year"`
-	Author    string    `db:"author"`
-	CreatedAt time.Time `db:"created_at"`
-}
\ No newline at end of file
+	ID   int       `db:"media_id"`
+	Type MediaType `db:"item_type"`
+	Name string    `db:"name"`
+	Year int       `db:"year"`
+	// Author - основной создатель одной строкой (режиссер, автор, студия), полный список - в Creators
+	Author        string        `db:"author"`
+	OriginalTitle string        `db:"original_title"`
+	Description   string        `db:"description"`
+	CoverURL      string        `db:"cover_url"`
+	Metadata      MediaMetadata `db:"metadata"`
+	Creators      MediaCreators // Из таблицы media_creators, в порядке указания
+	Genres        Labels        // Слаги жанров из media_genres
+	Tags          Labels        // Слаги одобренных тегов из media_tags
+	CreatedAt     time.Time     `db:"created_at"`
+}
+
+// MediaMetadata хранится в media_items.metadata (jsonb). Какие поля допустимы,
+// зависит от типа медиа: длительность - у фильмов, серии - у аниме и сериалов,
+// страницы - у книг, платформы - у игр. Жанры и теги хранятся в отдельных таблицах.
+type MediaMetadata struct {
+	AlternativeTitles []string `json:"alternative_titles,omitempty"`
+	RuntimeMinutes    int      `json:"runtime_minutes,omitempty"`
+	EpisodeCount      int      `json:"episode_count,omitempty"`
+	PageCount         int      `json:"page_count,omitempty"`
+	Platforms         []string `json:"platforms,omitempty"`
+	// ExternalIDs - идентификаторы во внешних каталогах: imdb, mal, isbn, steam и т.д.
+	ExternalIDs map[string]string `json:"external_ids,omitempty"`
+}
+
+func (m MediaMetadata) Value() (driver.Value, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (m *MediaMetadata) Scan(src any) error {
+	*m = MediaMetadata{}
+	return scanJSON(src, m)
+}
+
+type CreatorRole string
+
+const (
+	CreatorDirector       CreatorRole = "director"
+	CreatorWriter         CreatorRole = "writer"
+	CreatorProducer       CreatorRole = "producer"
+	CreatorShowrunner     CreatorRole = "showrunner"
+	CreatorActor          CreatorRole = "actor"
+	CreatorVoiceActor     CreatorRole = "voice_actor"
+	CreatorComposer       CreatorRole = "composer"
+	CreatorStudio         CreatorRole = "studio"
+	CreatorOriginalAuthor CreatorRole = "original_author"
+	CreatorAuthor         CreatorRole = "author"
+	CreatorIllustrator    CreatorRole = "illustrator"
+	CreatorTranslator     CreatorRole = "translator"
+	CreatorPublisher      CreatorRole = "publisher"
+	CreatorDeveloper      CreatorRole = "developer"
+)
+
+type MediaCreator struct {
+	Name string      `json:"name"`
+	Role CreatorRole `json:"role"`
+}
+
+// MediaCreators сканируется из jsonb-массива, который собирает запрос.
+type MediaCreators []MediaCreator
+
+func (c *MediaCreators) Scan(src any) error {
+	*c = MediaCreators{}
+	return scanJSON(src, c)
+}
+
+func scanJSON(src, dest any) error {
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", src, dest)
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// RankingKind - способ ранжирования медиа.
+type RankingKind string
+
+const (
+	// RankingTrending учитывает свежесть: рекомендация в начале окна весит почти 0, только что сделанная - 1
+	RankingTrending RankingKind = "trending"
+	// RankingPopular - просто число рекомендаций за окно
+	RankingPopular RankingKind = "popular"
+)
+
+// RankingWindow - период, за который считаются рекомендации.
+type RankingWindow string
+
+const (
+	Window24h RankingWindow = "24h"
+	Window7d  RankingWindow = "7d"
+	Window30d RankingWindow = "30d"
+	WindowAll RankingWindow = "all"
+)
+
+// Duration возвращает длину окна; 0 - за все время.
+func (w RankingWindow) Duration() time.Duration {
+	switch w {
+	case Window24h:
+		return 24 * time.Hour
+	case Window7d:
+		return 7 * 24 * time.Hour
+	case Window30d:
+		return 30 * 24 * time.Hour
+	default:
+		return 0
+	}
+}
+
+// Windows возвращает окна, для которых считается рейтинг. У trending нет окна
+// "за все время": свежесть без ограничения по времени не имеет смысла.
+func (k RankingKind) Windows() []RankingWindow {
+	if k == RankingTrending {
+		return []RankingWindow{Window24h, Window7d, Window30d}
+	}
+	return []RankingWindow{Window24h, Window7d, Window30d, WindowAll}
+}
+
+// RankedMedia - медиа на своем месте в рейтинге.
+type RankedMedia struct {
+	Rank                int `db:"rank"`
+	Media               MediaItem
+	Score               float64 `db:"score"`
+	RecommendationCount int64   `db:"recommendation_count"`
+}
@@ -8,4 +8,5 @@ type Recommendation struct {
 	ToUserID     int       `db:"to_user_id"`   
 	MediaID      int       `db:"media_id"`     
 	CreatedAt    time.Time `db:"created_at"`
+	Status       RecommendationStatus `db:"status"`
 }
\ No newline at end of file
@@ -7,4 +7,13 @@ type RecommendationDetails struct {
 	Media            MediaItem // Вложенная структура для информации о медиа
 	User             User      // Вложенная структура для информации о втором пользователе
 	CreatedAt        time.Time `db:"created_at"`
+	// Status становится completed, когда получатель завершил медиа в своей библиотеке
+	Status RecommendationStatus `db:"status"`
+	// Note - заметка отправителя, например из принятой ссылки
+	Note string `db:"note"`
+	// Состояние во входящих получателя; заполняется только в его списке полученных
+	ReadAt       *time.Time `db:"read_at"`
+	ArchivedAt   *time.Time `db:"archived_at"`
+	SnoozedUntil *time.Time `db:"snoozed_until"`
+	HiddenAt     *time.Time `db:"hidden_at"`
 }
\ No newline at end of file
@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+type ReportTargetType string
+
+const (
+	ReportTargetUser           ReportTargetType = "user"
+	ReportTargetRecommendation ReportTargetType = "recommendation"
+	ReportTargetMedia          ReportTargetType = "media"
+)
+
+type ReportReason string
+
+const (
+	ReportReasonSpam          ReportReason = "spam"
+	ReportReasonHarassment    ReportReason = "harassment"
+	ReportReasonInappropriate ReportReason = "inappropriate"
+	ReportReasonImpersonation ReportReason = "impersonation"
+	ReportReasonOther         ReportReason = "other"
+)
+
+type ReportStatus string
+
+const (
+	ReportOpen      ReportStatus = "open"
+	ReportResolved  ReportStatus = "resolved"
+	ReportDismissed ReportStatus = "dismissed"
+)
+
+type Report struct {
+	ID         int              `db:"report_id"`
+	ReporterID *int             `db:"reporter_id"`
+	TargetType ReportTargetType `db:"target_type"`
+	TargetID   int              `db:"target_id"`
+	Reason     ReportReason     `db:"reason"`
+	Details    string           `db:"details"`
+	Status     ReportStatus     `db:"status"`
+	// AssigneeID - модератор, который взял жалобу в работу
+	AssigneeID     *int       `db:"assignee_id"`
+	ResolutionNote string     `db:"resolution_note"`
+	CreatedAt      time.Time  `db:"created_at"`
+	ClosedAt       *time.Time `db:"closed_at"`
+}
+
+// ModerationActionType - действие модератора, которое записывается в журнал.
+type ModerationActionType string
+
+const (
+	ActionAssignReport         ModerationActionType = "assign_report"
+	ActionResolveReport        ModerationActionType = "resolve_report"
+	ActionDismissReport        ModerationActionType = "dismiss_report"
+	ActionSuspendUser          ModerationActionType = "suspend_user"
+	ActionUnsuspendUser        ModerationActionType = "unsuspend_user"
+	ActionSetUserRole          ModerationActionType = "set_user_role"
+	ActionRemoveRecommendation ModerationActionType = "remove_recommendation"
+)
+
+type ModerationAction struct {
+	ID          int                  `db:"action_id"`
+	ModeratorID int                  `db:"moderator_id"`
+	ReportID    *int                 `db:"report_id"`
+	Action      ModerationActionType `db:"action"`
+	TargetType  ReportTargetType     `db:"target_type"`
+	TargetID    int                  `db:"target_id"`
+	Note        string               `db:"note"`
+	CreatedAt   time.Time            `db:"created_at"`
+}
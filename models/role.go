@@ -0,0 +1,24 @@
+package models
+
+// Role определяет, какие действия доступны пользователю.
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+// Valid сообщает, известна ли роль.
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// IsStaff - модераторы и администраторы. Блокировать их может только администратор.
+func (r Role) IsStaff() bool {
+	return r == RoleModerator || r == RoleAdmin
+}
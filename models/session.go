@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserSession - вход пользователя с конкретного устройства.
+type UserSession struct {
+	ID         string     `db:"session_id"`
+	UserID     int        `db:"user_id"`
+	Device     string     `db:"device"`
+	UserAgent  string     `db:"user_agent"`
+	IPAddress  string     `db:"ip_address"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastSeenAt time.Time  `db:"last_seen_at"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+}
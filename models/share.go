@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ShareLink - ссылка, по которой любой может посмотреть подборку медиа,
+// а вошедший пользователь - принять ее как полученные рекомендации.
+type ShareLink struct {
+	ID      string `db:"share_id"`
+	OwnerID int    `db:"owner_id"`
+	Owner   User
+	Note    string `db:"note"`
+	// PromptFollow - предложить открывшему подписаться на автора
+	PromptFollow bool       `db:"prompt_follow"`
+	ExpiresAt    time.Time  `db:"expires_at"`
+	CreatedAt    time.Time  `db:"created_at"`
+	RevokedAt    *time.Time `db:"revoked_at"`
+	// Media - в порядке, заданном автором
+	Media []MediaItem
+}
+
+// Active сообщает, можно ли еще открыть ссылку.
+func (l ShareLink) Active(now time.Time) bool {
+	return l.RevokedAt == nil && now.Before(l.ExpiresAt)
+}
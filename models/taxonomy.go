@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// TaxonomyKind - вид классификации медиа: жанры ведут модераторы,
+// теги пользователи предлагают сами.
+type TaxonomyKind string
+
+const (
+	TaxonomyGenre TaxonomyKind = "genre"
+	TaxonomyTag   TaxonomyKind = "tag"
+)
+
+// Term - жанр или тег.
+type Term struct {
+	ID   int    `db:"term_id"`
+	Slug string `db:"slug"`
+	Name string `db:"name"`
+	// MediaCount заполняется только в списках терминов
+	MediaCount int64     `db:"media_count"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// Labels - слаги жанров или тегов медиа, сканируются из jsonb-массива.
+type Labels []string
+
+func (l *Labels) Scan(src any) error {
+	*l = Labels{}
+	return scanJSON(src, l)
+}
+
+type TagSuggestionStatus string
+
+const (
+	TagSuggestionPending  TagSuggestionStatus = "pending"
+	TagSuggestionApproved TagSuggestionStatus = "approved"
+	TagSuggestionRejected TagSuggestionStatus = "rejected"
+)
+
+// TagSuggestion - тег, предложенный пользователем для медиа. Тег появляется
+// у медиа только после одобрения модератором.
+type TagSuggestion struct {
+	ID      int                 `db:"suggestion_id"`
+	MediaID int                 `db:"media_id"`
+	UserID  *int                `db:"user_id"`
+	Name    string              `db:"name"`
+	Slug    string              `db:"slug"`
+	Status  TagSuggestionStatus `db:"status"`
+	// ReviewerID - модератор, принявший решение
+	ReviewerID *int       `db:"reviewer_id"`
+	CreatedAt  time.Time  `db:"created_at"`
+	ReviewedAt *time.Time `db:"reviewed_at"`
+}
+
+// MediaPopularity - медиа с числом рекомендаций, для подборок и рейтингов.
+type MediaPopularity struct {
+	Media               MediaItem
+	RecommendationCount int64 `db:"recommendation_count"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// UserTOTP - настройка двухфакторной аутентификации пользователя.
+// ConfirmedAt == nil, пока пользователь не подтвердил секрет первым кодом.
+type UserTOTP struct {
+	UserID         int        `db:"user_id"`
+	Secret         string     `db:"secret"`
+	ConfirmedAt    *time.Time `db:"confirmed_at"`
+	LastUsedStep   int64      `db:"last_used_step"`
+	FailedAttempts int        `db:"failed_attempts"`
+	LockedUntil    *time.Time `db:"locked_until"`
+	CreatedAt      time.Time  `db:"created_at"`
+}
+
+func (t UserTOTP) Enabled() bool {
+	return t.ConfirmedAt != nil
+}
@@ -8,4 +8,48 @@ const (
 	TypeBook   MediaType = "book"
 	TypeGame   MediaType = "game"
 	TypeSeries MediaType = "series"
-)
\ No newline at end of file
+)
+
+// Valid сообщает, известен ли тип медиа.
+func (t MediaType) Valid() bool {
+	switch t {
+	case TypeFilm, TypeAnime, TypeBook, TypeGame, TypeSeries:
+		return true
+	}
+	return false
+}
+
+// CreatorRoles - роли создателей, допустимые для медиа этого типа.
+// Первая роль - основная: ее создатель попадает в поле author.
+func (t MediaType) CreatorRoles() []CreatorRole {
+	switch t {
+	case TypeFilm:
+		return []CreatorRole{CreatorDirector, CreatorWriter, CreatorProducer, CreatorActor, CreatorComposer, CreatorStudio}
+	case TypeAnime:
+		return []CreatorRole{CreatorStudio, CreatorDirector, CreatorOriginalAuthor, CreatorWriter, CreatorVoiceActor, CreatorComposer}
+	case TypeSeries:
+		return []CreatorRole{CreatorShowrunner, CreatorDirector, CreatorWriter, CreatorProducer, CreatorActor, CreatorComposer, CreatorStudio}
+	case TypeBook:
+		return []CreatorRole{CreatorAuthor, CreatorIllustrator, CreatorTranslator, CreatorPublisher}
+	case TypeGame:
+		return []CreatorRole{CreatorDeveloper, CreatorPublisher, CreatorDirector, CreatorWriter, CreatorComposer}
+	default:
+		return nil
+	}
+}
+
+// ExternalIDSources - каталоги, на идентификаторы в которых можно ссылаться для медиа этого типа.
+func (t MediaType) ExternalIDSources() []string {
+	switch t {
+	case TypeFilm, TypeSeries:
+		return []string{"imdb", "tmdb", "wikidata"}
+	case TypeAnime:
+		return []string{"mal", "anilist", "anidb", "imdb", "wikidata"}
+	case TypeBook:
+		return []string{"isbn", "openlibrary", "goodreads", "wikidata"}
+	case TypeGame:
+		return []string{"igdb", "steam", "wikidata"}
+	default:
+		return nil
+	}
+}
@@ -3,9 +3,19 @@ package models
 import "time"
 
 type User struct {
-	ID           int       `db:"user_id"`
-	UserName     string    `db:"user_name"`
-	Email        string    `db:"email"`
-	PasswordHash []byte    `db:"password_hash"`
-	CreatedAt    time.Time `db:"created_at"`
-}
\ No newline at end of file
+	ID           int    `db:"user_id"`
+	UserName     string `db:"user_name"`
+	Email        string `db:"email"`
+	PasswordHash []byte `db:"password_hash"`
+	// PasswordLoginEnabled = false для аккаунтов, созданных через внешнего провайдера
+	PasswordLoginEnabled bool      `db:"password_login_enabled"`
+	CreatedAt            time.Time `db:"created_at"`
+	// DeletedAt задан, пока удаленный аккаунт ждет окончательного стирания
+	DeletedAt *time.Time `db:"deleted_at"`
+	// DeactivatedAt задан, пока пользователь временно скрыл свой аккаунт
+	DeactivatedAt *time.Time `db:"deactivated_at"`
+	Role          Role       `db:"role"`
+	// SuspendedAt задан, пока аккаунт заблокирован модератором
+	SuspendedAt      *time.Time `db:"suspended_at"`
+	SuspensionReason string     `db:"suspension_reason"`
+}
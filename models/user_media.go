@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+type LibraryStatus string
+
+const (
+	LibraryPlanned    LibraryStatus = "planned"
+	LibraryInProgress LibraryStatus = "in_progress"
+	LibraryCompleted  LibraryStatus = "completed"
+	LibraryDropped    LibraryStatus = "dropped"
+)
+
+// Valid сообщает, известен ли статус.
+func (s LibraryStatus) Valid() bool {
+	switch s {
+	case LibraryPlanned, LibraryInProgress, LibraryCompleted, LibraryDropped:
+		return true
+	}
+	return false
+}
+
+// UserMedia - запись в личной библиотеке пользователя.
+type UserMedia struct {
+	UserID int           `db:"user_id"`
+	Media  MediaItem     // Вложенная структура для информации о медиа
+	Status LibraryStatus `db:"status"`
+	// Progress - в единицах Media.Type.ProgressUnit()
+	Progress   int        `db:"progress"`
+	StartedOn  *time.Time `db:"started_on"`
+	FinishedOn *time.Time `db:"finished_on"`
+	// Score - личная оценка от 1 до 10, nil - без оценки
+	Score     *int      `db:"score"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// ProgressUnit - в чем измеряется прогресс для медиа этого типа.
+func (t MediaType) ProgressUnit() string {
+	switch t {
+	case TypeAnime, TypeSeries:
+		return "episodes"
+	case TypeBook:
+		return "pages"
+	case TypeGame:
+		return "hours"
+	case TypeFilm:
+		return "minutes"
+	default:
+		return ""
+	}
+}
+
+type RecommendationStatus string
+
+const (
+	RecommendationPending   RecommendationStatus = "pending"
+	RecommendationCompleted RecommendationStatus = "completed"
+	// RecommendationDeclined - получатель отклонил рекомендацию
+	RecommendationDeclined RecommendationStatus = "declined"
+)
+
+// Valid сообщает, известен ли статус.
+func (s RecommendationStatus) Valid() bool {
+	switch s {
+	case RecommendationPending, RecommendationCompleted, RecommendationDeclined:
+		return true
+	}
+	return false
+}
+
+// MediaRatings - сводка по библиотекам пользователей для одного медиа.
+type MediaRatings struct {
+	// AverageScore - nil, пока никто не поставил оценку
+	AverageScore   *float64 `db:"average_score"`
+	ScoreCount     int64    `db:"score_count"`
+	LibraryCount   int64    `db:"library_count"`
+	CompletedCount int64    `db:"completed_count"`
+}
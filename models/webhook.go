@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// WebhookEventType - событие, на которое можно подписать вебхук.
+type WebhookEventType string
+
+const (
+	// WebhookRecommendationReceived - пользователю порекомендовали медиа
+	WebhookRecommendationReceived WebhookEventType = "recommendation.received"
+	// WebhookFollowerNew - на пользователя подписались
+	WebhookFollowerNew WebhookEventType = "follower.new"
+	// WebhookFriendNew - подписка стала взаимной; событие получают оба
+	WebhookFriendNew WebhookEventType = "friend.new"
+)
+
+// Valid сообщает, известно ли событие.
+func (t WebhookEventType) Valid() bool {
+	switch t {
+	case WebhookRecommendationReceived, WebhookFollowerNew, WebhookFriendNew:
+		return true
+	}
+	return false
+}
+
+// Webhook - адрес, на который отправляются события. OwnerID = nil у вебхуков
+// администраторов: они получают события всех пользователей.
+type Webhook struct {
+	ID          int64              `db:"webhook_id"`
+	OwnerID     *int               `db:"owner_id"`
+	URL         string             `db:"url"`
+	Secret      string             `db:"secret"`
+	Events      []WebhookEventType `db:"events"`
+	Description string             `db:"description"`
+	Active      bool               `db:"active"`
+	CreatedAt   time.Time          `db:"created_at"`
+	UpdatedAt   time.Time          `db:"updated_at"`
+}
+
+// WebhookEvent - запись outbox: событие, которое нужно разослать вебхукам.
+type WebhookEvent struct {
+	ID        int64            `db:"event_id"`
+	Type      WebhookEventType `db:"event_type"`
+	UserID    int              `db:"user_id"`
+	Payload   []byte           `db:"payload"`
+	CreatedAt time.Time        `db:"created_at"`
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// DeliveryFailed - попытки закончились или вебхук выключили
+	DeliveryFailed WebhookDeliveryStatus = "failed"
+)
+
+// WebhookDelivery - запись журнала доставок.
+type WebhookDelivery struct {
+	ID             int64 `db:"delivery_id"`
+	WebhookID      int64 `db:"webhook_id"`
+	Event          WebhookEvent
+	RedeliveryOf   *int64                `db:"redelivery_of"`
+	Status         WebhookDeliveryStatus `db:"status"`
+	Attempts       int                   `db:"attempts"`
+	NextAttemptAt  time.Time             `db:"next_attempt_at"`
+	LastAttemptAt  *time.Time            `db:"last_attempt_at"`
+	ResponseStatus *int                  `db:"response_status"`
+	LastError      string                `db:"last_error"`
+	CreatedAt      time.Time             `db:"created_at"`
+}
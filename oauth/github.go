@@ -0,0 +1,114 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// githubProvider - вход через GitHub. GitHub не поддерживает OpenID Connect,
+// поэтому личность пользователя берется из REST API по access token.
+type githubProvider struct {
+	name   string
+	apiURL string
+	config oauth2.Config
+}
+
+func newGitHubProvider(name, apiURL string, cfg oauth2.Config) *githubProvider {
+	if apiURL == "" {
+		apiURL = defaultGitHubAPIURL
+	}
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"read:user", "user:email"}
+	}
+	cfg.Endpoint = github.Endpoint
+	return &githubProvider{name: name, apiURL: strings.TrimRight(apiURL, "/"), config: cfg}
+}
+
+func (p *githubProvider) Name() string {
+	return p.name
+}
+
+// nonce в OAuth2 без id_token не используется: от подмены ответа защищают state и PKCE.
+func (p *githubProvider) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	return p.config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), nil
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, nonce, verifier string) (Identity, error) {
+	token, err := p.config.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("failed to exchange code: %w", err)
+	}
+	client := p.config.Client(ctx, token)
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	if err := p.get(ctx, client, "/user", &user); err != nil {
+		return Identity{}, err
+	}
+	if user.ID == 0 {
+		return Identity{}, errors.New("github user has no id")
+	}
+
+	// Публичный email в профиле может отсутствовать, поэтому берем основной подтвержденный
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.get(ctx, client, "/user/emails", &emails); err != nil {
+		return Identity{}, err
+	}
+
+	identity := Identity{
+		Provider: p.name,
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	return identity, nil
+}
+
+func (p *githubProvider) get(ctx context.Context, client *http.Client, path string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.apiURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("github request %s failed: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("github request %s failed with status %d", path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(dst); err != nil {
+		return fmt.Errorf("failed to decode github response %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/cobrich/recommendo/config"
+	"golang.org/x/oauth2"
+)
+
+var ErrUnknownProvider = errors.New("unknown login provider")
+
+// Identity - пользователь, как его видит внешний провайдер после успешного входа.
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider - внешний провайдер входа по схеме authorization code + PKCE.
+type Provider interface {
+	Name() string
+	// AuthCodeURL возвращает адрес страницы входа провайдера.
+	AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error)
+	// Exchange меняет код на токены и возвращает проверенную личность пользователя.
+	Exchange(ctx context.Context, code, nonce, verifier string) (Identity, error)
+}
+
+// Registry хранит настроенных провайдеров по имени.
+type Registry struct {
+	providers map[string]Provider
+}
+
+// NewRegistry создает провайдеров из конфига. Сетевые запросы (OIDC discovery)
+// откладываются до первого входа, чтобы недоступность провайдера не мешала старту.
+func NewRegistry(cfg config.OAuthConfig) (*Registry, error) {
+	r := &Registry{providers: make(map[string]Provider)}
+
+	for _, p := range cfg.Providers {
+		oauthCfg := oauth2.Config{
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret.Value(),
+			RedirectURL:  callbackURL(cfg.RedirectBaseURL, p.Name),
+			Scopes:       p.Scopes,
+		}
+
+		switch p.Type {
+		case "oidc":
+			r.providers[p.Name] = newOIDCProvider(p.Name, p.IssuerURL, oauthCfg)
+		case "github":
+			r.providers[p.Name] = newGitHubProvider(p.Name, p.APIURL, oauthCfg)
+		default:
+			return nil, fmt.Errorf("oauth provider %q: unknown type %q", p.Name, p.Type)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Registry) Get(name string) (Provider, error) {
+	p, ok := r.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	return p, nil
+}
+
+func callbackURL(baseURL, provider string) string {
+	return strings.TrimRight(baseURL, "/") + "/auth/" + url.PathEscape(provider) + "/callback"
+}
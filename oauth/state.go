@@ -0,0 +1,107 @@
+package oauth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+var ErrInvalidState = errors.New("invalid or expired login state")
+
+// LoginState - данные, которые нужно пережить между редиректом к провайдеру
+// и callback. Хранятся в подписанной cookie, поэтому серверу не нужно хранилище.
+type LoginState struct {
+	Provider string `json:"p"`
+	State    string `json:"s"`
+	Nonce    string `json:"n"`
+	Verifier string `json:"v"`
+	// LinkUserID != 0 означает, что пользователь привязывает провайдера к своему аккаунту,
+	// а не входит в систему.
+	LinkUserID int       `json:"l,omitempty"`
+	ExpiresAt  time.Time `json:"e"`
+}
+
+// StateCodec подписывает LoginState HMAC-SHA256, чтобы клиент не мог подменить
+// его содержимое (например, LinkUserID).
+type StateCodec struct {
+	secret []byte
+	ttl    time.Duration
+}
+
+func NewStateCodec(secret []byte, ttl time.Duration) *StateCodec {
+	return &StateCodec{secret: secret, ttl: ttl}
+}
+
+// NewState создает состояние со случайными state, nonce и PKCE verifier.
+func (c *StateCodec) NewState(provider string, linkUserID int) (LoginState, error) {
+	state, err := randomString()
+	if err != nil {
+		return LoginState{}, err
+	}
+	nonce, err := randomString()
+	if err != nil {
+		return LoginState{}, err
+	}
+
+	return LoginState{
+		Provider:   provider,
+		State:      state,
+		Nonce:      nonce,
+		Verifier:   oauth2.GenerateVerifier(),
+		LinkUserID: linkUserID,
+		ExpiresAt:  time.Now().Add(c.ttl),
+	}, nil
+}
+
+func (c *StateCodec) Encode(s LoginState) (string, error) {
+	payload, err := json.Marshal(s)
+	if err != nil {
+		return "", err
+	}
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + c.sign(body), nil
+}
+
+// Decode проверяет подпись и срок действия.
+func (c *StateCodec) Decode(value string) (LoginState, error) {
+	body, sig, ok := strings.Cut(value, ".")
+	if !ok || !hmac.Equal([]byte(sig), []byte(c.sign(body))) {
+		return LoginState{}, ErrInvalidState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return LoginState{}, ErrInvalidState
+	}
+
+	var s LoginState
+	if err := json.Unmarshal(payload, &s); err != nil {
+		return LoginState{}, ErrInvalidState
+	}
+	if time.Now().After(s.ExpiresAt) {
+		return LoginState{}, ErrInvalidState
+	}
+
+	return s, nil
+}
+
+func (c *StateCodec) sign(body string) string {
+	mac := hmac.New(sha256.New, c.secret)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func randomString() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+// AuditRepo пишет и читает журнал безопасности. Методов изменения нет:
+// таблица только для добавления.
+type AuditRepo struct {
+	db DBTX
+}
+
+func NewAuditRepo(db *sql.DB) *AuditRepo {
+	return &AuditRepo{db: traceDB(db)}
+}
+
+func (r *AuditRepo) WithTx(tx *sql.Tx) *AuditRepo {
+	return &AuditRepo{db: traceDB(tx)}
+}
+
+func (r *AuditRepo) RecordEvent(ctx context.Context, event models.AuditEvent) error {
+	ctx, span := startSpan(ctx, "AuditRepo.RecordEvent")
+	defer span.End()
+
+	metadata := []byte("{}")
+	if len(event.Metadata) > 0 {
+		var err error
+		if metadata, err = json.Marshal(event.Metadata); err != nil {
+			return fmt.Errorf("failed to encode audit metadata: %w", err)
+		}
+	}
+
+	query := `
+		INSERT INTO audit_events (actor_id, action, target_type, target_id, ip_address, request_id, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	_, err := r.db.ExecContext(ctx, query, event.ActorID, event.Action, event.TargetType, event.TargetID,
+		event.IPAddress, event.RequestID, string(metadata))
+	if err != nil {
+		return fmt.Errorf("failed to record audit event: %w", err)
+	}
+	return nil
+}
+
+// AuditFilter - условия выборки журнала. Пустые поля не ограничивают выборку.
+type AuditFilter struct {
+	// UserID - события, где пользователь действовал сам или был объектом действия
+	UserID int
+	Action models.AuditAction
+	From   time.Time
+	To     time.Time
+}
+
+// ListEvents возвращает события журнала, новые первыми.
+func (r *AuditRepo) ListEvents(ctx context.Context, filter AuditFilter, page, limit int) ([]models.AuditEvent, int64, error) {
+	ctx, span := startSpan(ctx, "AuditRepo.ListEvents")
+	defer span.End()
+
+	conditions := []string{"TRUE"}
+	var args []any
+	if filter.UserID != 0 {
+		args = append(args, filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("(actor_id = $%d OR (target_type = 'user' AND target_id = $%d))", len(args), len(args)))
+	}
+	if filter.Action != "" {
+		args = append(args, filter.Action)
+		conditions = append(conditions, fmt.Sprintf("action = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("occurred_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("occurred_at < $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM audit_events WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit events: %w", err)
+	}
+	if total == 0 {
+		return []models.AuditEvent{}, 0, nil
+	}
+
+	args = append(args, limit, (page-1)*limit)
+	query := fmt.Sprintf(`
+		SELECT event_id, occurred_at, actor_id, action, target_type, target_id, ip_address, request_id, metadata
+		FROM audit_events
+		WHERE %s
+		ORDER BY occurred_at DESC, event_id DESC
+		LIMIT $%d OFFSET $%d`, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AuditEvent{}
+	for rows.Next() {
+		var event models.AuditEvent
+		var metadata []byte
+		if err := rows.Scan(&event.ID, &event.OccurredAt, &event.ActorID, &event.Action, &event.TargetType,
+			&event.TargetID, &event.IPAddress, &event.RequestID, &metadata); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+		if err := json.Unmarshal(metadata, &event.Metadata); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode audit metadata: %w", err)
+		}
+		events = append(events, event)
+	}
+	return events, total, rows.Err()
+}
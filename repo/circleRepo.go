@@ -0,0 +1,451 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+type CircleRepo struct {
+	db DBTX
+}
+
+func NewCircleRepo(db *sql.DB) *CircleRepo {
+	return &CircleRepo{db: traceDB(db)}
+}
+
+func (r *CircleRepo) WithTx(tx *sql.Tx) *CircleRepo {
+	return &CircleRepo{db: traceDB(tx)}
+}
+
+// memberCount - число видимых участников круга с псевдонимом c
+func memberCount(alias string) string {
+	return `(SELECT COUNT(*) FROM circle_members cm JOIN users cu ON cu.user_id = cm.user_id
+		WHERE cm.circle_id = ` + alias + `.circle_id AND ` + visibleUser("cu") + `)`
+}
+
+func (r *CircleRepo) CreateCircle(ctx context.Context, name, description string) (models.Circle, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.CreateCircle")
+	defer span.End()
+
+	var circle models.Circle
+	query := "INSERT INTO circles (name, description) VALUES ($1, $2) RETURNING circle_id, name, description, created_at"
+	if err := r.db.QueryRowContext(ctx, query, name, description).Scan(
+		&circle.ID, &circle.Name, &circle.Description, &circle.CreatedAt); err != nil {
+		return models.Circle{}, fmt.Errorf("failed to create circle: %w", err)
+	}
+	return circle, nil
+}
+
+// GetCircle возвращает круг с числом участников. Возвращает sql.ErrNoRows, если круга нет.
+func (r *CircleRepo) GetCircle(ctx context.Context, circleID int) (models.Circle, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.GetCircle")
+	defer span.End()
+
+	var circle models.Circle
+	query := `SELECT c.circle_id, c.name, c.description, c.created_at, ` + memberCount("c") + `
+		FROM circles c WHERE c.circle_id = $1`
+	if err := r.db.QueryRowContext(ctx, query, circleID).Scan(
+		&circle.ID, &circle.Name, &circle.Description, &circle.CreatedAt, &circle.MemberCount); err != nil {
+		if err == sql.ErrNoRows {
+			return models.Circle{}, sql.ErrNoRows
+		}
+		return models.Circle{}, fmt.Errorf("failed to get circle: %w", err)
+	}
+	return circle, nil
+}
+
+// GetUserCircles возвращает круги пользователя с его ролью, по названию.
+func (r *CircleRepo) GetUserCircles(ctx context.Context, userID int) ([]models.Circle, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.GetUserCircles")
+	defer span.End()
+
+	query := `
+		SELECT c.circle_id, c.name, c.description, c.created_at, ` + memberCount("c") + `, m.role
+		FROM circle_members m
+		JOIN circles c ON c.circle_id = m.circle_id
+		WHERE m.user_id = $1
+		ORDER BY lower(c.name), c.circle_id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user circles: %w", err)
+	}
+	defer rows.Close()
+
+	circles := []models.Circle{}
+	for rows.Next() {
+		var circle models.Circle
+		if err := rows.Scan(&circle.ID, &circle.Name, &circle.Description, &circle.CreatedAt,
+			&circle.MemberCount, &circle.MyRole); err != nil {
+			return nil, fmt.Errorf("failed to scan circle: %w", err)
+		}
+		circles = append(circles, circle)
+	}
+	return circles, rows.Err()
+}
+
+// UpdateCircle меняет название и описание. Возвращает sql.ErrNoRows, если круга нет.
+func (r *CircleRepo) UpdateCircle(ctx context.Context, circleID int, name, description string) error {
+	ctx, span := startSpan(ctx, "CircleRepo.UpdateCircle")
+	defer span.End()
+
+	return r.execAffectingOne(ctx, "update circle",
+		"UPDATE circles SET name = $2, description = $3 WHERE circle_id = $1", circleID, name, description)
+}
+
+func (r *CircleRepo) DeleteCircle(ctx context.Context, circleID int) error {
+	ctx, span := startSpan(ctx, "CircleRepo.DeleteCircle")
+	defer span.End()
+
+	return r.execAffectingOne(ctx, "delete circle", "DELETE FROM circles WHERE circle_id = $1", circleID)
+}
+
+// GetMemberRole возвращает роль пользователя в круге или sql.ErrNoRows, если он не участник.
+func (r *CircleRepo) GetMemberRole(ctx context.Context, circleID, userID int) (models.CircleRole, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.GetMemberRole")
+	defer span.End()
+
+	var role models.CircleRole
+	query := "SELECT role FROM circle_members WHERE circle_id = $1 AND user_id = $2"
+	if err := r.db.QueryRowContext(ctx, query, circleID, userID).Scan(&role); err != nil {
+		if err == sql.ErrNoRows {
+			return "", sql.ErrNoRows
+		}
+		return "", fmt.Errorf("failed to get circle member role: %w", err)
+	}
+	return role, nil
+}
+
+// GetMembers возвращает видимых участников круга: сначала владелец и администраторы.
+func (r *CircleRepo) GetMembers(ctx context.Context, circleID int) ([]models.CircleMember, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.GetMembers")
+	defer span.End()
+
+	query := `
+		SELECT u.user_id, u.user_name, u.created_at, m.role, m.joined_at
+		FROM circle_members m
+		JOIN users u ON u.user_id = m.user_id
+		WHERE m.circle_id = $1 AND ` + visibleUser("u") + `
+		ORDER BY CASE m.role WHEN 'owner' THEN 0 WHEN 'admin' THEN 1 ELSE 2 END, m.joined_at, u.user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, circleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get circle members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.CircleMember{}
+	for rows.Next() {
+		var member models.CircleMember
+		if err := rows.Scan(&member.User.ID, &member.User.UserName, &member.User.CreatedAt,
+			&member.Role, &member.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan circle member: %w", err)
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// CountMembers возвращает число всех участников, включая скрытые аккаунты: место в круге они занимают.
+func (r *CircleRepo) CountMembers(ctx context.Context, circleID int) (int, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.CountMembers")
+	defer span.End()
+
+	var count int
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM circle_members WHERE circle_id = $1", circleID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count circle members: %w", err)
+	}
+	return count, nil
+}
+
+// AddMember добавляет участника. Возвращает false, если он уже в круге.
+func (r *CircleRepo) AddMember(ctx context.Context, circleID, userID int, role models.CircleRole) (bool, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.AddMember")
+	defer span.End()
+
+	query := `
+		INSERT INTO circle_members (circle_id, user_id, role)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (circle_id, user_id) DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query, circleID, userID, role)
+	if err != nil {
+		return false, fmt.Errorf("failed to add circle member: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// SetMemberRole меняет роль участника. Возвращает sql.ErrNoRows, если он не в круге.
+func (r *CircleRepo) SetMemberRole(ctx context.Context, circleID, userID int, role models.CircleRole) error {
+	ctx, span := startSpan(ctx, "CircleRepo.SetMemberRole")
+	defer span.End()
+
+	return r.execAffectingOne(ctx, "set circle member role",
+		"UPDATE circle_members SET role = $3 WHERE circle_id = $1 AND user_id = $2", circleID, userID, role)
+}
+
+// RemoveMember исключает участника. Возвращает sql.ErrNoRows, если он не в круге.
+func (r *CircleRepo) RemoveMember(ctx context.Context, circleID, userID int) error {
+	ctx, span := startSpan(ctx, "CircleRepo.RemoveMember")
+	defer span.End()
+
+	return r.execAffectingOne(ctx, "remove circle member",
+		"DELETE FROM circle_members WHERE circle_id = $1 AND user_id = $2", circleID, userID)
+}
+
+// LeaveAllCircles убирает пользователя из всех кругов перед стиранием аккаунта.
+// Круги, которыми он владел, переходят самому давнему администратору, а если их нет -
+// самому давнему участнику; опустевшие круги удаляются.
+func (r *CircleRepo) LeaveAllCircles(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "CircleRepo.LeaveAllCircles")
+	defer span.End()
+
+	rows, err := r.db.QueryContext(ctx, "DELETE FROM circle_members WHERE user_id = $1 RETURNING circle_id", userID)
+	if err != nil {
+		return fmt.Errorf("failed to leave circles: %w", err)
+	}
+	var circleIDs []int64
+	for rows.Next() {
+		var circleID int64
+		if err := rows.Scan(&circleID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan circle id: %w", err)
+		}
+		circleIDs = append(circleIDs, circleID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(circleIDs) == 0 {
+		return nil
+	}
+
+	promote := `
+		UPDATE circle_members m
+		SET role = 'owner'
+		FROM (
+			SELECT DISTINCT ON (c.circle_id) c.circle_id, c.user_id
+			FROM circle_members c
+			WHERE c.circle_id = ANY($1::int[])
+				AND NOT EXISTS (SELECT 1 FROM circle_members o WHERE o.circle_id = c.circle_id AND o.role = 'owner')
+			ORDER BY c.circle_id, CASE c.role WHEN 'admin' THEN 0 ELSE 1 END, c.joined_at, c.user_id
+		) heir
+		WHERE m.circle_id = heir.circle_id AND m.user_id = heir.user_id`
+	if _, err := r.db.ExecContext(ctx, promote, circleIDs); err != nil {
+		return fmt.Errorf("failed to transfer circle ownership: %w", err)
+	}
+
+	cleanup := `
+		DELETE FROM circles c
+		WHERE c.circle_id = ANY($1::int[])
+			AND NOT EXISTS (SELECT 1 FROM circle_members m WHERE m.circle_id = c.circle_id)`
+	if _, err := r.db.ExecContext(ctx, cleanup, circleIDs); err != nil {
+		return fmt.Errorf("failed to delete empty circles: %w", err)
+	}
+	return nil
+}
+
+// CreatePost публикует рекомендацию медиа в ленту круга.
+func (r *CircleRepo) CreatePost(ctx context.Context, circleID, authorID, mediaID int, note string) (models.CirclePost, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.CreatePost")
+	defer span.End()
+
+	post := models.CirclePost{CircleID: circleID, Note: note}
+	query := `
+		INSERT INTO circle_posts (circle_id, author_id, media_id, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING post_id, created_at`
+	if err := r.db.QueryRowContext(ctx, query, circleID, authorID, mediaID, note).Scan(&post.ID, &post.CreatedAt); err != nil {
+		return models.CirclePost{}, fmt.Errorf("failed to create circle post: %w", err)
+	}
+	return post, nil
+}
+
+// FanOutPost превращает пост в полученные рекомендации всем видимым участникам, кроме автора.
+// Участники, запретившие это медиа, и те, кому автор его уже рекомендовал, пропускаются.
+// Возвращает созданные рекомендации (заполнены только ID и ToUserID).
+func (r *CircleRepo) FanOutPost(ctx context.Context, postID int64) ([]models.Recommendation, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.FanOutPost")
+	defer span.End()
+
+	query := `
+		INSERT INTO recommendations (from_user_id, to_user_id, media_id, note, circle_post_id)
+		SELECT p.author_id, m.user_id, p.media_id, p.note, p.post_id
+		FROM circle_posts p
+		JOIN circle_members m ON m.circle_id = p.circle_id
+		JOIN users u ON u.user_id = m.user_id
+		WHERE p.post_id = $1
+			AND m.user_id <> p.author_id
+			AND ` + visibleUser("u") + `
+			AND NOT EXISTS (
+				SELECT 1 FROM recommendation_opt_outs o
+				WHERE o.user_id = m.user_id AND o.media_id = p.media_id
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM recommendations r
+				WHERE r.from_user_id = p.author_id AND r.to_user_id = m.user_id AND r.media_id = p.media_id
+			)
+		RETURNING recommendation_id, to_user_id`
+
+	rows, err := r.db.QueryContext(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fan out circle post: %w", err)
+	}
+	defer rows.Close()
+
+	var recommendations []models.Recommendation
+	for rows.Next() {
+		var recommendation models.Recommendation
+		if err := rows.Scan(&recommendation.ID, &recommendation.ToUserID); err != nil {
+			return nil, fmt.Errorf("failed to scan circle recommendation: %w", err)
+		}
+		recommendations = append(recommendations, recommendation)
+	}
+	return recommendations, rows.Err()
+}
+
+// GetFeed возвращает ленту круга, новые посты первыми, вместе с реакциями.
+func (r *CircleRepo) GetFeed(ctx context.Context, circleID, page, limit int) ([]models.CirclePost, int64, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.GetFeed")
+	defer span.End()
+
+	const visibleAuthor = "(p.author_id IS NULL OR a.user_id IS NOT NULL)"
+	from := `
+		FROM circle_posts p
+		LEFT JOIN users a ON a.user_id = p.author_id AND ` + visibleUser("a") + `
+		JOIN media_items m ON m.media_id = p.media_id
+		WHERE p.circle_id = $1 AND ` + visibleAuthor
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) "+from, circleID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count circle feed: %w", err)
+	}
+	if total == 0 {
+		return []models.CirclePost{}, 0, nil
+	}
+
+	query := `
+		SELECT p.post_id, p.circle_id, p.note, p.created_at,
+			a.user_id, a.user_name, a.created_at,
+			` + mediaColumns("m") + from + `
+		ORDER BY p.created_at DESC, p.post_id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, circleID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get circle feed: %w", err)
+	}
+	defer rows.Close()
+
+	posts := []models.CirclePost{}
+	index := map[int64]int{}
+	var postIDs []int64
+	for rows.Next() {
+		var post models.CirclePost
+		var authorID sql.NullInt64
+		var authorName sql.NullString
+		var authorCreatedAt sql.NullTime
+		dest := []any{&post.ID, &post.CircleID, &post.Note, &post.CreatedAt, &authorID, &authorName, &authorCreatedAt}
+		if err := rows.Scan(append(dest, mediaFields(&post.Media)...)...); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan circle post: %w", err)
+		}
+		post.Author.ID = int(authorID.Int64)
+		post.Author.UserName = authorName.String
+		post.Author.CreatedAt = authorCreatedAt.Time
+		post.Reactions = []models.CirclePostReaction{}
+
+		index[post.ID] = len(posts)
+		postIDs = append(postIDs, post.ID)
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	reactionsQuery := `
+		SELECT cr.post_id, u.user_id, u.user_name, u.created_at, cr.reaction, cr.created_at
+		FROM circle_reactions cr
+		JOIN users u ON u.user_id = cr.user_id
+		WHERE cr.post_id = ANY($1) AND ` + visibleUser("u") + `
+		ORDER BY cr.created_at, u.user_id`
+
+	reactionRows, err := r.db.QueryContext(ctx, reactionsQuery, postIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get circle reactions: %w", err)
+	}
+	defer reactionRows.Close()
+
+	for reactionRows.Next() {
+		var postID int64
+		var reaction models.CirclePostReaction
+		if err := reactionRows.Scan(&postID, &reaction.User.ID, &reaction.User.UserName, &reaction.User.CreatedAt,
+			&reaction.Reaction, &reaction.CreatedAt); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan circle reaction: %w", err)
+		}
+		i := index[postID]
+		posts[i].Reactions = append(posts[i].Reactions, reaction)
+	}
+	return posts, total, reactionRows.Err()
+}
+
+// PostExists сообщает, есть ли пост в круге.
+func (r *CircleRepo) PostExists(ctx context.Context, circleID int, postID int64) (bool, error) {
+	ctx, span := startSpan(ctx, "CircleRepo.PostExists")
+	defer span.End()
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM circle_posts WHERE circle_id = $1 AND post_id = $2)"
+	if err := r.db.QueryRowContext(ctx, query, circleID, postID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check circle post: %w", err)
+	}
+	return exists, nil
+}
+
+// SetReaction ставит или меняет реакцию участника на пост.
+func (r *CircleRepo) SetReaction(ctx context.Context, postID int64, userID int, reaction models.CircleReaction) error {
+	ctx, span := startSpan(ctx, "CircleRepo.SetReaction")
+	defer span.End()
+
+	query := `
+		INSERT INTO circle_reactions (post_id, user_id, reaction)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (post_id, user_id) DO UPDATE SET reaction = EXCLUDED.reaction, created_at = now()`
+
+	if _, err := r.db.ExecContext(ctx, query, postID, userID, reaction); err != nil {
+		return fmt.Errorf("failed to set circle reaction: %w", err)
+	}
+	return nil
+}
+
+// RemoveReaction снимает реакцию. Возвращает sql.ErrNoRows, если ее не было.
+func (r *CircleRepo) RemoveReaction(ctx context.Context, postID int64, userID int) error {
+	ctx, span := startSpan(ctx, "CircleRepo.RemoveReaction")
+	defer span.End()
+
+	return r.execAffectingOne(ctx, "remove circle reaction",
+		"DELETE FROM circle_reactions WHERE post_id = $1 AND user_id = $2", postID, userID)
+}
+
+// execAffectingOne выполняет запрос и возвращает sql.ErrNoRows, если он ничего не изменил.
+func (r *CircleRepo) execAffectingOne(ctx context.Context, action, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+type DataExportRepo struct {
+	db DBTX
+}
+
+func NewDataExportRepo(db *sql.DB) *DataExportRepo {
+	return &DataExportRepo{db: traceDB(db)}
+}
+
+func (r *DataExportRepo) WithTx(tx *sql.Tx) *DataExportRepo {
+	return &DataExportRepo{db: traceDB(tx)}
+}
+
+const dataExportColumns = "export_id, user_id, status, file_path, created_at, completed_at, expires_at"
+
+func scanDataExport(row interface{ Scan(...any) error }) (models.DataExport, error) {
+	var export models.DataExport
+	err := row.Scan(&export.ID, &export.UserID, &export.Status, &export.FilePath,
+		&export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	return export, err
+}
+
+func (r *DataExportRepo) CreateExport(ctx context.Context, userID int) (models.DataExport, error) {
+	ctx, span := startSpan(ctx, "DataExportRepo.CreateExport")
+	defer span.End()
+
+	query := "INSERT INTO data_exports (user_id) VALUES ($1) RETURNING " + dataExportColumns
+
+	export, err := scanDataExport(r.db.QueryRowContext(ctx, query, userID))
+	if err != nil {
+		return models.DataExport{}, fmt.Errorf("failed to create data export: %w", err)
+	}
+
+	return export, nil
+}
+
+// GetExport возвращает выгрузку пользователя или sql.ErrNoRows.
+func (r *DataExportRepo) GetExport(ctx context.Context, userID int, exportID string) (models.DataExport, error) {
+	ctx, span := startSpan(ctx, "DataExportRepo.GetExport")
+	defer span.End()
+
+	query := "SELECT " + dataExportColumns + " FROM data_exports WHERE export_id = $1 AND user_id = $2"
+
+	return scanDataExport(r.db.QueryRowContext(ctx, query, exportID, userID))
+}
+
+// GetExportByID возвращает выгрузку без проверки владельца (для скачивания по подписанной ссылке).
+func (r *DataExportRepo) GetExportByID(ctx context.Context, exportID string) (models.DataExport, error) {
+	ctx, span := startSpan(ctx, "DataExportRepo.GetExportByID")
+	defer span.End()
+
+	query := "SELECT " + dataExportColumns + " FROM data_exports WHERE export_id = $1"
+
+	return scanDataExport(r.db.QueryRowContext(ctx, query, exportID))
+}
+
+// FindUnfinishedExport возвращает еще не собранную выгрузку пользователя или sql.ErrNoRows.
+func (r *DataExportRepo) FindUnfinishedExport(ctx context.Context, userID int) (models.DataExport, error) {
+	ctx, span := startSpan(ctx, "DataExportRepo.FindUnfinishedExport")
+	defer span.End()
+
+	query := "SELECT " + dataExportColumns + `
+		FROM data_exports
+		WHERE user_id = $1 AND status IN ('pending', 'running')
+		ORDER BY created_at DESC
+		LIMIT 1`
+
+	return scanDataExport(r.db.QueryRowContext(ctx, query, userID))
+}
+
+// StartExport переводит выгрузку в статус running перед сборкой. Выгрузка, сборка которой
+// прервалась, собирается заново. Возвращает sql.ErrNoRows, если собирать уже нечего.
+func (r *DataExportRepo) StartExport(ctx context.Context, exportID string) (models.DataExport, error) {
+	ctx, span := startSpan(ctx, "DataExportRepo.StartExport")
+	defer span.End()
+
+	query := `
+		UPDATE data_exports
+		SET status = 'running'
+		WHERE export_id = $1 AND status IN ('pending', 'running')
+		RETURNING ` + dataExportColumns
+
+	return scanDataExport(r.db.QueryRowContext(ctx, query, exportID))
+}
+
+func (r *DataExportRepo) CompleteExport(ctx context.Context, exportID, filePath string, expiresAt time.Time) error {
+	ctx, span := startSpan(ctx, "DataExportRepo.CompleteExport")
+	defer span.End()
+
+	query := `
+		UPDATE data_exports
+		SET status = 'ready', file_path = $2, completed_at = now(), expires_at = $3
+		WHERE export_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, exportID, filePath, expiresAt); err != nil {
+		return fmt.Errorf("failed to complete data export: %w", err)
+	}
+
+	return nil
+}
+
+// FailExport помечает как failed выгрузку, которая еще не собрана.
+func (r *DataExportRepo) FailExport(ctx context.Context, exportID string) error {
+	ctx, span := startSpan(ctx, "DataExportRepo.FailExport")
+	defer span.End()
+
+	query := "UPDATE data_exports SET status = 'failed' WHERE export_id = $1 AND status IN ('pending', 'running')"
+
+	if _, err := r.db.ExecContext(ctx, query, exportID); err != nil {
+		return fmt.Errorf("failed to mark data export as failed: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DataExportRepo) SetExportStatus(ctx context.Context, exportID string, status models.ExportStatus) error {
+	ctx, span := startSpan(ctx, "DataExportRepo.SetExportStatus")
+	defer span.End()
+
+	query := "UPDATE data_exports SET status = $2 WHERE export_id = $1"
+
+	if _, err := r.db.ExecContext(ctx, query, exportID, status); err != nil {
+		return fmt.Errorf("failed to update data export status: %w", err)
+	}
+
+	return nil
+}
+
+// GetExpiredExports возвращает готовые выгрузки, срок хранения которых истек.
+func (r *DataExportRepo) GetExpiredExports(ctx context.Context) ([]models.DataExport, error) {
+	ctx, span := startSpan(ctx, "DataExportRepo.GetExpiredExports")
+	defer span.End()
+
+	query := "SELECT " + dataExportColumns + " FROM data_exports WHERE status = 'ready' AND expires_at < now()"
+
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired data exports: %w", err)
+	}
+	defer rows.Close()
+
+	exports := []models.DataExport{}
+	for rows.Next() {
+		export, err := scanDataExport(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan data export row: %w", err)
+		}
+		exports = append(exports, export)
+	}
+
+	return exports, rows.Err()
+}
+
+// GetUserArchivePaths возвращает пути к архивам пользователя, еще лежащим на диске.
+func (r *DataExportRepo) GetUserArchivePaths(ctx context.Context, userID int) ([]string, error) {
+	ctx, span := startSpan(ctx, "DataExportRepo.GetUserArchivePaths")
+	defer span.End()
+
+	query := "SELECT file_path FROM data_exports WHERE user_id = $1 AND status = 'ready'"
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user export archives: %w", err)
+	}
+	defer rows.Close()
+
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, fmt.Errorf("failed to scan export path: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, rows.Err()
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/cobrich/recommendo/models"
 )
 
 type FollowRepo struct {
@@ -11,15 +13,18 @@ type FollowRepo struct {
 }
 
 func NewFollowRepo(db *sql.DB) *FollowRepo {
-	return &FollowRepo{db: db}
+	return &FollowRepo{db: traceDB(db)}
 }
 
 func (r *FollowRepo) WithTx(tx *sql.Tx) *FollowRepo {
-    return &FollowRepo{db: tx}
+    return &FollowRepo{db: traceDB(tx)}
 }
 
 
 func (r *FollowRepo) CreateFollow(ctx context.Context, followerID, followingID int) error {
+	ctx, span := startSpan(ctx, "FollowRepo.CreateFollow")
+	defer span.End()
+
 	query := `
         INSERT INTO follows (follower_id, following_id)
         VALUES ($1, $2)
@@ -44,7 +49,23 @@ func (r *FollowRepo) CreateFollow(ctx context.Context, followerID, followingID i
 	return nil
 }
 
+// IsFollowing сообщает, подписан ли followerID на followingID.
+func (r *FollowRepo) IsFollowing(ctx context.Context, followerID, followingID int) (bool, error) {
+	ctx, span := startSpan(ctx, "FollowRepo.IsFollowing")
+	defer span.End()
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM follows WHERE follower_id = $1 AND following_id = $2)"
+	if err := r.db.QueryRowContext(ctx, query, followerID, followingID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return exists, nil
+}
+
 func (r *FollowRepo) DeleteFollow(ctx context.Context, followerID, followingID int) error {
+	ctx, span := startSpan(ctx, "FollowRepo.DeleteFollow")
+	defer span.End()
+
 	query := `
 		DELETE FROM follows 
 		WHERE follower_id = $1 AND following_id= $2
@@ -70,6 +91,9 @@ func (r *FollowRepo) DeleteFollow(ctx context.Context, followerID, followingID i
 }
 
 func (r *FollowRepo) AreUsersFriends(ctx context.Context, userID1, userID2 int) (bool, error) {
+	ctx, span := startSpan(ctx, "FollowRepo.AreUsersFriends")
+	defer span.End()
+
 	query := `
 		SELECT EXISTS (
 			SELECT 1
@@ -89,6 +113,9 @@ func (r *FollowRepo) AreUsersFriends(ctx context.Context, userID1, userID2 int)
 }
 
 func (r *FollowRepo) DeleteAllUserFollows(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "FollowRepo.DeleteAllUserFollows")
+	defer span.End()
+
     query := "DELETE FROM follows WHERE follower_id = $1 OR following_id = $1"
     
     _, err := r.db.ExecContext(ctx, query, userID)
@@ -96,4 +123,52 @@ func (r *FollowRepo) DeleteAllUserFollows(ctx context.Context, userID int) error
         return fmt.Errorf("failed to delete all user follows: %w", err)
     }
     return nil
-}
\ No newline at end of file
+}
+// GetAllFollowers возвращает всех подписчиков пользователя с датой подписки.
+func (r *FollowRepo) GetAllFollowers(ctx context.Context, userID int) ([]models.FollowDetails, error) {
+	ctx, span := startSpan(ctx, "FollowRepo.GetAllFollowers")
+	defer span.End()
+
+	query := `
+		SELECT u.user_id, u.user_name, u.created_at, f.created_at
+		FROM follows f
+		JOIN users u ON u.user_id = f.follower_id
+		WHERE f.following_id = $1
+		ORDER BY f.created_at`
+
+	return r.queryFollowDetails(ctx, query, userID)
+}
+
+// GetAllFollowings возвращает всех, на кого подписан пользователь, с датой подписки.
+func (r *FollowRepo) GetAllFollowings(ctx context.Context, userID int) ([]models.FollowDetails, error) {
+	ctx, span := startSpan(ctx, "FollowRepo.GetAllFollowings")
+	defer span.End()
+
+	query := `
+		SELECT u.user_id, u.user_name, u.created_at, f.created_at
+		FROM follows f
+		JOIN users u ON u.user_id = f.following_id
+		WHERE f.follower_id = $1
+		ORDER BY f.created_at`
+
+	return r.queryFollowDetails(ctx, query, userID)
+}
+
+func (r *FollowRepo) queryFollowDetails(ctx context.Context, query string, userID int) ([]models.FollowDetails, error) {
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get follows: %w", err)
+	}
+	defer rows.Close()
+
+	follows := []models.FollowDetails{}
+	for rows.Next() {
+		var follow models.FollowDetails
+		if err := rows.Scan(&follow.User.ID, &follow.User.UserName, &follow.User.CreatedAt, &follow.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan follow row: %w", err)
+		}
+		follows = append(follows, follow)
+	}
+
+	return follows, rows.Err()
+}
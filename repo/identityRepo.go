@@ -0,0 +1,112 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+type IdentityRepo struct {
+	db DBTX
+}
+
+func NewIdentityRepo(db *sql.DB) *IdentityRepo {
+	return &IdentityRepo{db: traceDB(db)}
+}
+
+func (r *IdentityRepo) WithTx(tx *sql.Tx) *IdentityRepo {
+	return &IdentityRepo{db: traceDB(tx)}
+}
+
+func (r *IdentityRepo) CreateIdentity(ctx context.Context, identity models.UserIdentity) (models.UserIdentity, error) {
+	ctx, span := startSpan(ctx, "IdentityRepo.CreateIdentity")
+	defer span.End()
+
+	query := `
+		INSERT INTO user_identities (user_id, provider, subject, email)
+		VALUES ($1, $2, $3, $4)
+		RETURNING identity_id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, identity.UserID, identity.Provider, identity.Subject, identity.Email).Scan(
+		&identity.ID,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		return models.UserIdentity{}, fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	return identity, nil
+}
+
+// FindIdentity ищет привязку по провайдеру и идентификатору пользователя у провайдера.
+// Возвращает sql.ErrNoRows, если такой привязки нет.
+func (r *IdentityRepo) FindIdentity(ctx context.Context, provider, subject string) (models.UserIdentity, error) {
+	ctx, span := startSpan(ctx, "IdentityRepo.FindIdentity")
+	defer span.End()
+
+	var identity models.UserIdentity
+	query := `
+		SELECT identity_id, user_id, provider, subject, email, created_at
+		FROM user_identities
+		WHERE provider = $1 AND subject = $2`
+
+	err := r.db.QueryRowContext(ctx, query, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt)
+	if err != nil {
+		return models.UserIdentity{}, err // sql.ErrNoRows будет обработан в сервисе
+	}
+
+	return identity, nil
+}
+
+func (r *IdentityRepo) GetUserIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	ctx, span := startSpan(ctx, "IdentityRepo.GetUserIdentities")
+	defer span.End()
+
+	query := `
+		SELECT identity_id, user_id, provider, subject, email, created_at
+		FROM user_identities
+		WHERE user_id = $1
+		ORDER BY provider`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email, &identity.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan identity row: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, rows.Err()
+}
+
+func (r *IdentityRepo) DeleteIdentity(ctx context.Context, userID int, provider string) error {
+	ctx, span := startSpan(ctx, "IdentityRepo.DeleteIdentity")
+	defer span.End()
+
+	query := "DELETE FROM user_identities WHERE user_id = $1 AND provider = $2"
+
+	result, err := r.db.ExecContext(ctx, query, userID, provider)
+	if err != nil {
+		return fmt.Errorf("failed to delete identity: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
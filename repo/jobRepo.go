@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+type JobRepo struct {
+	db DBTX
+}
+
+func NewJobRepo(db *sql.DB) *JobRepo {
+	return &JobRepo{db: traceDB(db)}
+}
+
+func (r *JobRepo) WithTx(tx *sql.Tx) *JobRepo {
+	return &JobRepo{db: traceDB(tx)}
+}
+
+// Enqueue ставит задачу. uniqueKey != "" - не ставить, если такая же задача еще
+// не закончена; тогда возвращается false.
+func (r *JobRepo) Enqueue(ctx context.Context, kind string, payload []byte, maxAttempts int, runAt time.Time, uniqueKey string) (bool, error) {
+	ctx, span := startSpan(ctx, "JobRepo.Enqueue")
+	defer span.End()
+
+	query := `
+		INSERT INTO jobs (kind, payload, max_attempts, run_at, unique_key)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (kind, unique_key) WHERE unique_key IS NOT NULL AND status IN ('pending', 'running')
+		DO NOTHING`
+
+	result, err := r.db.ExecContext(ctx, query, kind, string(payload), maxAttempts, runAt, uniqueKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
+
+// ClaimJobs берет до limit задач указанных видов, время которых пришло, и задачи,
+// чей обработчик не уложился в locked_until (скорее всего, процесс упал). Задачи
+// закрепляются за вызывающим на lease, счетчик попыток увеличивается.
+func (r *JobRepo) ClaimJobs(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]models.Job, error) {
+	ctx, span := startSpan(ctx, "JobRepo.ClaimJobs")
+	defer span.End()
+
+	query := `
+		WITH due AS (
+			SELECT job_id
+			FROM jobs
+			WHERE kind = ANY($1::text[])
+				AND ((status = 'pending' AND run_at <= now()) OR (status = 'running' AND locked_until < now()))
+			ORDER BY run_at, job_id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE jobs j
+		SET status = 'running', attempts = j.attempts + 1, locked_until = now() + make_interval(secs => $3)
+		FROM due
+		WHERE j.job_id = due.job_id
+		RETURNING j.job_id, j.kind, j.payload, j.status, j.attempts, j.max_attempts, j.run_at, j.last_error, j.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, kinds, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		var job models.Job
+		if err := rows.Scan(&job.ID, &job.Kind, &job.Payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+			&job.RunAt, &job.LastError, &job.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+func (r *JobRepo) CompleteJob(ctx context.Context, jobID int64) error {
+	ctx, span := startSpan(ctx, "JobRepo.CompleteJob")
+	defer span.End()
+
+	query := "UPDATE jobs SET status = 'succeeded', locked_until = NULL, finished_at = now() WHERE job_id = $1"
+	if _, err := r.db.ExecContext(ctx, query, jobID); err != nil {
+		return fmt.Errorf("failed to complete job: %w", err)
+	}
+	return nil
+}
+
+// FailJob записывает ошибку попытки. retryAt = nil - попыток больше не будет.
+func (r *JobRepo) FailJob(ctx context.Context, jobID int64, lastError string, retryAt *time.Time) error {
+	ctx, span := startSpan(ctx, "JobRepo.FailJob")
+	defer span.End()
+
+	query := `
+		UPDATE jobs
+		SET status = CASE WHEN $3::timestamptz IS NULL THEN 'failed' ELSE 'pending' END,
+			run_at = COALESCE($3, run_at),
+			finished_at = CASE WHEN $3::timestamptz IS NULL THEN now() END,
+			locked_until = NULL,
+			last_error = $2
+		WHERE job_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, jobID, lastError, retryAt); err != nil {
+		return fmt.Errorf("failed to record job failure: %w", err)
+	}
+	return nil
+}
+
+// ReleaseJob возвращает прерванную остановкой сервера задачу в очередь,
+// не засчитывая попытку.
+func (r *JobRepo) ReleaseJob(ctx context.Context, jobID int64) error {
+	ctx, span := startSpan(ctx, "JobRepo.ReleaseJob")
+	defer span.End()
+
+	query := "UPDATE jobs SET status = 'pending', attempts = attempts - 1, locked_until = NULL WHERE job_id = $1 AND status = 'running'"
+	if _, err := r.db.ExecContext(ctx, query, jobID); err != nil {
+		return fmt.Errorf("failed to release job: %w", err)
+	}
+	return nil
+}
+
+// DeleteFinishedJobs удаляет выполненные и проваленные задачи, законченные раньше before.
+func (r *JobRepo) DeleteFinishedJobs(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "JobRepo.DeleteFinishedJobs")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM jobs WHERE finished_at < $1", before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete finished jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// SaveSchedule добавляет расписание. Если расписание с таким именем уже есть,
+// время следующего запуска пересчитывается, только когда поменялся spec.
+func (r *JobRepo) SaveSchedule(ctx context.Context, name, spec string, nextRunAt time.Time) error {
+	ctx, span := startSpan(ctx, "JobRepo.SaveSchedule")
+	defer span.End()
+
+	query := `
+		INSERT INTO job_schedules (name, spec, next_run_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE
+		SET spec = EXCLUDED.spec, next_run_at = EXCLUDED.next_run_at
+		WHERE job_schedules.spec <> EXCLUDED.spec`
+
+	if _, err := r.db.ExecContext(ctx, query, name, spec, nextRunAt); err != nil {
+		return fmt.Errorf("failed to save job schedule: %w", err)
+	}
+	return nil
+}
+
+// AdvanceSchedule переносит наступивший запуск расписания на nextRunAt. Возвращает
+// false, если время запуска еще не пришло или его уже забрал другой экземпляр.
+func (r *JobRepo) AdvanceSchedule(ctx context.Context, name string, nextRunAt time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "JobRepo.AdvanceSchedule")
+	defer span.End()
+
+	query := `
+		UPDATE job_schedules
+		SET next_run_at = $2, last_run_at = now()
+		WHERE name = $1 AND next_run_at <= now()`
+
+	result, err := r.db.ExecContext(ctx, query, name, nextRunAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance job schedule: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rowsAffected == 1, nil
+}
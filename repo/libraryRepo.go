@@ -0,0 +1,186 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+// LibraryRepo хранит личные библиотеки пользователей (таблица user_media).
+type LibraryRepo struct {
+	db DBTX
+}
+
+func NewLibraryRepo(db *sql.DB) *LibraryRepo {
+	return &LibraryRepo{db: traceDB(db)}
+}
+
+func (r *LibraryRepo) WithTx(tx *sql.Tx) *LibraryRepo {
+	return &LibraryRepo{db: traceDB(tx)}
+}
+
+var libraryEntryColumns = `um.user_id, um.status, um.progress, um.started_on, um.finished_on, um.score, um.created_at, um.updated_at,
+	` + mediaColumns("m")
+
+func scanLibraryEntry(row interface{ Scan(...any) error }) (models.UserMedia, error) {
+	var entry models.UserMedia
+	dest := []any{&entry.UserID, &entry.Status, &entry.Progress, &entry.StartedOn, &entry.FinishedOn, &entry.Score,
+		&entry.CreatedAt, &entry.UpdatedAt}
+	err := row.Scan(append(dest, mediaFields(&entry.Media)...)...)
+	return entry, err
+}
+
+// SaveEntry добавляет медиа в библиотеку или обновляет существующую запись.
+// Возвращает created_at и updated_at сохраненной записи.
+func (r *LibraryRepo) SaveEntry(ctx context.Context, entry models.UserMedia) (models.UserMedia, error) {
+	ctx, span := startSpan(ctx, "LibraryRepo.SaveEntry")
+	defer span.End()
+
+	query := `
+		INSERT INTO user_media (user_id, media_id, status, progress, started_on, finished_on, score)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (user_id, media_id) DO UPDATE
+		SET status = EXCLUDED.status,
+		    progress = EXCLUDED.progress,
+		    started_on = EXCLUDED.started_on,
+		    finished_on = EXCLUDED.finished_on,
+		    score = EXCLUDED.score,
+		    updated_at = now()
+		RETURNING created_at, updated_at`
+
+	err := r.db.QueryRowContext(ctx, query, entry.UserID, entry.Media.ID, entry.Status, entry.Progress,
+		entry.StartedOn, entry.FinishedOn, entry.Score).Scan(&entry.CreatedAt, &entry.UpdatedAt)
+	if err != nil {
+		return models.UserMedia{}, fmt.Errorf("failed to save library entry: %w", err)
+	}
+	return entry, nil
+}
+
+// GetEntry возвращает запись библиотеки или sql.ErrNoRows.
+func (r *LibraryRepo) GetEntry(ctx context.Context, userID, mediaID int) (models.UserMedia, error) {
+	ctx, span := startSpan(ctx, "LibraryRepo.GetEntry")
+	defer span.End()
+
+	query := `
+		SELECT ` + libraryEntryColumns + `
+		FROM user_media um
+		JOIN media_items m ON m.media_id = um.media_id
+		WHERE um.user_id = $1 AND um.media_id = $2`
+
+	return scanLibraryEntry(r.db.QueryRowContext(ctx, query, userID, mediaID))
+}
+
+// GetEntries возвращает библиотеку пользователя, недавно измененные записи первыми.
+// Пустой status - все записи.
+func (r *LibraryRepo) GetEntries(ctx context.Context, userID int, status models.LibraryStatus, page, limit int) ([]models.UserMedia, int64, error) {
+	ctx, span := startSpan(ctx, "LibraryRepo.GetEntries")
+	defer span.End()
+
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM user_media WHERE user_id = $1 AND ($2 = '' OR status = $2)"
+	if err := r.db.QueryRowContext(ctx, countQuery, userID, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count library entries: %w", err)
+	}
+	if total == 0 {
+		return []models.UserMedia{}, 0, nil
+	}
+
+	query := `
+		SELECT ` + libraryEntryColumns + `
+		FROM user_media um
+		JOIN media_items m ON m.media_id = um.media_id
+		WHERE um.user_id = $1 AND ($2 = '' OR um.status = $2)
+		ORDER BY um.updated_at DESC, um.media_id
+		LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get library entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.UserMedia{}
+	for rows.Next() {
+		entry, err := scanLibraryEntry(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan library entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// GetAllEntries возвращает всю библиотеку пользователя (для выгрузки данных).
+func (r *LibraryRepo) GetAllEntries(ctx context.Context, userID int) ([]models.UserMedia, error) {
+	ctx, span := startSpan(ctx, "LibraryRepo.GetAllEntries")
+	defer span.End()
+
+	query := `
+		SELECT ` + libraryEntryColumns + `
+		FROM user_media um
+		JOIN media_items m ON m.media_id = um.media_id
+		WHERE um.user_id = $1
+		ORDER BY um.created_at`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get library entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []models.UserMedia{}
+	for rows.Next() {
+		entry, err := scanLibraryEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan library entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// DeleteEntry убирает медиа из библиотеки. Возвращает sql.ErrNoRows, если записи не было.
+func (r *LibraryRepo) DeleteEntry(ctx context.Context, userID, mediaID int) error {
+	ctx, span := startSpan(ctx, "LibraryRepo.DeleteEntry")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM user_media WHERE user_id = $1 AND media_id = $2", userID, mediaID)
+	if err != nil {
+		return fmt.Errorf("failed to delete library entry: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// GetMediaRatings собирает оценки и статусы медиа из библиотек видимых пользователей.
+func (r *LibraryRepo) GetMediaRatings(ctx context.Context, mediaID int) (models.MediaRatings, error) {
+	ctx, span := startSpan(ctx, "LibraryRepo.GetMediaRatings")
+	defer span.End()
+
+	query := `
+		SELECT
+			ROUND(AVG(um.score), 2)::float8,
+			COUNT(um.score),
+			COUNT(*),
+			COUNT(*) FILTER (WHERE um.status = 'completed')
+		FROM user_media um
+		JOIN users u ON u.user_id = um.user_id
+		WHERE um.media_id = $1 AND ` + visibleUser("u")
+
+	var ratings models.MediaRatings
+	err := r.db.QueryRowContext(ctx, query, mediaID).Scan(&ratings.AverageScore, &ratings.ScoreCount,
+		&ratings.LibraryCount, &ratings.CompletedCount)
+	if err != nil {
+		return models.MediaRatings{}, fmt.Errorf("failed to get media ratings: %w", err)
+	}
+	return ratings, nil
+}
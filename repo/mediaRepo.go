@@ -13,17 +13,46 @@ type MediaRepo struct {
 }
 
 func NewMediaRepo(db *sql.DB) *MediaRepo {
-	return &MediaRepo{db: db}
+	return &MediaRepo{db: traceDB(db)}
 }
 
 func (r *MediaRepo) WithTx(tx *sql.Tx) *MediaRepo {
-    return &MediaRepo{db: tx}
+    return &MediaRepo{db: traceDB(tx)}
 }
 
 
+// mediaColumns - колонки media_items для чтения через mediaFields. Создатели, жанры и теги
+// собираются подзапросами в jsonb-массивы, чтобы не делать отдельные запросы на каждое медиа.
+func mediaColumns(alias string) string {
+	return fmt.Sprintf(`%[1]s.media_id, %[1]s.item_type, %[1]s.name, %[1]s.year, %[1]s.author,
+		%[1]s.original_title, %[1]s.description, %[1]s.cover_url, %[1]s.metadata,
+		COALESCE((
+			SELECT jsonb_agg(jsonb_build_object('name', c.name, 'role', c.role) ORDER BY c.position)
+			FROM media_creators c WHERE c.media_id = %[1]s.media_id
+		), '[]'::jsonb),
+		COALESCE((
+			SELECT jsonb_agg(g.slug ORDER BY g.slug)
+			FROM media_genres mg JOIN genres g ON g.genre_id = mg.genre_id WHERE mg.media_id = %[1]s.media_id
+		), '[]'::jsonb),
+		COALESCE((
+			SELECT jsonb_agg(t.slug ORDER BY t.slug)
+			FROM media_tags mt JOIN tags t ON t.tag_id = mt.tag_id WHERE mt.media_id = %[1]s.media_id
+		), '[]'::jsonb),
+		%[1]s.created_at`, alias)
+}
+
+func mediaFields(media *models.MediaItem) []any {
+	return []any{&media.ID, &media.Type, &media.Name, &media.Year, &media.Author,
+		&media.OriginalTitle, &media.Description, &media.CoverURL, &media.Metadata, &media.Creators,
+		&media.Genres, &media.Tags, &media.CreatedAt}
+}
+
 func (r *MediaRepo) FindMedia(ctx context.Context, mtype, name string) ([]models.MediaItem, error) {
+	ctx, span := startSpan(ctx, "MediaRepo.FindMedia")
+	defer span.End()
+
 	// 1. Начинаем с базового запроса
-	query := "SELECT media_id, item_type, name, year, author, created_at FROM media_items WHERE 1=1"
+	query := "SELECT " + mediaColumns("m") + " FROM media_items m WHERE 1=1"
 
 	// 2. Создаем срез для хранения аргументов для плейсхолдеров
 	var args []interface{}
@@ -31,20 +60,20 @@ func (r *MediaRepo) FindMedia(ctx context.Context, mtype, name string) ([]models
 	// 3. Динамически добавляем условия в WHERE
 	if mtype != "" {
 		// Добавляем условие в запрос
-		query += fmt.Sprintf(" AND item_type = $%d", len(args)+1)
+		query += fmt.Sprintf(" AND m.item_type = $%d", len(args)+1)
 		// Добавляем значение в срез аргументов
 		args = append(args, mtype)
 	}
 
 	if name != "" {
 		// Используем ILIKE для регистронезависимого поиска по части строки
-		query += fmt.Sprintf(" AND name ILIKE $%d", len(args)+1)
+		query += fmt.Sprintf(" AND m.name ILIKE $%d", len(args)+1)
 		// Добавляем '%' для поиска по префиксу
 		args = append(args, "%"+name+"%")
 	}
 
 	// 4. (Опционально) Добавляем сортировку и ограничение
-	query += " ORDER BY name LIMIT 20"
+	query += " ORDER BY m.name LIMIT 20"
 
 	// 5. Выполняем финальный, собранный запрос
 	sqlRows, err := r.db.QueryContext(ctx, query, args...) // 'args...' - это специальный синтаксис для передачи среза как отдельных аргументов
@@ -57,13 +86,7 @@ func (r *MediaRepo) FindMedia(ctx context.Context, mtype, name string) ([]models
 	var media_items []models.MediaItem
 	for sqlRows.Next() {
 		var media_item models.MediaItem
-		if err := sqlRows.Scan(
-			&media_item.ID,
-			&media_item.Type,
-			&media_item.Name,
-			&media_item.Year,
-			&media_item.Author,
-			&media_item.CreatedAt); err != nil {
+		if err := sqlRows.Scan(mediaFields(&media_item)...); err != nil {
 			return nil, fmt.Errorf("ошибка сканирования строки: %w", err)
 		}
 		media_items = append(media_items, media_item)
@@ -73,21 +96,81 @@ func (r *MediaRepo) FindMedia(ctx context.Context, mtype, name string) ([]models
 }
 
 func (r *MediaRepo) GetMedia(ctx context.Context, mediaID int) (models.MediaItem, error) {
-	query := "SELECT media_id, item_type, name, year, author, created_at FROM media_items WHERE media_id=$1"
+	ctx, span := startSpan(ctx, "MediaRepo.GetMedia")
+	defer span.End()
+
+	query := "SELECT " + mediaColumns("m") + " FROM media_items m WHERE m.media_id=$1"
 
 	var media_item models.MediaItem
-	if err := r.db.QueryRowContext(ctx, query, mediaID).Scan(
-		&media_item.ID,
-		&media_item.Type,
-		&media_item.Name,
-		&media_item.Year,
-		&media_item.Author,
-		&media_item.CreatedAt); err != nil {
+	if err := r.db.QueryRowContext(ctx, query, mediaID).Scan(mediaFields(&media_item)...); err != nil {
 		if err == sql.ErrNoRows {
-			return models.MediaItem{}, fmt.Errorf("media with id %d not found", mediaID)
+			return models.MediaItem{}, fmt.Errorf("media with id %d not found: %w", mediaID, sql.ErrNoRows)
 		}
 		return models.MediaItem{}, fmt.Errorf("error while scanning row: %w", err)
 
 	}
 	return media_item, nil
 }
+
+func (r *MediaRepo) CreateMedia(ctx context.Context, media models.MediaItem) (models.MediaItem, error) {
+	ctx, span := startSpan(ctx, "MediaRepo.CreateMedia")
+	defer span.End()
+
+	query := `
+		INSERT INTO media_items (item_type, name, year, author, original_title, description, cover_url, metadata)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING media_id, created_at`
+
+	err := r.db.QueryRowContext(ctx, query, media.Type, media.Name, media.Year, media.Author,
+		media.OriginalTitle, media.Description, media.CoverURL, media.Metadata).Scan(&media.ID, &media.CreatedAt)
+	if err != nil {
+		return models.MediaItem{}, fmt.Errorf("failed to create media item: %w", err)
+	}
+	return media, nil
+}
+
+// UpdateMedia перезаписывает сведения о медиа. Возвращает sql.ErrNoRows, если медиа нет.
+func (r *MediaRepo) UpdateMedia(ctx context.Context, media models.MediaItem) error {
+	ctx, span := startSpan(ctx, "MediaRepo.UpdateMedia")
+	defer span.End()
+
+	query := `
+		UPDATE media_items
+		SET item_type = $2, name = $3, year = $4, author = $5,
+		    original_title = $6, description = $7, cover_url = $8, metadata = $9
+		WHERE media_id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, media.ID, media.Type, media.Name, media.Year, media.Author,
+		media.OriginalTitle, media.Description, media.CoverURL, media.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to update media item: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ReplaceCreators заменяет список создателей медиа; порядок в срезе сохраняется.
+func (r *MediaRepo) ReplaceCreators(ctx context.Context, mediaID int, creators []models.MediaCreator) error {
+	ctx, span := startSpan(ctx, "MediaRepo.ReplaceCreators")
+	defer span.End()
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM media_creators WHERE media_id = $1", mediaID); err != nil {
+		return fmt.Errorf("failed to clear media creators: %w", err)
+	}
+
+	for position, creator := range creators {
+		_, err := r.db.ExecContext(ctx, "INSERT INTO media_creators (media_id, position, name, role) VALUES ($1, $2, $3, $4)",
+			mediaID, position, creator.Name, creator.Role)
+		if err != nil {
+			return fmt.Errorf("failed to add media creator: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+// ModerationRepo хранит жалобы пользователей и журнал действий модераторов.
+type ModerationRepo struct {
+	db DBTX
+}
+
+func NewModerationRepo(db *sql.DB) *ModerationRepo {
+	return &ModerationRepo{db: traceDB(db)}
+}
+
+func (r *ModerationRepo) WithTx(tx *sql.Tx) *ModerationRepo {
+	return &ModerationRepo{db: traceDB(tx)}
+}
+
+const reportColumns = `report_id, reporter_id, target_type, target_id, reason, details, status,
+	assignee_id, resolution_note, created_at, closed_at`
+
+func scanReport(row interface{ Scan(...any) error }) (models.Report, error) {
+	var report models.Report
+	err := row.Scan(&report.ID, &report.ReporterID, &report.TargetType, &report.TargetID, &report.Reason,
+		&report.Details, &report.Status, &report.AssigneeID, &report.ResolutionNote, &report.CreatedAt, &report.ClosedAt)
+	return report, err
+}
+
+// CreateReport сохраняет жалобу. Возвращает sql.ErrNoRows, если у пользователя уже
+// есть открытая жалоба на этот объект.
+func (r *ModerationRepo) CreateReport(ctx context.Context, report models.Report) (models.Report, error) {
+	ctx, span := startSpan(ctx, "ModerationRepo.CreateReport")
+	defer span.End()
+
+	query := `
+		INSERT INTO reports (reporter_id, target_type, target_id, reason, details)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT DO NOTHING
+		RETURNING ` + reportColumns
+
+	created, err := scanReport(r.db.QueryRowContext(ctx, query,
+		report.ReporterID, report.TargetType, report.TargetID, report.Reason, report.Details))
+	if err != nil {
+		return models.Report{}, fmt.Errorf("failed to create report: %w", err)
+	}
+	return created, nil
+}
+
+// GetReport возвращает жалобу или sql.ErrNoRows.
+func (r *ModerationRepo) GetReport(ctx context.Context, reportID int) (models.Report, error) {
+	ctx, span := startSpan(ctx, "ModerationRepo.GetReport")
+	defer span.End()
+
+	query := "SELECT " + reportColumns + " FROM reports WHERE report_id = $1"
+
+	return scanReport(r.db.QueryRowContext(ctx, query, reportID))
+}
+
+// ReportFilter - условия выборки очереди жалоб. Пустые поля не ограничивают выборку.
+type ReportFilter struct {
+	Status     models.ReportStatus
+	TargetType models.ReportTargetType
+	AssigneeID int
+}
+
+// ListReports возвращает жалобы, старые первыми: так очередь разбирается по порядку.
+func (r *ModerationRepo) ListReports(ctx context.Context, filter ReportFilter, page, limit int) ([]models.Report, int64, error) {
+	ctx, span := startSpan(ctx, "ModerationRepo.ListReports")
+	defer span.End()
+
+	conditions := []string{"TRUE"}
+	var args []any
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+	if filter.TargetType != "" {
+		args = append(args, filter.TargetType)
+		conditions = append(conditions, fmt.Sprintf("target_type = $%d", len(args)))
+	}
+	if filter.AssigneeID != 0 {
+		args = append(args, filter.AssigneeID)
+		conditions = append(conditions, fmt.Sprintf("assignee_id = $%d", len(args)))
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM reports WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+	if total == 0 {
+		return []models.Report{}, 0, nil
+	}
+
+	args = append(args, limit, (page-1)*limit)
+	query := fmt.Sprintf("SELECT %s FROM reports WHERE %s ORDER BY created_at, report_id LIMIT $%d OFFSET $%d",
+		reportColumns, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := []models.Report{}
+	for rows.Next() {
+		report, err := scanReport(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan report: %w", err)
+		}
+		reports = append(reports, report)
+	}
+	return reports, total, rows.Err()
+}
+
+// AssignReport назначает открытую жалобу модератору. Возвращает sql.ErrNoRows,
+// если жалоба не найдена или уже закрыта.
+func (r *ModerationRepo) AssignReport(ctx context.Context, reportID, moderatorID int) (models.Report, error) {
+	ctx, span := startSpan(ctx, "ModerationRepo.AssignReport")
+	defer span.End()
+
+	query := "UPDATE reports SET assignee_id = $2 WHERE report_id = $1 AND status = 'open' RETURNING " + reportColumns
+
+	return scanReport(r.db.QueryRowContext(ctx, query, reportID, moderatorID))
+}
+
+// CloseReport закрывает открытую жалобу с итоговым статусом и комментарием.
+// Возвращает sql.ErrNoRows, если жалоба не найдена или уже закрыта.
+func (r *ModerationRepo) CloseReport(ctx context.Context, reportID, moderatorID int, status models.ReportStatus, note string) (models.Report, error) {
+	ctx, span := startSpan(ctx, "ModerationRepo.CloseReport")
+	defer span.End()
+
+	// Жалобу закрывает тот, кто принял решение, даже если она была назначена другому
+	query := `
+		UPDATE reports
+		SET status = $2, resolution_note = $3, assignee_id = $4, closed_at = now()
+		WHERE report_id = $1 AND status = 'open'
+		RETURNING ` + reportColumns
+
+	return scanReport(r.db.QueryRowContext(ctx, query, reportID, status, note, moderatorID))
+}
+
+// RecordAction добавляет запись в журнал действий модераторов.
+func (r *ModerationRepo) RecordAction(ctx context.Context, action models.ModerationAction) error {
+	ctx, span := startSpan(ctx, "ModerationRepo.RecordAction")
+	defer span.End()
+
+	query := `
+		INSERT INTO moderation_actions (moderator_id, report_id, action, target_type, target_id, note)
+		VALUES ($1, $2, $3, $4, $5, $6)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		action.ModeratorID, action.ReportID, action.Action, action.TargetType, action.TargetID, action.Note)
+	if err != nil {
+		return fmt.Errorf("failed to record moderation action: %w", err)
+	}
+	return nil
+}
+
+// GetReportActions возвращает историю работы с жалобой в хронологическом порядке.
+func (r *ModerationRepo) GetReportActions(ctx context.Context, reportID int) ([]models.ModerationAction, error) {
+	ctx, span := startSpan(ctx, "ModerationRepo.GetReportActions")
+	defer span.End()
+
+	// moderator_id = NULL, если аккаунт модератора уже стерт; в модели это 0
+	query := `
+		SELECT action_id, COALESCE(moderator_id, 0), report_id, action, target_type, target_id, note, created_at
+		FROM moderation_actions
+		WHERE report_id = $1
+		ORDER BY created_at, action_id`
+
+	rows, err := r.db.QueryContext(ctx, query, reportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderation actions: %w", err)
+	}
+	defer rows.Close()
+
+	actions := []models.ModerationAction{}
+	for rows.Next() {
+		var action models.ModerationAction
+		if err := rows.Scan(&action.ID, &action.ModeratorID, &action.ReportID, &action.Action,
+			&action.TargetType, &action.TargetID, &action.Note, &action.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan moderation action: %w", err)
+		}
+		actions = append(actions, action)
+	}
+	return actions, rows.Err()
+}
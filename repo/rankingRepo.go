@@ -0,0 +1,130 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+// RankingRepo пересчитывает и читает таблицу media_rankings.
+type RankingRepo struct {
+	db DBTX
+}
+
+func NewRankingRepo(db *sql.DB) *RankingRepo {
+	return &RankingRepo{db: traceDB(db)}
+}
+
+func (r *RankingRepo) WithTx(tx *sql.Tx) *RankingRepo {
+	return &RankingRepo{db: traceDB(tx)}
+}
+
+// RebuildRanking заменяет рейтинг kind за окно window. В рейтинг попадают
+// size лучших медиа каждого типа.
+func (r *RankingRepo) RebuildRanking(ctx context.Context, kind models.RankingKind, window models.RankingWindow, size int) (int64, error) {
+	ctx, span := startSpan(ctx, "RankingRepo.RebuildRanking")
+	defer span.End()
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM media_rankings WHERE ranking = $1 AND time_window = $2", kind, window); err != nil {
+		return 0, fmt.Errorf("failed to clear %s ranking: %w", kind, err)
+	}
+
+	args := []any{kind, window, size}
+	score := "COUNT(*)::float8"
+	condition := "TRUE"
+	if seconds := window.Duration().Seconds(); seconds > 0 {
+		args = append(args, seconds)
+		condition = "r.created_at >= now() - make_interval(secs => $4)"
+		if kind == models.RankingTrending {
+			// Вес рекомендации линейно падает от 1 (только что) до 0 (на границе окна)
+			score = "SUM(GREATEST(0, 1 - EXTRACT(EPOCH FROM now() - r.created_at) / $4))::float8"
+		}
+	}
+
+	query := fmt.Sprintf(`
+		WITH scores AS (
+			SELECT r.media_id, m.item_type::text AS item_type, COUNT(*) AS recommendation_count, %s AS score
+			FROM recommendations r
+			JOIN media_items m ON m.media_id = r.media_id
+			WHERE %s
+			GROUP BY r.media_id, m.item_type
+		), ranked AS (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY item_type ORDER BY score DESC, recommendation_count DESC, media_id) AS type_rank
+			FROM scores
+		)
+		INSERT INTO media_rankings (ranking, time_window, media_id, item_type, overall_rank, type_rank, score, recommendation_count, computed_at)
+		SELECT $1, $2, media_id, item_type,
+			ROW_NUMBER() OVER (ORDER BY score DESC, recommendation_count DESC, media_id),
+			type_rank, score, recommendation_count, now()
+		FROM ranked
+		WHERE type_rank <= $3`, score, condition)
+
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild %s ranking: %w", kind, err)
+	}
+	return result.RowsAffected()
+}
+
+// GetRanking возвращает страницу рейтинга. Если задан mediaType, места
+// считаются среди медиа этого типа.
+func (r *RankingRepo) GetRanking(ctx context.Context, kind models.RankingKind, window models.RankingWindow, mediaType models.MediaType,
+	page, limit int) ([]models.RankedMedia, int64, error) {
+	ctx, span := startSpan(ctx, "RankingRepo.GetRanking")
+	defer span.End()
+
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM media_rankings WHERE ranking = $1 AND time_window = $2 AND ($3 = '' OR item_type = $3)"
+	if err := r.db.QueryRowContext(ctx, countQuery, kind, window, mediaType).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count %s ranking: %w", kind, err)
+	}
+	if total == 0 {
+		return []models.RankedMedia{}, 0, nil
+	}
+
+	rank := "mr.overall_rank"
+	if mediaType != "" {
+		rank = "mr.type_rank"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, mr.score, mr.recommendation_count, %[2]s
+		FROM media_rankings mr
+		JOIN media_items m ON m.media_id = mr.media_id
+		WHERE mr.ranking = $1 AND mr.time_window = $2 AND ($3 = '' OR mr.item_type = $3)
+		ORDER BY %[1]s
+		LIMIT $4 OFFSET $5`, rank, mediaColumns("m"))
+
+	rows, err := r.db.QueryContext(ctx, query, kind, window, mediaType, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get %s ranking: %w", kind, err)
+	}
+	defer rows.Close()
+
+	items := []models.RankedMedia{}
+	for rows.Next() {
+		var item models.RankedMedia
+		dest := []any{&item.Rank, &item.Score, &item.RecommendationCount}
+		if err := rows.Scan(append(dest, mediaFields(&item.Media)...)...); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan ranked media: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, total, rows.Err()
+}
+
+// GetComputedAt возвращает время последнего пересчета рейтинга или nil, если его еще не было.
+func (r *RankingRepo) GetComputedAt(ctx context.Context, kind models.RankingKind, window models.RankingWindow) (*time.Time, error) {
+	ctx, span := startSpan(ctx, "RankingRepo.GetComputedAt")
+	defer span.End()
+
+	var computedAt *time.Time
+	query := "SELECT MAX(computed_at) FROM media_rankings WHERE ranking = $1 AND time_window = $2"
+	if err := r.db.QueryRowContext(ctx, query, kind, window).Scan(&computedAt); err != nil {
+		return nil, fmt.Errorf("failed to get %s ranking time: %w", kind, err)
+	}
+	return computedAt, nil
+}
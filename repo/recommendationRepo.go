@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cobrich/recommendo/models"
 )
@@ -13,15 +15,18 @@ type RecommendationRepo struct {
 }
 
 func NewRecommendationRepo(db *sql.DB) *RecommendationRepo {
-	return &RecommendationRepo{db: db}
+	return &RecommendationRepo{db: traceDB(db)}
 }
 
 func (r *RecommendationRepo) WithTx(tx *sql.Tx) *RecommendationRepo {
-    return &RecommendationRepo{db: tx}
+    return &RecommendationRepo{db: traceDB(tx)}
 }
 
 
 func (r *RecommendationRepo) GetRecommendation(ctx context.Context, fromId, toID, mediaID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.GetRecommendation")
+	defer span.End()
+
 	var recommendation models.Recommendation
 
 	query := `SELECT recommendation_id, from_user_id, to_user_id, media_id, created_at FROM recommendations 
@@ -42,41 +47,141 @@ func (r *RecommendationRepo) GetRecommendation(ctx context.Context, fromId, toID
 	return nil
 }
 
-func (r *RecommendationRepo) CreateRecommendation(ctx context.Context, fromId, toID, mediaID int) error {
+// CreateRecommendation создает рекомендацию и возвращает ее ID.
+func (r *RecommendationRepo) CreateRecommendation(ctx context.Context, fromId, toID, mediaID int) (int, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.CreateRecommendation")
+	defer span.End()
+
 	query := `
         INSERT INTO recommendations (from_user_id, to_user_id, media_id)
         VALUES ($1, $2, $3)
+        RETURNING recommendation_id
 		`
 
-	result, err := r.db.ExecContext(ctx, query, fromId, toID, mediaID)
-	if err != nil {
+	var recommendationID int
+	if err := r.db.QueryRowContext(ctx, query, fromId, toID, mediaID).Scan(&recommendationID); err != nil {
 		// Если произошла ошибка (например, нарушение UNIQUE constraint), мы ее получим.
-		return fmt.Errorf("failed to create recommendation: %w", err)
+		return 0, fmt.Errorf("failed to create recommendation: %w", err)
 	}
 
-	// 3. (Опционально, но хорошая практика) Проверяем, что была затронута ровно одна строка.
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		// Маловероятная ошибка, но проверка не помешает.
-		return fmt.Errorf("failed to check rows affected: %w", err)
+	return recommendationID, nil
+}
+
+// CreateSharedRecommendation создает рекомендацию из принятой ссылки: с заметкой автора
+// и без проверки дружбы.
+func (r *RecommendationRepo) CreateSharedRecommendation(ctx context.Context, fromID, toID, mediaID int, note, shareID string) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.CreateSharedRecommendation")
+	defer span.End()
+
+	query := `
+		INSERT INTO recommendations (from_user_id, to_user_id, media_id, note, share_id)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	if _, err := r.db.ExecContext(ctx, query, fromID, toID, mediaID, note, shareID); err != nil {
+		return fmt.Errorf("failed to create shared recommendation: %w", err)
 	}
-	if rowsAffected == 0 {
-		return fmt.Errorf("no rows were affected, following not created")
+	return nil
+}
+
+// Порядок выдачи списков рекомендаций.
+const (
+	RecommendationSortDate = "date" // по дате рекомендации
+	RecommendationSortName = "name" // по названию медиа
+	RecommendationSortYear = "year" // по году выхода медиа
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// RecommendationFilter - фильтры и сортировка списков отправленных и полученных рекомендаций.
+// Нулевые значения полей означают отсутствие фильтра.
+type RecommendationFilter struct {
+	MediaType models.MediaType
+	// UserID - второй участник: получатель для отправленных, отправитель для полученных
+	UserID int
+	From   time.Time
+	To     time.Time
+	Status models.RecommendationStatus
+	// State учитывается только для полученных
+	State models.InboxState
+	// IncludeHidden - не отбрасывать скрытые получателем (выгрузка данных, модерация)
+	IncludeHidden bool
+	Sort  string
+	// Order - SortAsc или SortDesc; по умолчанию по дате и году сначала новые, по названию - от А до Я
+	Order string
+}
+
+// where собирает условия фильтра; counterpart - колонка второго участника.
+// Аргументы дописываются после уже переданных в args.
+func (f RecommendationFilter) where(counterpart string, args []any) (string, []any) {
+	var conditions []string
+	if f.MediaType != "" {
+		args = append(args, f.MediaType)
+		conditions = append(conditions, fmt.Sprintf("m.item_type = $%d", len(args)))
 	}
+	if f.UserID != 0 {
+		args = append(args, f.UserID)
+		conditions = append(conditions, fmt.Sprintf("%s = $%d", counterpart, len(args)))
+	}
+	if !f.From.IsZero() {
+		args = append(args, f.From)
+		conditions = append(conditions, fmt.Sprintf("r.created_at >= $%d", len(args)))
+	}
+	if !f.To.IsZero() {
+		args = append(args, f.To)
+		conditions = append(conditions, fmt.Sprintf("r.created_at < $%d", len(args)))
+	}
+	if f.Status != "" {
+		args = append(args, f.Status)
+		conditions = append(conditions, fmt.Sprintf("r.status = $%d", len(args)))
+	}
+	if len(conditions) == 0 {
+		return "TRUE", args
+	}
+	return strings.Join(conditions, " AND "), args
+}
 
-	return nil
+// orderBy возвращает ORDER BY для фильтра; recommendation_id в конце делает порядок стабильным.
+func (f RecommendationFilter) orderBy() string {
+	switch f.Sort {
+	case RecommendationSortName:
+		direction := "ASC"
+		if f.Order == SortDesc {
+			direction = "DESC"
+		}
+		return "lower(m.name) " + direction + ", m.year DESC, r.recommendation_id DESC"
+	case RecommendationSortYear:
+		direction := "DESC"
+		if f.Order == SortAsc {
+			direction = "ASC"
+		}
+		return "m.year " + direction + ", r.created_at DESC, r.recommendation_id DESC"
+	default:
+		direction := "DESC"
+		if f.Order == SortAsc {
+			direction = "ASC"
+		}
+		return "r.created_at " + direction + ", r.recommendation_id " + direction
+	}
 }
 
 // GetSentRecommendations возвращает список рекомендаций, ОТПРАВЛЕННЫХ пользователем.
-func (r *RecommendationRepo) GetSentRecommendations(ctx context.Context, userID int) ([]models.RecommendationDetails, error) {
+func (r *RecommendationRepo) GetSentRecommendations(ctx context.Context, userID int, filter RecommendationFilter) ([]models.RecommendationDetails, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.GetSentRecommendations")
+	defer span.End()
+
+	conditions, args := filter.where("r.to_user_id", []any{userID})
+
 	// SQL-запрос, который объединяет 3 таблицы: recommendations, media_items и users (для получателя).
 	query := `
 		SELECT
 			r.recommendation_id,
 			r.created_at,
+			r.status,
+			r.note,
 			
 			-- Поля для media_items
-			m.media_id, m.item_type, m.name, m.year, m.author, m.created_at,
+			` + mediaColumns("m") + `,
 			
 			-- Поля для users (получателя рекомендации)
 			u.user_id, u.user_name, u.created_at
@@ -88,12 +193,13 @@ func (r *RecommendationRepo) GetSentRecommendations(ctx context.Context, userID
 			-- Присоединяем информацию о том, КОМУ порекомендовали
 			users u ON r.to_user_id = u.user_id
 		WHERE
-			r.from_user_id = $1
+			r.from_user_id = $1 AND ` + visibleUser("u") + `
+			AND ` + conditions + `
 		ORDER BY
-			r.created_at DESC;
+			` + filter.orderBy() + `;
 	`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sent recommendations: %w", err)
 	}
@@ -104,12 +210,10 @@ func (r *RecommendationRepo) GetSentRecommendations(ctx context.Context, userID
 		var rec models.RecommendationDetails
 		// Сканируем результат в поля нашей "богатой" структуры.
 		// Обратите внимание на вложенные поля rec.Media и rec.User.
-		if err := rows.Scan(
-			&rec.RecommendationID,
-			&rec.CreatedAt,
-			&rec.Media.ID, &rec.Media.Type, &rec.Media.Name, &rec.Media.Year, &rec.Media.Author, &rec.Media.CreatedAt,
-			&rec.User.ID, &rec.User.UserName, &rec.User.CreatedAt,
-		); err != nil {
+		dest := []any{&rec.RecommendationID, &rec.CreatedAt, &rec.Status, &rec.Note}
+		dest = append(dest, mediaFields(&rec.Media)...)
+		dest = append(dest, &rec.User.ID, &rec.User.UserName, &rec.User.CreatedAt)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("failed to scan sent recommendation row: %w", err)
 		}
 		recommendations = append(recommendations, rec)
@@ -118,15 +222,52 @@ func (r *RecommendationRepo) GetSentRecommendations(ctx context.Context, userID
 	return recommendations, nil
 }
 
+// inboxCondition возвращает SQL-условие для состояния во входящих (таблица recommendations с псевдонимом r).
+// Отложенная рекомендация возвращается во входящие, когда наступает snoozed_until.
+// Скрытые получателем рекомендации видны только в состоянии InboxHidden.
+func inboxCondition(state models.InboxState) string {
+	const notSnoozed = "(r.snoozed_until IS NULL OR r.snoozed_until <= now())"
+	const notHidden = "r.hidden_at IS NULL AND "
+	switch state {
+	case models.InboxActive:
+		return notHidden + "r.archived_at IS NULL AND " + notSnoozed
+	case models.InboxUnread:
+		return notHidden + "r.read_at IS NULL AND r.archived_at IS NULL AND " + notSnoozed
+	case models.InboxRead:
+		return notHidden + "r.read_at IS NOT NULL AND r.archived_at IS NULL AND " + notSnoozed
+	case models.InboxSnoozed:
+		return notHidden + "r.archived_at IS NULL AND r.snoozed_until > now()"
+	case models.InboxArchived:
+		return notHidden + "r.archived_at IS NOT NULL"
+	case models.InboxHidden:
+		return "r.hidden_at IS NOT NULL"
+	default:
+		return "TRUE"
+	}
+}
+
 // GetReceivedRecommendations возвращает список рекомендаций, ПОЛУЧЕННЫХ пользователем.
-func (r *RecommendationRepo) GetReceivedRecommendations(ctx context.Context, userID int) ([]models.RecommendationDetails, error) {
+// Пустой filter.State - все рекомендации независимо от состояния во входящих.
+func (r *RecommendationRepo) GetReceivedRecommendations(ctx context.Context, userID int, filter RecommendationFilter) ([]models.RecommendationDetails, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.GetReceivedRecommendations")
+	defer span.End()
+
+	conditions, args := filter.where("r.from_user_id", []any{userID})
+	state := inboxCondition(filter.State)
+	if filter.State == "" && !filter.IncludeHidden {
+		state = "r.hidden_at IS NULL"
+	}
+
 	// Запрос очень похож, но меняются условия в JOIN и WHERE.
 	query := `
 		SELECT
 			r.recommendation_id,
 			r.created_at,
+			r.status,
+			r.note,
+			r.read_at, r.archived_at, r.snoozed_until, r.hidden_at,
 			
-			m.media_id, m.item_type, m.name, m.year, m.author, m.created_at,
+			` + mediaColumns("m") + `,
 			
 			-- Присоединяем информацию о том, КТО порекомендовал
 			u.user_id, u.user_name, u.created_at
@@ -134,17 +275,21 @@ func (r *RecommendationRepo) GetReceivedRecommendations(ctx context.Context, use
 			recommendations r
 		JOIN
 			media_items m ON r.media_id = m.media_id
-		JOIN
-			-- Присоединяем информацию об ОТПРАВИТЕЛЕ рекомендации
+		LEFT JOIN
+			-- Присоединяем информацию об ОТПРАВИТЕЛЕ рекомендации.
+			-- LEFT JOIN: у рекомендаций от стертых аккаунтов отправителя нет
 			users u ON r.from_user_id = u.user_id
 		WHERE
 			r.to_user_id = $1 -- <-- Главное отличие здесь
+			AND (r.from_user_id IS NULL OR ` + visibleUser("u") + `)
+			AND ` + state + `
+			AND ` + conditions + `
 		ORDER BY
-			r.created_at DESC;
+			` + filter.orderBy() + `;
 	`
 
 	// Код для выполнения запроса и сканирования будет точно таким же, как в GetSentRecommendations
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get received recommendations: %w", err)
 	}
@@ -153,14 +298,19 @@ func (r *RecommendationRepo) GetReceivedRecommendations(ctx context.Context, use
 	var recommendations []models.RecommendationDetails
 	for rows.Next() {
 		var rec models.RecommendationDetails
-		if err := rows.Scan(
-			&rec.RecommendationID,
-			&rec.CreatedAt,
-			&rec.Media.ID, &rec.Media.Type, &rec.Media.Name, &rec.Media.Year, &rec.Media.Author, &rec.Media.CreatedAt,
-			&rec.User.ID, &rec.User.UserName, &rec.User.CreatedAt,
-		); err != nil {
+		var senderID sql.NullInt64
+		var senderName sql.NullString
+		var senderCreatedAt sql.NullTime
+		dest := []any{&rec.RecommendationID, &rec.CreatedAt, &rec.Status, &rec.Note, &rec.ReadAt, &rec.ArchivedAt, &rec.SnoozedUntil, &rec.HiddenAt}
+		dest = append(dest, mediaFields(&rec.Media)...)
+		dest = append(dest, &senderID, &senderName, &senderCreatedAt)
+		if err := rows.Scan(dest...); err != nil {
 			return nil, fmt.Errorf("failed to scan received recommendation row: %w", err)
 		}
+		// Для анонимизированных рекомендаций rec.User остается пустым (ID = 0)
+		rec.User.ID = int(senderID.Int64)
+		rec.User.UserName = senderName.String
+		rec.User.CreatedAt = senderCreatedAt.Time
 		recommendations = append(recommendations, rec)
 	}
 
@@ -168,14 +318,18 @@ func (r *RecommendationRepo) GetReceivedRecommendations(ctx context.Context, use
 }
 
 func (r *RecommendationRepo) GetRecommendationByID(ctx context.Context, recomID int) (models.Recommendation, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.GetRecommendationByID")
+	defer span.End()
+
 	var recommendation models.Recommendation
 
-	query := "SELECT recommendation_id, from_user_id, to_user_id, media_id, created_at FROM recommendations WHERE recommendation_id=$1"
+	// from_user_id = NULL у анонимизированных рекомендаций, в модели это 0
+	query := "SELECT recommendation_id, COALESCE(from_user_id, 0), to_user_id, media_id, created_at, status FROM recommendations WHERE recommendation_id=$1"
 
 	if err := r.db.QueryRowContext(ctx, query, recomID).Scan(
 		&recommendation.ID, &recommendation.FromUserID,
 		&recommendation.ToUserID, &recommendation.MediaID,
-		&recommendation.CreatedAt); err != nil {
+		&recommendation.CreatedAt, &recommendation.Status); err != nil {
 		if err == sql.ErrNoRows {
 			return models.Recommendation{}, sql.ErrNoRows
 		}
@@ -186,6 +340,9 @@ func (r *RecommendationRepo) GetRecommendationByID(ctx context.Context, recomID
 }
 
 func (r *RecommendationRepo) DeleteRecommendation(ctx context.Context, recomID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.DeleteRecommendation")
+	defer span.End()
+
 	query := `
 		DELETE FROM recommendations 
 		WHERE recommendation_id = $1
@@ -213,6 +370,9 @@ func (r *RecommendationRepo) DeleteRecommendation(ctx context.Context, recomID i
 // DeleteAllUserRecommendations удаляет все рекомендации, отправленные
 // или полученные пользователем.
 func (r *RecommendationRepo) DeleteAllUserRecommendations(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.DeleteAllUserRecommendations")
+	defer span.End()
+
     query := "DELETE FROM recommendations WHERE from_user_id = $1 OR to_user_id = $1"
 
     _, err := r.db.ExecContext(ctx, query, userID)
@@ -220,4 +380,310 @@ func (r *RecommendationRepo) DeleteAllUserRecommendations(ctx context.Context, u
         return fmt.Errorf("failed to delete all user recommendations: %w", err)
     }
     return nil
-}
\ No newline at end of file
+}
+
+// AnonymizeSentRecommendations отвязывает отправленные пользователем рекомендации
+// от его аккаунта: получатели их сохраняют, но без автора.
+func (r *RecommendationRepo) AnonymizeSentRecommendations(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.AnonymizeSentRecommendations")
+	defer span.End()
+
+	query := "UPDATE recommendations SET from_user_id = NULL WHERE from_user_id = $1"
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to anonymize sent recommendations: %w", err)
+	}
+	return nil
+}
+
+func (r *RecommendationRepo) DeleteReceivedRecommendations(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.DeleteReceivedRecommendations")
+	defer span.End()
+
+	query := "DELETE FROM recommendations WHERE to_user_id = $1"
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to delete received recommendations: %w", err)
+	}
+	return nil
+}
+
+// CompleteRecommendationsForMedia отмечает выполненными все рекомендации медиа,
+// полученные пользователем. Возвращает число обновленных рекомендаций.
+func (r *RecommendationRepo) CompleteRecommendationsForMedia(ctx context.Context, userID, mediaID int) (int64, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.CompleteRecommendationsForMedia")
+	defer span.End()
+
+	query := `
+		UPDATE recommendations
+		SET status = 'completed', completed_at = now()
+		WHERE to_user_id = $1 AND media_id = $2 AND status = 'pending'`
+
+	result, err := r.db.ExecContext(ctx, query, userID, mediaID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to complete recommendations: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetMediaRecommendationsForUser возвращает рекомендации медиа, которые касаются
+// пользователя: отправленные и полученные им, а также отправленные его друзьями.
+func (r *RecommendationRepo) GetMediaRecommendationsForUser(ctx context.Context, mediaID, userID int) ([]models.MediaRecommendation, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.GetMediaRecommendationsForUser")
+	defer span.End()
+
+	query := `
+		SELECT
+			r.recommendation_id, r.status, r.created_at,
+			f.user_id, f.user_name, f.created_at,
+			t.user_id, t.user_name, t.created_at
+		FROM recommendations r
+		-- LEFT JOIN: у рекомендаций от стертых аккаунтов отправителя нет
+		LEFT JOIN users f ON f.user_id = r.from_user_id
+		JOIN users t ON t.user_id = r.to_user_id
+		WHERE r.media_id = $1
+			AND (
+				r.from_user_id = $2 OR r.to_user_id = $2
+				OR EXISTS (
+					SELECT 1
+					FROM follows f1
+					JOIN follows f2 ON f1.follower_id = f2.following_id AND f1.following_id = f2.follower_id
+					WHERE f1.follower_id = $2 AND f1.following_id = r.from_user_id
+				)
+			)
+			-- Скрытые пользователем рекомендации не показываем ему самому
+			AND NOT (r.to_user_id = $2 AND r.hidden_at IS NOT NULL)
+			AND (r.from_user_id IS NULL OR ` + visibleUser("f") + `)
+			AND ` + visibleUser("t") + `
+		ORDER BY r.created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, mediaID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get media recommendations: %w", err)
+	}
+	defer rows.Close()
+
+	recommendations := []models.MediaRecommendation{}
+	for rows.Next() {
+		var rec models.MediaRecommendation
+		var senderID sql.NullInt64
+		var senderName sql.NullString
+		var senderCreatedAt sql.NullTime
+		if err := rows.Scan(
+			&rec.RecommendationID, &rec.Status, &rec.CreatedAt,
+			&senderID, &senderName, &senderCreatedAt,
+			&rec.To.ID, &rec.To.UserName, &rec.To.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan media recommendation row: %w", err)
+		}
+		rec.From.ID = int(senderID.Int64)
+		rec.From.UserName = senderName.String
+		rec.From.CreatedAt = senderCreatedAt.Time
+		recommendations = append(recommendations, rec)
+	}
+
+	return recommendations, rows.Err()
+}
+
+// CountMediaRecipients возвращает, скольким пользователям рекомендовали медиа.
+func (r *RecommendationRepo) CountMediaRecipients(ctx context.Context, mediaID int) (int64, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.CountMediaRecipients")
+	defer span.End()
+
+	var count int64
+	query := "SELECT COUNT(DISTINCT to_user_id) FROM recommendations WHERE media_id = $1"
+	if err := r.db.QueryRowContext(ctx, query, mediaID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count media recipients: %w", err)
+	}
+	return count, nil
+}
+
+// CountUnreadRecommendations возвращает число непрочитанных рекомендаций во входящих.
+func (r *RecommendationRepo) CountUnreadRecommendations(ctx context.Context, userID int) (int64, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.CountUnreadRecommendations")
+	defer span.End()
+
+	query := `
+		SELECT COUNT(*)
+		FROM recommendations r
+		LEFT JOIN users u ON r.from_user_id = u.user_id
+		WHERE r.to_user_id = $1
+			AND (r.from_user_id IS NULL OR ` + visibleUser("u") + `)
+			AND ` + inboxCondition(models.InboxUnread)
+
+	var count int64
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count unread recommendations: %w", err)
+	}
+	return count, nil
+}
+
+// MarkRecommendationsRead отмечает прочитанными рекомендации из входящих получателя.
+// Пустой recomIDs - все непрочитанные. Возвращает число отмеченных.
+func (r *RecommendationRepo) MarkRecommendationsRead(ctx context.Context, userID int, recomIDs []int64) (int64, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.MarkRecommendationsRead")
+	defer span.End()
+
+	query := `
+		UPDATE recommendations r
+		SET read_at = now()
+		WHERE r.to_user_id = $1
+			AND (cardinality($2::bigint[]) = 0 OR r.recommendation_id = ANY($2::bigint[]))
+			AND ` + inboxCondition(models.InboxUnread)
+
+	result, err := r.db.ExecContext(ctx, query, userID, recomIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark recommendations read: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// MarkRecommendationUnread возвращает рекомендации статус непрочитанной.
+// Возвращает sql.ErrNoRows, если у получателя нет такой рекомендации.
+func (r *RecommendationRepo) MarkRecommendationUnread(ctx context.Context, userID, recomID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.MarkRecommendationUnread")
+	defer span.End()
+
+	return r.updateInboxState(ctx, userID, recomID, "read_at = NULL")
+}
+
+// SetRecommendationArchived убирает рекомендацию в архив или возвращает из него.
+// Архивирование отменяет откладывание.
+func (r *RecommendationRepo) SetRecommendationArchived(ctx context.Context, userID, recomID int, archived bool) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.SetRecommendationArchived")
+	defer span.End()
+
+	if archived {
+		return r.updateInboxState(ctx, userID, recomID, "archived_at = COALESCE(archived_at, now()), snoozed_until = NULL")
+	}
+	return r.updateInboxState(ctx, userID, recomID, "archived_at = NULL")
+}
+
+// SnoozeRecommendation откладывает рекомендацию до until (nil - вернуть сразу).
+// Отложенная рекомендация вернется во входящие непрочитанной.
+func (r *RecommendationRepo) SnoozeRecommendation(ctx context.Context, userID, recomID int, until *time.Time) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.SnoozeRecommendation")
+	defer span.End()
+
+	if until == nil {
+		return r.updateInboxState(ctx, userID, recomID, "snoozed_until = NULL")
+	}
+	return r.updateInboxState(ctx, userID, recomID, "snoozed_until = $3, read_at = NULL, archived_at = NULL", *until)
+}
+
+// SetRecommendationHidden скрывает полученную рекомендацию у получателя или возвращает ее.
+// У отправителя рекомендация остается в списке отправленных.
+func (r *RecommendationRepo) SetRecommendationHidden(ctx context.Context, userID, recomID int, hidden bool) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.SetRecommendationHidden")
+	defer span.End()
+
+	if hidden {
+		return r.updateInboxState(ctx, userID, recomID, "hidden_at = COALESCE(hidden_at, now())")
+	}
+	return r.updateInboxState(ctx, userID, recomID, "hidden_at = NULL")
+}
+
+// DeclineRecommendation отклоняет полученную рекомендацию и скрывает ее у получателя.
+func (r *RecommendationRepo) DeclineRecommendation(ctx context.Context, userID, recomID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.DeclineRecommendation")
+	defer span.End()
+
+	return r.updateInboxState(ctx, userID, recomID,
+		"status = 'declined', declined_at = COALESCE(declined_at, now()), hidden_at = COALESCE(hidden_at, now())")
+}
+
+func (r *RecommendationRepo) updateInboxState(ctx context.Context, userID, recomID int, set string, args ...any) error {
+	query := "UPDATE recommendations SET " + set + " WHERE recommendation_id = $1 AND to_user_id = $2"
+
+	result, err := r.db.ExecContext(ctx, query, append([]any{recomID, userID}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update recommendation inbox state: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// AddOptOut запрещает рекомендовать пользователю медиа. Повторный вызов ничего не меняет.
+func (r *RecommendationRepo) AddOptOut(ctx context.Context, userID, mediaID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.AddOptOut")
+	defer span.End()
+
+	query := `
+		INSERT INTO recommendation_opt_outs (user_id, media_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, media_id) DO NOTHING`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, mediaID); err != nil {
+		return fmt.Errorf("failed to add recommendation opt-out: %w", err)
+	}
+	return nil
+}
+
+// RemoveOptOut снимает запрет. Возвращает sql.ErrNoRows, если запрета не было.
+func (r *RecommendationRepo) RemoveOptOut(ctx context.Context, userID, mediaID int) error {
+	ctx, span := startSpan(ctx, "RecommendationRepo.RemoveOptOut")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM recommendation_opt_outs WHERE user_id = $1 AND media_id = $2", userID, mediaID)
+	if err != nil {
+		return fmt.Errorf("failed to remove recommendation opt-out: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// HasOptOut сообщает, запретил ли пользователь рекомендовать ему медиа.
+func (r *RecommendationRepo) HasOptOut(ctx context.Context, userID, mediaID int) (bool, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.HasOptOut")
+	defer span.End()
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM recommendation_opt_outs WHERE user_id = $1 AND media_id = $2)"
+	if err := r.db.QueryRowContext(ctx, query, userID, mediaID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check recommendation opt-out: %w", err)
+	}
+	return exists, nil
+}
+
+// GetOptOuts возвращает медиа, которые пользователь запретил ему рекомендовать, новые первыми.
+func (r *RecommendationRepo) GetOptOuts(ctx context.Context, userID int) ([]models.RecommendationOptOut, error) {
+	ctx, span := startSpan(ctx, "RecommendationRepo.GetOptOuts")
+	defer span.End()
+
+	query := `
+		SELECT ` + mediaColumns("m") + `, o.created_at
+		FROM recommendation_opt_outs o
+		JOIN media_items m ON m.media_id = o.media_id
+		WHERE o.user_id = $1
+		ORDER BY o.created_at DESC, o.media_id`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendation opt-outs: %w", err)
+	}
+	defer rows.Close()
+
+	optOuts := []models.RecommendationOptOut{}
+	for rows.Next() {
+		var optOut models.RecommendationOptOut
+		if err := rows.Scan(append(mediaFields(&optOut.Media), &optOut.CreatedAt)...); err != nil {
+			return nil, fmt.Errorf("failed to scan recommendation opt-out: %w", err)
+		}
+		optOuts = append(optOuts, optOut)
+	}
+	return optOuts, rows.Err()
+}
@@ -0,0 +1,167 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+type SessionRepo struct {
+	db DBTX
+}
+
+func NewSessionRepo(db *sql.DB) *SessionRepo {
+	return &SessionRepo{db: traceDB(db)}
+}
+
+func (r *SessionRepo) WithTx(tx *sql.Tx) *SessionRepo {
+	return &SessionRepo{db: traceDB(tx)}
+}
+
+func (r *SessionRepo) CreateSession(ctx context.Context, session models.UserSession) (models.UserSession, error) {
+	ctx, span := startSpan(ctx, "SessionRepo.CreateSession")
+	defer span.End()
+
+	query := `
+		INSERT INTO user_sessions (user_id, device, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING session_id, created_at, last_seen_at`
+
+	err := r.db.QueryRowContext(ctx, query, session.UserID, session.Device, session.UserAgent, session.IPAddress, session.ExpiresAt).Scan(
+		&session.ID,
+		&session.CreatedAt,
+		&session.LastSeenAt,
+	)
+	if err != nil {
+		return models.UserSession{}, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return session, nil
+}
+
+// GetSession возвращает сессию пользователя или sql.ErrNoRows.
+func (r *SessionRepo) GetSession(ctx context.Context, userID int, sessionID string) (models.UserSession, error) {
+	ctx, span := startSpan(ctx, "SessionRepo.GetSession")
+	defer span.End()
+
+	var session models.UserSession
+	query := `
+		SELECT session_id, user_id, device, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at
+		FROM user_sessions
+		WHERE session_id = $1 AND user_id = $2`
+
+	err := r.db.QueryRowContext(ctx, query, sessionID, userID).Scan(
+		&session.ID, &session.UserID, &session.Device, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt)
+	if err != nil {
+		return models.UserSession{}, err // sql.ErrNoRows будет обработан в сервисе
+	}
+
+	return session, nil
+}
+
+// GetActiveSessions возвращает неотозванные и неистекшие сессии, последние активные первыми.
+func (r *SessionRepo) GetActiveSessions(ctx context.Context, userID int) ([]models.UserSession, error) {
+	ctx, span := startSpan(ctx, "SessionRepo.GetActiveSessions")
+	defer span.End()
+
+	query := `
+		SELECT session_id, user_id, device, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at
+		FROM user_sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_seen_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []models.UserSession{}
+	for rows.Next() {
+		var session models.UserSession
+		if err := rows.Scan(&session.ID, &session.UserID, &session.Device, &session.UserAgent, &session.IPAddress,
+			&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session row: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
+// TouchSession обновляет время последней активности.
+func (r *SessionRepo) TouchSession(ctx context.Context, sessionID string) error {
+	ctx, span := startSpan(ctx, "SessionRepo.TouchSession")
+	defer span.End()
+
+	query := "UPDATE user_sessions SET last_seen_at = now() WHERE session_id = $1"
+
+	if _, err := r.db.ExecContext(ctx, query, sessionID); err != nil {
+		return fmt.Errorf("failed to update session activity: %w", err)
+	}
+
+	return nil
+}
+
+// RevokeSession отзывает одну сессию пользователя. Возвращает sql.ErrNoRows,
+// если сессии нет или она уже отозвана.
+func (r *SessionRepo) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	ctx, span := startSpan(ctx, "SessionRepo.RevokeSession")
+	defer span.End()
+
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = now()
+		WHERE session_id = $1 AND user_id = $2 AND revoked_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, sessionID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// RevokeOtherSessions отзывает все сессии пользователя, кроме keepSessionID,
+// и возвращает количество отозванных.
+func (r *SessionRepo) RevokeOtherSessions(ctx context.Context, userID int, keepSessionID string) (int64, error) {
+	ctx, span := startSpan(ctx, "SessionRepo.RevokeOtherSessions")
+	defer span.End()
+
+	query := `
+		UPDATE user_sessions
+		SET revoked_at = now()
+		WHERE user_id = $1 AND session_id <> $2 AND revoked_at IS NULL AND expires_at > now()`
+
+	result, err := r.db.ExecContext(ctx, query, userID, keepSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
+// RevokeAllSessions отзывает все сессии пользователя (например, при удалении аккаунта).
+func (r *SessionRepo) RevokeAllSessions(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "SessionRepo.RevokeAllSessions")
+	defer span.End()
+
+	query := "UPDATE user_sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL"
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	return nil
+}
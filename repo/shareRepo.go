@@ -0,0 +1,173 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+type ShareRepo struct {
+	db DBTX
+}
+
+func NewShareRepo(db *sql.DB) *ShareRepo {
+	return &ShareRepo{db: traceDB(db)}
+}
+
+func (r *ShareRepo) WithTx(tx *sql.Tx) *ShareRepo {
+	return &ShareRepo{db: traceDB(tx)}
+}
+
+const shareLinkColumns = "l.share_id, l.owner_id, l.note, l.prompt_follow, l.expires_at, l.created_at, l.revoked_at"
+
+func scanShareLink(row interface{ Scan(...any) error }, extra ...any) (models.ShareLink, error) {
+	var link models.ShareLink
+	dest := []any{&link.ID, &link.OwnerID, &link.Note, &link.PromptFollow, &link.ExpiresAt, &link.CreatedAt, &link.RevokedAt}
+	err := row.Scan(append(dest, extra...)...)
+	return link, err
+}
+
+// CreateShareLink создает ссылку на медиа в заданном порядке. Вызывать в транзакции:
+// ссылка и ее медиа пишутся двумя запросами.
+func (r *ShareRepo) CreateShareLink(ctx context.Context, ownerID int, note string, promptFollow bool, expiresAt time.Time, mediaIDs []int) (models.ShareLink, error) {
+	ctx, span := startSpan(ctx, "ShareRepo.CreateShareLink")
+	defer span.End()
+
+	query := `
+		INSERT INTO share_links AS l (owner_id, note, prompt_follow, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + shareLinkColumns
+
+	link, err := scanShareLink(r.db.QueryRowContext(ctx, query, ownerID, note, promptFollow, expiresAt))
+	if err != nil {
+		return models.ShareLink{}, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	ids := make([]int64, len(mediaIDs))
+	for i, id := range mediaIDs {
+		ids[i] = int64(id)
+	}
+	mediaQuery := `
+		INSERT INTO share_link_media (share_id, media_id, position)
+		SELECT $1, t.media_id, t.position
+		FROM unnest($2::int[]) WITH ORDINALITY AS t(media_id, position)`
+	if _, err := r.db.ExecContext(ctx, mediaQuery, link.ID, ids); err != nil {
+		return models.ShareLink{}, fmt.Errorf("failed to add share link media: %w", err)
+	}
+
+	link.Media, err = r.getLinkMedia(ctx, link.ID)
+	return link, err
+}
+
+// GetShareLink возвращает ссылку с автором и медиа. Ссылки скрытых аккаунтов
+// не находятся (sql.ErrNoRows). Срок и отзыв проверяет вызывающий.
+func (r *ShareRepo) GetShareLink(ctx context.Context, shareID string) (models.ShareLink, error) {
+	ctx, span := startSpan(ctx, "ShareRepo.GetShareLink")
+	defer span.End()
+
+	query := `
+		SELECT ` + shareLinkColumns + `, u.user_id, u.user_name, u.created_at
+		FROM share_links l
+		JOIN users u ON u.user_id = l.owner_id
+		WHERE l.share_id = $1 AND ` + visibleUser("u")
+
+	var owner models.User
+	link, err := scanShareLink(r.db.QueryRowContext(ctx, query, shareID), &owner.ID, &owner.UserName, &owner.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return models.ShareLink{}, sql.ErrNoRows
+		}
+		return models.ShareLink{}, fmt.Errorf("failed to get share link: %w", err)
+	}
+	link.Owner = owner
+
+	link.Media, err = r.getLinkMedia(ctx, link.ID)
+	return link, err
+}
+
+// GetUserShareLinks возвращает ссылки пользователя, новые первыми, включая истекшие и отозванные.
+func (r *ShareRepo) GetUserShareLinks(ctx context.Context, ownerID int) ([]models.ShareLink, error) {
+	ctx, span := startSpan(ctx, "ShareRepo.GetUserShareLinks")
+	defer span.End()
+
+	query := `
+		SELECT ` + shareLinkColumns + `
+		FROM share_links l
+		WHERE l.owner_id = $1
+		ORDER BY l.created_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := []models.ShareLink{}
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range links {
+		if links[i].Media, err = r.getLinkMedia(ctx, links[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return links, nil
+}
+
+// RevokeShareLink отзывает ссылку автора. Возвращает sql.ErrNoRows, если ссылки нет.
+func (r *ShareRepo) RevokeShareLink(ctx context.Context, ownerID int, shareID string) error {
+	ctx, span := startSpan(ctx, "ShareRepo.RevokeShareLink")
+	defer span.End()
+
+	query := "UPDATE share_links SET revoked_at = COALESCE(revoked_at, now()) WHERE share_id = $1 AND owner_id = $2"
+
+	result, err := r.db.ExecContext(ctx, query, shareID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *ShareRepo) getLinkMedia(ctx context.Context, shareID string) ([]models.MediaItem, error) {
+	query := `
+		SELECT ` + mediaColumns("m") + `
+		FROM share_link_media s
+		JOIN media_items m ON m.media_id = s.media_id
+		WHERE s.share_id = $1
+		ORDER BY s.position`
+
+	rows, err := r.db.QueryContext(ctx, query, shareID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link media: %w", err)
+	}
+	defer rows.Close()
+
+	items := []models.MediaItem{}
+	for rows.Next() {
+		var item models.MediaItem
+		if err := rows.Scan(mediaFields(&item)...); err != nil {
+			return nil, fmt.Errorf("failed to scan share link media: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
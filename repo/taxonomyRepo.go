@@ -0,0 +1,321 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+// Порядок выдачи медиа в подборках по жанру или тегу.
+const (
+	MediaSortPopular = "popular" // по числу рекомендаций
+	MediaSortRecent  = "recent"  // недавно добавленные в каталог
+)
+
+// Порядок выдачи списка жанров или тегов.
+const (
+	TermSortPopular = "popular" // по числу медиа
+	TermSortName    = "name"
+)
+
+// TaxonomyRepo работает с жанрами и тегами: у них одинаковое устройство,
+// отличаются только таблицы.
+type TaxonomyRepo struct {
+	db DBTX
+}
+
+func NewTaxonomyRepo(db *sql.DB) *TaxonomyRepo {
+	return &TaxonomyRepo{db: traceDB(db)}
+}
+
+func (r *TaxonomyRepo) WithTx(tx *sql.Tx) *TaxonomyRepo {
+	return &TaxonomyRepo{db: traceDB(tx)}
+}
+
+type taxonomyTables struct {
+	terms string // таблица терминов
+	id    string // первичный ключ термина
+	links string // связь с media_items
+}
+
+func tablesFor(kind models.TaxonomyKind) taxonomyTables {
+	if kind == models.TaxonomyGenre {
+		return taxonomyTables{terms: "genres", id: "genre_id", links: "media_genres"}
+	}
+	return taxonomyTables{terms: "tags", id: "tag_id", links: "media_tags"}
+}
+
+func scanTerm(row interface{ Scan(...any) error }) (models.Term, error) {
+	var term models.Term
+	err := row.Scan(&term.ID, &term.Slug, &term.Name, &term.CreatedAt)
+	return term, err
+}
+
+// GetTerms возвращает жанры или теги с числом медиа. query - поиск по части названия.
+func (r *TaxonomyRepo) GetTerms(ctx context.Context, kind models.TaxonomyKind, query, sort string) ([]models.Term, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.GetTerms")
+	defer span.End()
+
+	t := tablesFor(kind)
+	order := "media_count DESC, t.name"
+	if sort == TermSortName {
+		order = "t.name"
+	}
+
+	sqlQuery := fmt.Sprintf(`
+		SELECT t.%[2]s, t.slug, t.name, t.created_at, COUNT(l.media_id) AS media_count
+		FROM %[1]s t
+		LEFT JOIN %[3]s l ON l.%[2]s = t.%[2]s
+		WHERE $1 = '' OR t.name ILIKE $2
+		GROUP BY t.%[2]s
+		ORDER BY %[4]s`, t.terms, t.id, t.links, order)
+
+	rows, err := r.db.QueryContext(ctx, sqlQuery, query, "%"+escapeLike(query)+"%")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %ss: %w", kind, err)
+	}
+	defer rows.Close()
+
+	terms := []models.Term{}
+	for rows.Next() {
+		var term models.Term
+		if err := rows.Scan(&term.ID, &term.Slug, &term.Name, &term.CreatedAt, &term.MediaCount); err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", kind, err)
+		}
+		terms = append(terms, term)
+	}
+	return terms, rows.Err()
+}
+
+// GetTerm возвращает жанр или тег по слагу или sql.ErrNoRows.
+func (r *TaxonomyRepo) GetTerm(ctx context.Context, kind models.TaxonomyKind, slug string) (models.Term, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.GetTerm")
+	defer span.End()
+
+	t := tablesFor(kind)
+	query := fmt.Sprintf("SELECT %s, slug, name, created_at FROM %s WHERE slug = $1", t.id, t.terms)
+
+	return scanTerm(r.db.QueryRowContext(ctx, query, slug))
+}
+
+// CreateTerm добавляет жанр или тег. Возвращает sql.ErrNoRows, если слаг уже занят.
+func (r *TaxonomyRepo) CreateTerm(ctx context.Context, kind models.TaxonomyKind, slug, name string) (models.Term, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.CreateTerm")
+	defer span.End()
+
+	t := tablesFor(kind)
+	query := fmt.Sprintf(`
+		INSERT INTO %s (slug, name) VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING %s, slug, name, created_at`, t.terms, t.id)
+
+	return scanTerm(r.db.QueryRowContext(ctx, query, slug, name))
+}
+
+// EnsureTerm возвращает жанр или тег по слагу, создавая его при необходимости.
+func (r *TaxonomyRepo) EnsureTerm(ctx context.Context, kind models.TaxonomyKind, slug, name string) (models.Term, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.EnsureTerm")
+	defer span.End()
+
+	t := tablesFor(kind)
+	// DO UPDATE без изменений нужен, чтобы RETURNING вернул и уже существующую строку
+	query := fmt.Sprintf(`
+		INSERT INTO %s (slug, name) VALUES ($1, $2)
+		ON CONFLICT (slug) DO UPDATE SET slug = EXCLUDED.slug
+		RETURNING %s, slug, name, created_at`, t.terms, t.id)
+
+	term, err := scanTerm(r.db.QueryRowContext(ctx, query, slug, name))
+	if err != nil {
+		return models.Term{}, fmt.Errorf("failed to ensure %s: %w", kind, err)
+	}
+	return term, nil
+}
+
+// SetMediaTerms заменяет жанры или теги медиа.
+func (r *TaxonomyRepo) SetMediaTerms(ctx context.Context, kind models.TaxonomyKind, mediaID int, termIDs []int) error {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.SetMediaTerms")
+	defer span.End()
+
+	t := tablesFor(kind)
+	if _, err := r.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE media_id = $1", t.links), mediaID); err != nil {
+		return fmt.Errorf("failed to clear media %ss: %w", kind, err)
+	}
+
+	for _, termID := range termIDs {
+		if err := r.LinkTerm(ctx, kind, mediaID, termID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LinkTerm добавляет медиа жанр или тег; повторная связь ничего не меняет.
+func (r *TaxonomyRepo) LinkTerm(ctx context.Context, kind models.TaxonomyKind, mediaID, termID int) error {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.LinkTerm")
+	defer span.End()
+
+	t := tablesFor(kind)
+	query := fmt.Sprintf("INSERT INTO %s (media_id, %s) VALUES ($1, $2) ON CONFLICT DO NOTHING", t.links, t.id)
+	if _, err := r.db.ExecContext(ctx, query, mediaID, termID); err != nil {
+		return fmt.Errorf("failed to link media %s: %w", kind, err)
+	}
+	return nil
+}
+
+// HasTerm сообщает, есть ли у медиа жанр или тег с этим слагом.
+func (r *TaxonomyRepo) HasTerm(ctx context.Context, kind models.TaxonomyKind, mediaID int, slug string) (bool, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.HasTerm")
+	defer span.End()
+
+	t := tablesFor(kind)
+	query := fmt.Sprintf(`
+		SELECT EXISTS (
+			SELECT 1 FROM %[1]s l JOIN %[2]s t ON t.%[3]s = l.%[3]s
+			WHERE l.media_id = $1 AND t.slug = $2
+		)`, t.links, t.terms, t.id)
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, mediaID, slug).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check media %s: %w", kind, err)
+	}
+	return exists, nil
+}
+
+// GetMediaByTerm возвращает медиа с жанром или тегом вместе с числом рекомендаций.
+// Пустой mediaType - медиа всех типов.
+func (r *TaxonomyRepo) GetMediaByTerm(ctx context.Context, kind models.TaxonomyKind, termID int, mediaType models.MediaType,
+	sort string, page, limit int) ([]models.MediaPopularity, int64, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.GetMediaByTerm")
+	defer span.End()
+
+	t := tablesFor(kind)
+
+	var total int64
+	countQuery := fmt.Sprintf(`
+		SELECT COUNT(*)
+		FROM %s l JOIN media_items m ON m.media_id = l.media_id
+		WHERE l.%s = $1 AND ($2 = '' OR m.item_type::text = $2)`, t.links, t.id)
+	if err := r.db.QueryRowContext(ctx, countQuery, termID, mediaType).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count media by %s: %w", kind, err)
+	}
+	if total == 0 {
+		return []models.MediaPopularity{}, 0, nil
+	}
+
+	order := "recommendation_count DESC, m.media_id"
+	if sort == MediaSortRecent {
+		order = "m.created_at DESC, m.media_id DESC"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %s,
+			(SELECT COUNT(*) FROM recommendations r WHERE r.media_id = m.media_id) AS recommendation_count
+		FROM %s l JOIN media_items m ON m.media_id = l.media_id
+		WHERE l.%s = $1 AND ($2 = '' OR m.item_type::text = $2)
+		ORDER BY %s
+		LIMIT $3 OFFSET $4`, mediaColumns("m"), t.links, t.id, order)
+
+	rows, err := r.db.QueryContext(ctx, query, termID, mediaType, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get media by %s: %w", kind, err)
+	}
+	defer rows.Close()
+
+	items := []models.MediaPopularity{}
+	for rows.Next() {
+		var item models.MediaPopularity
+		if err := rows.Scan(append(mediaFields(&item.Media), &item.RecommendationCount)...); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan media: %w", err)
+		}
+		items = append(items, item)
+	}
+	return items, total, rows.Err()
+}
+
+const tagSuggestionColumns = "suggestion_id, media_id, user_id, name, slug, status, reviewer_id, created_at, reviewed_at"
+
+func scanTagSuggestion(row interface{ Scan(...any) error }) (models.TagSuggestion, error) {
+	var suggestion models.TagSuggestion
+	err := row.Scan(&suggestion.ID, &suggestion.MediaID, &suggestion.UserID, &suggestion.Name, &suggestion.Slug,
+		&suggestion.Status, &suggestion.ReviewerID, &suggestion.CreatedAt, &suggestion.ReviewedAt)
+	return suggestion, err
+}
+
+// CreateTagSuggestion сохраняет предложенный тег. Возвращает sql.ErrNoRows, если
+// такой же тег для этого медиа уже ждет решения.
+func (r *TaxonomyRepo) CreateTagSuggestion(ctx context.Context, mediaID, userID int, name, slug string) (models.TagSuggestion, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.CreateTagSuggestion")
+	defer span.End()
+
+	query := `
+		INSERT INTO tag_suggestions (media_id, user_id, name, slug)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (media_id, slug) WHERE status = 'pending' DO NOTHING
+		RETURNING ` + tagSuggestionColumns
+
+	return scanTagSuggestion(r.db.QueryRowContext(ctx, query, mediaID, userID, name, slug))
+}
+
+// GetTagSuggestion возвращает предложение или sql.ErrNoRows.
+func (r *TaxonomyRepo) GetTagSuggestion(ctx context.Context, suggestionID int) (models.TagSuggestion, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.GetTagSuggestion")
+	defer span.End()
+
+	query := "SELECT " + tagSuggestionColumns + " FROM tag_suggestions WHERE suggestion_id = $1"
+
+	return scanTagSuggestion(r.db.QueryRowContext(ctx, query, suggestionID))
+}
+
+// GetTagSuggestions возвращает предложения, старые первыми. Пустой status - все.
+func (r *TaxonomyRepo) GetTagSuggestions(ctx context.Context, status models.TagSuggestionStatus, page, limit int) ([]models.TagSuggestion, int64, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.GetTagSuggestions")
+	defer span.End()
+
+	var total int64
+	countQuery := "SELECT COUNT(*) FROM tag_suggestions WHERE $1 = '' OR status = $1"
+	if err := r.db.QueryRowContext(ctx, countQuery, status).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count tag suggestions: %w", err)
+	}
+	if total == 0 {
+		return []models.TagSuggestion{}, 0, nil
+	}
+
+	query := "SELECT " + tagSuggestionColumns + `
+		FROM tag_suggestions
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at, suggestion_id
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, status, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get tag suggestions: %w", err)
+	}
+	defer rows.Close()
+
+	suggestions := []models.TagSuggestion{}
+	for rows.Next() {
+		suggestion, err := scanTagSuggestion(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan tag suggestion: %w", err)
+		}
+		suggestions = append(suggestions, suggestion)
+	}
+	return suggestions, total, rows.Err()
+}
+
+// ReviewTagSuggestion закрывает ожидающее предложение решением модератора.
+// Возвращает sql.ErrNoRows, если предложение уже рассмотрено.
+func (r *TaxonomyRepo) ReviewTagSuggestion(ctx context.Context, suggestionID, reviewerID int, status models.TagSuggestionStatus) (models.TagSuggestion, error) {
+	ctx, span := startSpan(ctx, "TaxonomyRepo.ReviewTagSuggestion")
+	defer span.End()
+
+	query := `
+		UPDATE tag_suggestions
+		SET status = $3, reviewer_id = $2, reviewed_at = now()
+		WHERE suggestion_id = $1 AND status = 'pending'
+		RETURNING ` + tagSuggestionColumns
+
+	return scanTagSuggestion(r.db.QueryRowContext(ctx, query, suggestionID, reviewerID, status))
+}
@@ -0,0 +1,68 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/cobrich/recommendo/repo")
+
+// startSpan открывает клиентский спан на запрос репозитория.
+// name - имя запроса в формате "<Repo>.<Method>", оно же уходит в db.query.name.
+func startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system.name", "postgresql"),
+			attribute.String("db.query.name", name),
+		),
+	)
+}
+
+// tracedDB оборачивает DBTX и добавляет в текущий спан текст каждого
+// выполненного SQL-запроса и ошибки драйвера.
+type tracedDB struct {
+	db DBTX
+}
+
+func traceDB(db DBTX) DBTX {
+	return tracedDB{db: db}
+}
+
+func (t tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	res, err := t.db.ExecContext(ctx, query, args...)
+	recordQuery(ctx, query, err)
+	return res, err
+}
+
+func (t tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	recordQuery(ctx, query, err)
+	return rows, err
+}
+
+func (t tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := t.db.QueryRowContext(ctx, query, args...)
+	// row.Err() возвращает ошибку выполнения запроса, но не sql.ErrNoRows из Scan
+	recordQuery(ctx, query, row.Err())
+	return row
+}
+
+func recordQuery(ctx context.Context, query string, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	span.AddEvent("db.query", trace.WithAttributes(attribute.String("db.query.text", query)))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,228 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+type TwoFactorRepo struct {
+	db DBTX
+}
+
+func NewTwoFactorRepo(db *sql.DB) *TwoFactorRepo {
+	return &TwoFactorRepo{db: traceDB(db)}
+}
+
+func (r *TwoFactorRepo) WithTx(tx *sql.Tx) *TwoFactorRepo {
+	return &TwoFactorRepo{db: traceDB(tx)}
+}
+
+// GetTOTP возвращает настройку 2FA пользователя или sql.ErrNoRows, если ее нет.
+func (r *TwoFactorRepo) GetTOTP(ctx context.Context, userID int) (models.UserTOTP, error) {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.GetTOTP")
+	defer span.End()
+
+	var totp models.UserTOTP
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, failed_attempts, locked_until, created_at
+		FROM user_totp
+		WHERE user_id = $1`
+
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&totp.UserID, &totp.Secret, &totp.ConfirmedAt, &totp.LastUsedStep,
+		&totp.FailedAttempts, &totp.LockedUntil, &totp.CreatedAt)
+	if err != nil {
+		return models.UserTOTP{}, err // sql.ErrNoRows будет обработан в сервисе
+	}
+
+	return totp, nil
+}
+
+// SavePendingTOTP сохраняет новый неподтвержденный секрет. Повторная настройка
+// заменяет прежний неподтвержденный секрет, но не включенную 2FA.
+func (r *TwoFactorRepo) SavePendingTOTP(ctx context.Context, userID int, secret string) error {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.SavePendingTOTP")
+	defer span.End()
+
+	query := `
+		INSERT INTO user_totp (user_id, secret)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, failed_attempts = 0, locked_until = NULL, created_at = now()
+		WHERE user_totp.confirmed_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return fmt.Errorf("failed to save totp secret: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ConfirmTOTP включает 2FA и запоминает интервал кода, которым она подтверждена.
+func (r *TwoFactorRepo) ConfirmTOTP(ctx context.Context, userID int, step int64) error {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.ConfirmTOTP")
+	defer span.End()
+
+	query := `
+		UPDATE user_totp
+		SET confirmed_at = now(), last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND confirmed_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return fmt.Errorf("failed to confirm totp: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// UseTOTPStep отмечает интервал кода как использованный. Возвращает false, если этот
+// или более поздний интервал уже был принят (повтор перехваченного кода).
+// Условие в WHERE делает проверку атомарной при параллельных запросах.
+func (r *TwoFactorRepo) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.UseTOTPStep")
+	defer span.End()
+
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2, failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1 AND last_used_step < $2`
+
+	result, err := r.db.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, fmt.Errorf("failed to update totp step: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+// RecordFailedAttempt увеличивает счетчик неверных кодов. Когда он доходит до
+// maxAttempts, проверка кодов блокируется на lockout, а счетчик обнуляется.
+func (r *TwoFactorRepo) RecordFailedAttempt(ctx context.Context, userID, maxAttempts int, lockout time.Duration) error {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.RecordFailedAttempt")
+	defer span.End()
+
+	query := `
+		UPDATE user_totp
+		SET failed_attempts = CASE WHEN failed_attempts + 1 >= $2 THEN 0 ELSE failed_attempts + 1 END,
+		    locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN now() + $3 * interval '1 second' ELSE locked_until END
+		WHERE user_id = $1`
+
+	if _, err := r.db.ExecContext(ctx, query, userID, maxAttempts, lockout.Seconds()); err != nil {
+		return fmt.Errorf("failed to record failed 2fa attempt: %w", err)
+	}
+
+	return nil
+}
+
+func (r *TwoFactorRepo) ResetFailedAttempts(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.ResetFailedAttempts")
+	defer span.End()
+
+	query := "UPDATE user_totp SET failed_attempts = 0, locked_until = NULL WHERE user_id = $1"
+
+	if _, err := r.db.ExecContext(ctx, query, userID); err != nil {
+		return fmt.Errorf("failed to reset 2fa attempts: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteTOTP выключает 2FA вместе с кодами восстановления.
+func (r *TwoFactorRepo) DeleteTOTP(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.DeleteTOTP")
+	defer span.End()
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM user_totp WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete totp: %w", err)
+	}
+
+	return nil
+}
+
+// ReplaceRecoveryCodes удаляет старые коды восстановления и сохраняет новые.
+// Вызывать внутри транзакции.
+func (r *TwoFactorRepo) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.ReplaceRecoveryCodes")
+	defer span.End()
+
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	query := "INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)"
+	for _, hash := range codeHashes {
+		if _, err := r.db.ExecContext(ctx, query, userID, hash); err != nil {
+			return fmt.Errorf("failed to insert recovery code: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// UseRecoveryCode гасит код восстановления. Возвращает false, если кода нет
+// или он уже был использован.
+func (r *TwoFactorRepo) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.UseRecoveryCode")
+	defer span.End()
+
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = now()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (r *TwoFactorRepo) CountUnusedRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	ctx, span := startSpan(ctx, "TwoFactorRepo.CountUnusedRecoveryCodes")
+	defer span.End()
+
+	var count int
+	query := "SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL"
+
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/cobrich/recommendo/models"
 )
@@ -13,25 +15,29 @@ type UserRepo struct {
 }
 
 func NewUserRepo(db *sql.DB) *UserRepo {
-	return &UserRepo{db: db}
+	return &UserRepo{db: traceDB(db)}
 }
 
 func (r *UserRepo) WithTx(tx *sql.Tx) *UserRepo {
-	return &UserRepo{db: tx}
+	return &UserRepo{db: traceDB(tx)}
 }
 
 func (r *UserRepo) CreateUser(ctx context.Context, user models.User) (models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepo.CreateUser")
+	defer span.End()
+
 	var createdUser models.User
 
 	query := `
-		INSERT INTO users (user_name, email, password_hash) 
-		VALUES ($1, $2, $3) 
-		RETURNING user_id, user_name, email, created_at`
+		INSERT INTO users (user_name, email, password_hash, password_login_enabled) 
+		VALUES ($1, $2, $3, $4) 
+		RETURNING user_id, user_name, email, password_login_enabled, created_at`
 
-	err := r.db.QueryRowContext(ctx, query, user.UserName, user.Email, user.PasswordHash).Scan(
+	err := r.db.QueryRowContext(ctx, query, user.UserName, user.Email, user.PasswordHash, user.PasswordLoginEnabled).Scan(
 		&createdUser.ID,
 		&createdUser.UserName,
 		&createdUser.Email,
+		&createdUser.PasswordLoginEnabled,
 		&createdUser.CreatedAt,
 	)
 
@@ -43,9 +49,12 @@ func (r *UserRepo) CreateUser(ctx context.Context, user models.User) (models.Use
 }
 
 func (r *UserRepo) GetUsers(ctx context.Context, page, limit int) ([]models.User, int64, error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetUsers")
+	defer span.End()
+
 	// 1. Gettig total count
 	var total int64
-	countQuery := "SELECT COUNT(*) FROM users"
+	countQuery := "SELECT COUNT(*) FROM users WHERE " + visibleUser("users")
 	if err := r.db.QueryRowContext(ctx, countQuery).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -58,7 +67,7 @@ func (r *UserRepo) GetUsers(ctx context.Context, page, limit int) ([]models.User
 	// 2. Getting page datas
 	offset := (page - 1) * limit
 
-	query := "SELECT user_id, user_name, created_at FROM users ORDER BY user_name LIMIT $1 OFFSET $2"
+	query := "SELECT user_id, user_name, created_at FROM users WHERE " + visibleUser("users") + " ORDER BY user_name LIMIT $1 OFFSET $2"
 
 	var users []models.User
 
@@ -82,7 +91,10 @@ func (r *UserRepo) GetUsers(ctx context.Context, page, limit int) ([]models.User
 }
 
 func (r *UserRepo) GetUserByID(ctx context.Context, id int) (models.User, error) {
-	query := "SELECT user_id, user_name, created_at FROM users WHERE user_id = $1"
+	ctx, span := startSpan(ctx, "UserRepo.GetUserByID")
+	defer span.End()
+
+	query := "SELECT user_id, user_name, created_at FROM users WHERE user_id = $1 AND " + visibleUser("users")
 
 	var user models.User
 
@@ -98,6 +110,8 @@ func (r *UserRepo) GetUserByID(ctx context.Context, id int) (models.User, error)
 }
 
 func (r *UserRepo) GetUserFriends(ctx context.Context, userID, page, limit int) ([]models.User, int64, error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetUserFriends")
+	defer span.End()
 
 	// 1. Gettig total count
 	var total int64
@@ -107,9 +121,10 @@ func (r *UserRepo) GetUserFriends(ctx context.Context, userID, page, limit int)
 		    follows f1
 		JOIN
 		    follows f2 ON f1.follower_id = f2.following_id AND f1.following_id = f2.follower_id
+		JOIN
+		    users u ON u.user_id = f1.following_id
 		WHERE
-		    f1.follower_id = $1;
-	`
+		    f1.follower_id = $1 AND ` + visibleUser("u")
 	if err := r.db.QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -133,7 +148,7 @@ func (r *UserRepo) GetUserFriends(ctx context.Context, userID, page, limit int)
 		JOIN
 		    users u ON u.user_id = f1.following_id
 		WHERE
-		    f1.follower_id = $1;
+		    f1.follower_id = $1 AND ` + visibleUser("u") + `
 		ORDER BY
 		    u.user_name -- <-- ВАЖНО: Пагинация без сортировки не имеет смысла!
 		LIMIT $2 OFFSET $3; -- <-- Новые параметры
@@ -160,15 +175,19 @@ func (r *UserRepo) GetUserFriends(ctx context.Context, userID, page, limit int)
 }
 
 func (r *UserRepo) GetUserFollowers(ctx context.Context, userID, page, limit int) ([]models.User, int64, error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetUserFollowers")
+	defer span.End()
+
 	var total int64
 
 	countQuery := `
 		SELECT COUNT(*)
 		FROM
 		    follows f
+		JOIN
+		    users u ON u.user_id = f.follower_id
 		WHERE
-		    f.following_id = $1;
-	`
+		    f.following_id = $1 AND ` + visibleUser("u")
 	if err := r.db.QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
 	}
@@ -189,7 +208,7 @@ func (r *UserRepo) GetUserFollowers(ctx context.Context, userID, page, limit int
 		JOIN
 		    users u ON u.user_id = f.follower_id
 		WHERE
-		    f.following_id = $1;
+		    f.following_id = $1 AND ` + visibleUser("u") + `
 		ORDER BY
 			u.user_name 
 		LIMIT $2 OFFSET $3;
@@ -216,6 +235,8 @@ func (r *UserRepo) GetUserFollowers(ctx context.Context, userID, page, limit int
 }
 
 func (r *UserRepo) GetUserFollowings(ctx context.Context, userID, page, limit int) ([]models.User, int64, error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetUserFollowings")
+	defer span.End()
 
 	// 1
 	var total int64
@@ -224,9 +245,10 @@ func (r *UserRepo) GetUserFollowings(ctx context.Context, userID, page, limit in
 		SELECT COUNT(*)
 		FROM
 		    follows f
+		JOIN
+		    users u ON u.user_id = f.following_id
 		WHERE
-		    f.follower_id = $1;
-	`
+		    f.follower_id = $1 AND ` + visibleUser("u")
 
 	if err := r.db.QueryRowContext(ctx, countQuery, userID).Scan(&total); err != nil {
 		return nil, 0, fmt.Errorf("failed to count users: %w", err)
@@ -252,7 +274,7 @@ func (r *UserRepo) GetUserFollowings(ctx context.Context, userID, page, limit in
 		    users u ON u.user_id = f.following_id
 		WHERE
 		    -- Условие: мы ищем тех, на кого подписан user ($1).
-		    f.follower_id = $1;
+		    f.follower_id = $1 AND ` + visibleUser("u") + `
 		ORDER BY
 			u.user_name
 		LIMIT $2 OFFSET $3;
@@ -262,6 +284,7 @@ func (r *UserRepo) GetUserFollowings(ctx context.Context, userID, page, limit in
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to get user friends: %w", err)
 	}
+	defer sqlRows.Close()
 
 	for sqlRows.Next() {
 		var user models.User
@@ -276,9 +299,11 @@ func (r *UserRepo) GetUserFollowings(ctx context.Context, userID, page, limit in
 
 // FindUserByEmail ищет пользователя по email. Возвращает хеш пароля для проверки в сервисе.
 func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (models.User, error) {
-	var user models.User
-	query := "SELECT user_id, user_name, email, password_hash, created_at FROM users WHERE email = $1"
-	err := r.db.QueryRowContext(ctx, query, email).Scan(&user.ID, &user.UserName, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	ctx, span := startSpan(ctx, "UserRepo.FindUserByEmail")
+	defer span.End()
+
+	query := "SELECT " + fullUserColumns + " FROM users WHERE email = $1"
+	user, err := scanFullUser(r.db.QueryRowContext(ctx, query, email))
 	if err != nil {
 		return models.User{}, err // err может быть sql.ErrNoRows, это нормально
 	}
@@ -287,17 +312,247 @@ func (r *UserRepo) FindUserByEmail(ctx context.Context, email string) (models.Us
 
 // FindUserByIDWithPassword получает ВСЕ данные пользователя, включая хеш.
 func (r *UserRepo) FindUserByIDWithPassword(ctx context.Context, id int) (models.User, error) {
-	var user models.User
+	ctx, span := startSpan(ctx, "UserRepo.FindUserByIDWithPassword")
+	defer span.End()
+
 	// Этот запрос выбирает все поля, включая password_hash
-	query := "SELECT user_id, user_name, email, password_hash, created_at FROM users WHERE user_id = $1"
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&user.ID, &user.UserName, &user.Email, &user.PasswordHash, &user.CreatedAt)
+	query := "SELECT " + fullUserColumns + " FROM users WHERE user_id = $1"
+	user, err := scanFullUser(r.db.QueryRowContext(ctx, query, id))
 	if err != nil {
 		return models.User{}, err // sql.ErrNoRows будет обработан в сервисе
 	}
 	return user, nil
 }
 
+// fullUserColumns - все поля пользователя в порядке, который ожидает scanFullUser.
+const fullUserColumns = `user_id, user_name, email, password_hash, password_login_enabled, created_at,
+	deleted_at, deactivated_at, role, suspended_at, COALESCE(suspension_reason, '')`
+
+func scanFullUser(row interface{ Scan(...any) error }) (models.User, error) {
+	var user models.User
+	err := row.Scan(&user.ID, &user.UserName, &user.Email, &user.PasswordHash, &user.PasswordLoginEnabled, &user.CreatedAt,
+		&user.DeletedAt, &user.DeactivatedAt, &user.Role, &user.SuspendedAt, &user.SuspensionReason)
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// UserFilter - условия поиска пользователей в админке. Пустые поля не ограничивают выборку.
+type UserFilter struct {
+	// Query ищет по подстроке в имени или email
+	Query string
+	Role  models.Role
+	// Status - active, deactivated, deleted или suspended
+	Status string
+}
+
+// SearchUsers ищет пользователей среди всех аккаунтов, включая скрытые от остальных.
+func (r *UserRepo) SearchUsers(ctx context.Context, filter UserFilter, page, limit int) ([]models.User, int64, error) {
+	ctx, span := startSpan(ctx, "UserRepo.SearchUsers")
+	defer span.End()
+
+	conditions := []string{"TRUE"}
+	var args []any
+	if filter.Query != "" {
+		args = append(args, "%"+escapeLike(filter.Query)+"%")
+		conditions = append(conditions, fmt.Sprintf("(user_name ILIKE $%d OR email ILIKE $%d)", len(args), len(args)))
+	}
+	if filter.Role != "" {
+		args = append(args, filter.Role)
+		conditions = append(conditions, fmt.Sprintf("role = $%d", len(args)))
+	}
+	switch filter.Status {
+	case "active":
+		conditions = append(conditions, visibleUser("users"))
+	case "deactivated":
+		conditions = append(conditions, "deactivated_at IS NOT NULL")
+	case "deleted":
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	case "suspended":
+		conditions = append(conditions, "suspended_at IS NOT NULL")
+	}
+	where := strings.Join(conditions, " AND ")
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE "+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	if total == 0 {
+		return []models.User{}, 0, nil
+	}
+
+	args = append(args, limit, (page-1)*limit)
+	query := fmt.Sprintf("SELECT %s FROM users WHERE %s ORDER BY user_id LIMIT $%d OFFSET $%d",
+		fullUserColumns, where, len(args)-1, len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		user, err := scanFullUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+	return users, total, rows.Err()
+}
+
+// escapeLike экранирует спецсимволы шаблона LIKE в пользовательском вводе.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// SuspendUser блокирует аккаунт. Возвращает sql.ErrNoRows, если аккаунт не найден или уже заблокирован.
+func (r *UserRepo) SuspendUser(ctx context.Context, userID int, reason string) error {
+	ctx, span := startSpan(ctx, "UserRepo.SuspendUser")
+	defer span.End()
+
+	query := "UPDATE users SET suspended_at = now(), suspension_reason = NULLIF($2, '') WHERE user_id = $1 AND suspended_at IS NULL"
+	return r.execAffectingOne(ctx, "suspend user", query, userID, reason)
+}
+
+// UnsuspendUser снимает блокировку. Возвращает sql.ErrNoRows, если аккаунт не был заблокирован.
+func (r *UserRepo) UnsuspendUser(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "UserRepo.UnsuspendUser")
+	defer span.End()
+
+	query := "UPDATE users SET suspended_at = NULL, suspension_reason = NULL WHERE user_id = $1 AND suspended_at IS NOT NULL"
+	return r.execAffectingOne(ctx, "unsuspend user", query, userID)
+}
+
+// SetUserRole меняет роль пользователя. Возвращает sql.ErrNoRows, если пользователь не найден.
+func (r *UserRepo) SetUserRole(ctx context.Context, userID int, role models.Role) error {
+	ctx, span := startSpan(ctx, "UserRepo.SetUserRole")
+	defer span.End()
+
+	query := "UPDATE users SET role = $2 WHERE user_id = $1"
+	return r.execAffectingOne(ctx, "set user role", query, userID, role)
+}
+
+func (r *UserRepo) execAffectingOne(ctx context.Context, action, query string, args ...any) error {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SoftDeleteUser помечает аккаунт удаленным. Данные стираются позже фоновой задачей,
+// а до этого аккаунт можно восстановить входом. Возвращает время удаления.
+func (r *UserRepo) SoftDeleteUser(ctx context.Context, userID int) (time.Time, error) {
+	ctx, span := startSpan(ctx, "UserRepo.SoftDeleteUser")
+	defer span.End()
+
+	var deletedAt time.Time
+	query := "UPDATE users SET deleted_at = now() WHERE user_id = $1 AND deleted_at IS NULL RETURNING deleted_at"
+
+	if err := r.db.QueryRowContext(ctx, query, userID).Scan(&deletedAt); err != nil {
+		return time.Time{}, err // sql.ErrNoRows будет обработан в сервисе
+	}
+	return deletedAt, nil
+}
+
+// DeactivateUser временно скрывает аккаунт. Возвращает sql.ErrNoRows, если аккаунт
+// не найден или уже деактивирован.
+func (r *UserRepo) DeactivateUser(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "UserRepo.DeactivateUser")
+	defer span.End()
+
+	query := "UPDATE users SET deactivated_at = now() WHERE user_id = $1 AND deactivated_at IS NULL AND deleted_at IS NULL"
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RestoreUser снимает пометки об удалении и деактивации. Возвращает true,
+// если аккаунт был удален или деактивирован.
+func (r *UserRepo) RestoreUser(ctx context.Context, userID int) (bool, error) {
+	ctx, span := startSpan(ctx, "UserRepo.RestoreUser")
+	defer span.End()
+
+	query := `
+		UPDATE users SET deleted_at = NULL, deactivated_at = NULL
+		WHERE user_id = $1 AND (deleted_at IS NOT NULL OR deactivated_at IS NOT NULL)`
+
+	result, err := r.db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetUsersDeletedBefore возвращает ID аккаунтов, удаленных раньше cutoff (не больше limit).
+func (r *UserRepo) GetUsersDeletedBefore(ctx context.Context, cutoff time.Time, limit int) ([]int, error) {
+	ctx, span := startSpan(ctx, "UserRepo.GetUsersDeletedBefore")
+	defer span.End()
+
+	query := "SELECT user_id FROM users WHERE deleted_at < $1 ORDER BY deleted_at LIMIT $2"
+
+	rows, err := r.db.QueryContext(ctx, query, cutoff, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deleted users: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user id: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// IsUserDeletedBefore сообщает, удален ли аккаунт раньше cutoff (и не восстановлен с тех пор).
+func (r *UserRepo) IsUserDeletedBefore(ctx context.Context, userID int, cutoff time.Time) (bool, error) {
+	ctx, span := startSpan(ctx, "UserRepo.IsUserDeletedBefore")
+	defer span.End()
+
+	query := "SELECT EXISTS (SELECT 1 FROM users WHERE user_id = $1 AND deleted_at < $2)"
+
+	var deleted bool
+	if err := r.db.QueryRowContext(ctx, query, userID, cutoff).Scan(&deleted); err != nil {
+		return false, fmt.Errorf("failed to check deleted user: %w", err)
+	}
+	return deleted, nil
+}
+
 func (r *UserRepo) DeleteUser(ctx context.Context, userID int) error {
+	ctx, span := startSpan(ctx, "UserRepo.DeleteUser")
+	defer span.End()
+
 	query := "DELETE FROM users WHERE user_id = $1"
 
 	_, err := r.db.ExecContext(ctx, query, userID)
@@ -308,6 +563,9 @@ func (r *UserRepo) DeleteUser(ctx context.Context, userID int) error {
 }
 
 func (r *UserRepo) UpdateUser(ctx context.Context, userID int, userName string) (models.User, error) {
+	ctx, span := startSpan(ctx, "UserRepo.UpdateUser")
+	defer span.End()
+
 	var user models.User
 
 	query := "UPDATE users SET user_name = $1 WHERE user_id = $2 RETURNING user_id, user_name, email, created_at"
@@ -324,6 +582,9 @@ func (r *UserRepo) UpdateUser(ctx context.Context, userID int, userName string)
 }
 
 func (r *UserRepo) UpdatePassword(ctx context.Context, userID int, newPasswordHash []byte) error {
+	ctx, span := startSpan(ctx, "UserRepo.UpdatePassword")
+	defer span.End()
+
 	query := "UPDATE users SET password_hash = $1 WHERE user_id = $2"
 	result, err := r.db.ExecContext(ctx, query, newPasswordHash, userID)
 	if err != nil {
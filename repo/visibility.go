@@ -0,0 +1,8 @@
+package repo
+
+// visibleUser возвращает SQL-условие "аккаунт виден другим пользователям" для
+// таблицы users с псевдонимом alias. Скрыты удаленные, но еще не стертые
+// (идет период восстановления), деактивированные и заблокированные аккаунты.
+func visibleUser(alias string) string {
+	return alias + ".deleted_at IS NULL AND " + alias + ".deactivated_at IS NULL AND " + alias + ".suspended_at IS NULL"
+}
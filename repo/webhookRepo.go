@@ -0,0 +1,390 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+)
+
+// WebhookRepo хранит вебхуки, outbox событий и журнал доставок.
+// ownerID = nil во всех методах означает вебхуки администраторов.
+type WebhookRepo struct {
+	db DBTX
+}
+
+func NewWebhookRepo(db *sql.DB) *WebhookRepo {
+	return &WebhookRepo{db: traceDB(db)}
+}
+
+func (r *WebhookRepo) WithTx(tx *sql.Tx) *WebhookRepo {
+	return &WebhookRepo{db: traceDB(tx)}
+}
+
+// События хранятся в TEXT[]; database/sql не умеет читать массивы, поэтому читаем их как JSON
+const webhookColumns = "webhook_id, owner_id, url, secret, array_to_json(events), description, active, created_at, updated_at"
+
+func scanWebhook(row interface{ Scan(...any) error }) (models.Webhook, error) {
+	var webhook models.Webhook
+	var events []byte
+	if err := row.Scan(&webhook.ID, &webhook.OwnerID, &webhook.URL, &webhook.Secret, &events,
+		&webhook.Description, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return models.Webhook{}, err
+	}
+	if err := json.Unmarshal(events, &webhook.Events); err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to decode webhook events: %w", err)
+	}
+	return webhook, nil
+}
+
+func eventNames(events []models.WebhookEventType) []string {
+	names := make([]string, 0, len(events))
+	for _, event := range events {
+		names = append(names, string(event))
+	}
+	return names
+}
+
+func (r *WebhookRepo) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.CreateWebhook")
+	defer span.End()
+
+	query := `
+		INSERT INTO webhooks (owner_id, url, secret, events, description, active)
+		VALUES ($1, $2, $3, $4::text[], $5, $6)
+		RETURNING ` + webhookColumns
+
+	created, err := scanWebhook(r.db.QueryRowContext(ctx, query, webhook.OwnerID, webhook.URL, webhook.Secret,
+		eventNames(webhook.Events), webhook.Description, webhook.Active))
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return created, nil
+}
+
+func (r *WebhookRepo) CountWebhooks(ctx context.Context, ownerID *int) (int, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.CountWebhooks")
+	defer span.End()
+
+	var count int
+	query := "SELECT COUNT(*) FROM webhooks WHERE owner_id IS NOT DISTINCT FROM $1"
+	if err := r.db.QueryRowContext(ctx, query, ownerID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count webhooks: %w", err)
+	}
+	return count, nil
+}
+
+func (r *WebhookRepo) GetWebhooks(ctx context.Context, ownerID *int) ([]models.Webhook, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.GetWebhooks")
+	defer span.End()
+
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE owner_id IS NOT DISTINCT FROM $1 ORDER BY webhook_id"
+
+	rows, err := r.db.QueryContext(ctx, query, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+// GetWebhook возвращает вебхук владельца или sql.ErrNoRows.
+func (r *WebhookRepo) GetWebhook(ctx context.Context, ownerID *int, webhookID int64) (models.Webhook, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.GetWebhook")
+	defer span.End()
+
+	query := "SELECT " + webhookColumns + " FROM webhooks WHERE webhook_id = $1 AND owner_id IS NOT DISTINCT FROM $2"
+
+	webhook, err := scanWebhook(r.db.QueryRowContext(ctx, query, webhookID, ownerID))
+	if err == sql.ErrNoRows {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook сохраняет адрес, события, описание и активность.
+// Возвращает sql.ErrNoRows, если у владельца нет такого вебхука.
+func (r *WebhookRepo) UpdateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.UpdateWebhook")
+	defer span.End()
+
+	query := `
+		UPDATE webhooks
+		SET url = $3, events = $4::text[], description = $5, active = $6, updated_at = now()
+		WHERE webhook_id = $1 AND owner_id IS NOT DISTINCT FROM $2
+		RETURNING ` + webhookColumns
+
+	updated, err := scanWebhook(r.db.QueryRowContext(ctx, query, webhook.ID, webhook.OwnerID, webhook.URL,
+		eventNames(webhook.Events), webhook.Description, webhook.Active))
+	if err == sql.ErrNoRows {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return updated, nil
+}
+
+// DeleteWebhook удаляет вебхук вместе с журналом доставок. Возвращает sql.ErrNoRows, если его нет.
+func (r *WebhookRepo) DeleteWebhook(ctx context.Context, ownerID *int, webhookID int64) error {
+	ctx, span := startSpan(ctx, "WebhookRepo.DeleteWebhook")
+	defer span.End()
+
+	result, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE webhook_id = $1 AND owner_id IS NOT DISTINCT FROM $2",
+		webhookID, ownerID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// CancelPendingDeliveries завершает ожидающие доставки вебхука с причиной reason:
+// выключенный вебхук не должен получить накопившиеся события после включения.
+func (r *WebhookRepo) CancelPendingDeliveries(ctx context.Context, webhookID int64, reason string) error {
+	ctx, span := startSpan(ctx, "WebhookRepo.CancelPendingDeliveries")
+	defer span.End()
+
+	query := "UPDATE webhook_deliveries SET status = 'failed', last_error = $2 WHERE webhook_id = $1 AND status = 'pending'"
+	if _, err := r.db.ExecContext(ctx, query, webhookID, reason); err != nil {
+		return fmt.Errorf("failed to cancel pending deliveries: %w", err)
+	}
+	return nil
+}
+
+// RecordEvent добавляет событие в outbox и возвращает его ID. Вызывается в транзакции действия.
+func (r *WebhookRepo) RecordEvent(ctx context.Context, eventType models.WebhookEventType, userID int, payload []byte) (int64, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.RecordEvent")
+	defer span.End()
+
+	query := "INSERT INTO webhook_events (event_type, user_id, payload) VALUES ($1, $2, $3) RETURNING event_id"
+
+	var eventID int64
+	if err := r.db.QueryRowContext(ctx, query, eventType, userID, string(payload)).Scan(&eventID); err != nil {
+		return 0, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+	return eventID, nil
+}
+
+// DispatchEvent создает по доставке события для каждого подходящего активного вебхука:
+// вебхука пользователя, чье это событие, и вебхуков администраторов. Событие отмечается
+// разосланным в том же запросе, поэтому повторный вызов ничего не создаст.
+// Возвращает ID созданных доставок.
+func (r *WebhookRepo) DispatchEvent(ctx context.Context, eventID int64) ([]int64, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.DispatchEvent")
+	defer span.End()
+
+	query := `
+		WITH event AS (
+			UPDATE webhook_events
+			SET dispatched_at = now()
+			WHERE event_id = $1 AND dispatched_at IS NULL
+			RETURNING event_id, event_type, user_id
+		)
+		INSERT INTO webhook_deliveries (webhook_id, event_id)
+		SELECT w.webhook_id, e.event_id
+		FROM event e
+		JOIN webhooks w ON w.active
+			AND e.event_type = ANY(w.events)
+			AND (w.owner_id IS NULL OR w.owner_id = e.user_id)
+		RETURNING delivery_id`
+
+	rows, err := r.db.QueryContext(ctx, query, eventID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dispatch webhook event: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveryIDs []int64
+	for rows.Next() {
+		var deliveryID int64
+		if err := rows.Scan(&deliveryID); err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery id: %w", err)
+		}
+		deliveryIDs = append(deliveryIDs, deliveryID)
+	}
+	return deliveryIDs, rows.Err()
+}
+
+// DueDelivery - доставка, взятая в работу, с адресом и секретом вебхука.
+type DueDelivery struct {
+	Delivery models.WebhookDelivery
+	URL      string
+	Secret   string
+}
+
+// StartAttempt отмечает начало попытки attempt ожидающей доставки и возвращает ее
+// вместе с событием и вебхуком. Возвращает sql.ErrNoRows, если доставка уже
+// закончена, отменена или удалена вместе с вебхуком.
+func (r *WebhookRepo) StartAttempt(ctx context.Context, deliveryID int64, attempt int) (DueDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.StartAttempt")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries d
+		SET attempts = $2, last_attempt_at = now()
+		FROM webhooks w, webhook_events e
+		WHERE d.delivery_id = $1 AND d.status = 'pending' AND w.webhook_id = d.webhook_id AND e.event_id = d.event_id
+		RETURNING d.delivery_id, d.webhook_id, d.attempts, w.url, w.secret,
+			e.event_id, e.event_type, e.user_id, e.payload, e.created_at`
+
+	var d DueDelivery
+	err := r.db.QueryRowContext(ctx, query, deliveryID, attempt).Scan(&d.Delivery.ID, &d.Delivery.WebhookID, &d.Delivery.Attempts,
+		&d.URL, &d.Secret, &d.Delivery.Event.ID, &d.Delivery.Event.Type, &d.Delivery.Event.UserID, &d.Delivery.Event.Payload, &d.Delivery.Event.CreatedAt)
+	if err == sql.ErrNoRows {
+		return DueDelivery{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return DueDelivery{}, fmt.Errorf("failed to start webhook delivery attempt: %w", err)
+	}
+	return d, nil
+}
+
+// FailDelivery завершает ожидающую доставку с причиной reason.
+func (r *WebhookRepo) FailDelivery(ctx context.Context, deliveryID int64, reason string) error {
+	ctx, span := startSpan(ctx, "WebhookRepo.FailDelivery")
+	defer span.End()
+
+	query := "UPDATE webhook_deliveries SET status = 'failed', last_error = $2 WHERE delivery_id = $1 AND status = 'pending'"
+	if _, err := r.db.ExecContext(ctx, query, deliveryID, reason); err != nil {
+		return fmt.Errorf("failed to fail webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// RecordAttempt сохраняет результат попытки. Для status = pending nextAttemptAt -
+// время следующей попытки.
+func (r *WebhookRepo) RecordAttempt(ctx context.Context, deliveryID int64, status models.WebhookDeliveryStatus,
+	responseStatus *int, lastError string, nextAttemptAt time.Time) error {
+	ctx, span := startSpan(ctx, "WebhookRepo.RecordAttempt")
+	defer span.End()
+
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, response_status = $3, last_error = $4, next_attempt_at = $5
+		WHERE delivery_id = $1 AND status = 'pending'`
+
+	if _, err := r.db.ExecContext(ctx, query, deliveryID, status, responseStatus, lastError, nextAttemptAt); err != nil {
+		return fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return nil
+}
+
+const webhookDeliveryColumns = `d.delivery_id, d.webhook_id, d.redelivery_of, d.status, d.attempts, d.next_attempt_at,
+	d.last_attempt_at, d.response_status, d.last_error, d.created_at, e.event_id, e.event_type, e.user_id, e.payload, e.created_at`
+
+func scanWebhookDelivery(row interface{ Scan(...any) error }) (models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.RedeliveryOf, &d.Status, &d.Attempts, &d.NextAttemptAt,
+		&d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt,
+		&d.Event.ID, &d.Event.Type, &d.Event.UserID, &d.Event.Payload, &d.Event.CreatedAt); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+// GetDeliveries возвращает журнал доставок вебхука, новые первыми.
+func (r *WebhookRepo) GetDeliveries(ctx context.Context, webhookID int64, page, limit int) ([]models.WebhookDelivery, int64, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.GetDeliveries")
+	defer span.End()
+
+	var total int64
+	if err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM webhook_deliveries WHERE webhook_id = $1", webhookID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+	if total == 0 {
+		return []models.WebhookDelivery{}, 0, nil
+	}
+
+	query := "SELECT " + webhookDeliveryColumns + `
+		FROM webhook_deliveries d
+		JOIN webhook_events e ON e.event_id = d.event_id
+		WHERE d.webhook_id = $1
+		ORDER BY d.created_at DESC, d.delivery_id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.QueryContext(ctx, query, webhookID, limit, (page-1)*limit)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, total, rows.Err()
+}
+
+// Redeliver создает новую доставку того же события тому же вебхуку.
+// Возвращает sql.ErrNoRows, если у вебхука нет такой доставки.
+func (r *WebhookRepo) Redeliver(ctx context.Context, webhookID, deliveryID int64) (models.WebhookDelivery, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.Redeliver")
+	defer span.End()
+
+	query := `
+		WITH d AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id, redelivery_of)
+			SELECT webhook_id, event_id, delivery_id
+			FROM webhook_deliveries
+			WHERE webhook_id = $1 AND delivery_id = $2
+			RETURNING *
+		)
+		SELECT ` + webhookDeliveryColumns + `
+		FROM d
+		JOIN webhook_events e ON e.event_id = d.event_id`
+
+	delivery, err := scanWebhookDelivery(r.db.QueryRowContext(ctx, query, webhookID, deliveryID))
+	if err == sql.ErrNoRows {
+		return models.WebhookDelivery{}, sql.ErrNoRows
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("failed to redeliver webhook event: %w", err)
+	}
+	return delivery, nil
+}
+
+// DeleteEventsBefore удаляет разосланные события старше before вместе с журналом
+// их доставок. События с еще не законченными доставками остаются.
+func (r *WebhookRepo) DeleteEventsBefore(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := startSpan(ctx, "WebhookRepo.DeleteEventsBefore")
+	defer span.End()
+
+	query := `
+		DELETE FROM webhook_events e
+		WHERE e.created_at < $1 AND e.dispatched_at IS NOT NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM webhook_deliveries d WHERE d.event_id = e.event_id AND d.status = 'pending'
+			)`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old webhook events: %w", err)
+	}
+	return result.RowsAffected()
+}
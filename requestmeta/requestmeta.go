@@ -0,0 +1,26 @@
+// Package requestmeta передает сведения о клиенте из HTTP-слоя в сервисы
+// через context, не привязывая сервисы к net/http.
+package requestmeta
+
+import "context"
+
+// Meta - данные о клиенте, выполнившем запрос.
+type Meta struct {
+	IP        string
+	UserAgent string
+	// RequestID связывает записи журнала аудита и логи одного запроса
+	RequestID string
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, meta Meta) context.Context {
+	return context.WithValue(ctx, contextKey{}, meta)
+}
+
+// FromContext возвращает данные о клиенте или пустую Meta, если их нет
+// (например, при вызове из фоновой задачи).
+func FromContext(ctx context.Context) Meta {
+	meta, _ := ctx.Value(contextKey{}).(Meta)
+	return meta
+}
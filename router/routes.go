@@ -4,24 +4,67 @@ import (
 	"log/slog"
 	"net/http"
 
+	"github.com/cobrich/recommendo/config"
 	"github.com/cobrich/recommendo/handlers"
+	"github.com/cobrich/recommendo/jwt"
 	"github.com/cobrich/recommendo/middleware"
+	"github.com/cobrich/recommendo/models"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
 )
 
-func NewRouter(userHandler *handlers.UserHandler, followHandler *handlers.FollowHandler,
-	mediaHandler *handlers.MediaHandler, recommendationHandler *handlers.RecommendationHandler, logger *slog.Logger) http.Handler {
+// Handlers - все HTTP-обработчики приложения, которые нужно подключить к роутеру.
+type Handlers struct {
+	User           *handlers.UserHandler
+	Follow         *handlers.FollowHandler
+	Media          *handlers.MediaHandler
+	Recommendation *handlers.RecommendationHandler
+	Health         *handlers.HealthHandler
+	JWKS           *handlers.JWKSHandler
+	OAuth          *handlers.OAuthHandler
+	TwoFactor      *handlers.TwoFactorHandler
+	Session        *handlers.SessionHandler
+	DataExport     *handlers.DataExportHandler
+	Admin          *handlers.AdminHandler
+	Moderation     *handlers.ModerationHandler
+	Audit          *handlers.AuditHandler
+	Library        *handlers.LibraryHandler
+	Taxonomy       *handlers.TaxonomyHandler
+	Ranking        *handlers.RankingHandler
+	Share          *handlers.ShareHandler
+	Circle         *handlers.CircleHandler
+	Webhook        *handlers.WebhookHandler
+	// AdminWebhook - вебхуки администраторов, получают события всех пользователей
+	AdminWebhook *handlers.WebhookHandler
+}
+
+func NewRouter(h Handlers, tokens *jwt.TokenManager, sessions middleware.SessionValidator, serverCfg config.ServerConfig, corsCfg config.CORSConfig, logger *slog.Logger) http.Handler {
+	userHandler := h.User
+	followHandler := h.Follow
+	mediaHandler := h.Media
+	recommendationHandler := h.Recommendation
+
+	// Пробы живут на корневом роутере без middleware: их дергают часто,
+	// и они не должны засорять логи и трассы.
+	root := chi.NewRouter()
+	root.Get("/healthz", h.Health.Healthz)
+	root.Get("/readyz", h.Health.Readyz)
+
 	router := chi.NewRouter()
+	root.Mount("/", router)
+
+	// Трассировка идет первой, чтобы спан запроса покрывал все остальные middleware
+	router.Use(middleware.NewTracing())
 
 	router.Use(cors.Handler(cors.Options{
-		// Укажите, с какого источника разрешены запросы.
-		// Для разработки идеально подходит адрес вашего Vite dev-сервера.
-		AllowedOrigins: []string{"http://localhost:5173"},
+		// Разрешенные источники запросов берутся из конфига (cors.allowed_origins).
+		AllowedOrigins: corsCfg.AllowedOrigins,
 		// Разрешенные методы
 		AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "PATCH", "OPTIONS"},
 		// Разрешенные заголовки
 		AllowedHeaders: []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		// Идентификатор запроса нужен клиенту для обращений в поддержку
+		ExposedHeaders: []string{middleware.RequestIDHeader},
 		// Разрешаем отправку cookies (если понадобится в будущем)
 		AllowCredentials: true,
 		// Время жизни preflight-запроса в секундах
@@ -30,11 +73,26 @@ func NewRouter(userHandler *handlers.UserHandler, followHandler *handlers.Follow
 
 	router.Use(middleware.NewRecoverer(logger))
 
+	// IP и User-Agent клиента нужны сервисам (например, для списка сессий)
+	router.Use(middleware.NewRequestMeta(serverCfg.TrustProxyHeaders))
+
 	router.Use(middleware.NewLogger(logger))
 
+	// Публичные ключи для проверки наших токенов другими сервисами
+	router.Get("/.well-known/jwks.json", h.JWKS.GetJWKS)
+
 	// Auth Routes
 	router.Post("/register", userHandler.RegisterUser)
 	router.Post("/login", userHandler.LoginUser)
+	// Второй шаг входа при включенной 2FA
+	router.Post("/login/2fa", h.TwoFactor.VerifyLogin)
+	// Вход через внешних провайдеров (OIDC, GitHub)
+	router.Get("/auth/{provider}/login", h.OAuth.StartLogin)
+	router.Get("/auth/{provider}/callback", h.OAuth.Callback)
+	// Скачивание выгрузки данных по подписанной ссылке (без токена)
+	router.Get("/exports/{exportID}/download", h.DataExport.Download)
+	// Подборка медиа по подписанной ссылке (без токена)
+	router.Get("/shares/{shareID}", h.Share.OpenShareLink)
 	router.Get("/users", userHandler.GetUsers)
 	router.Get("/users/{userID}", userHandler.GetUserByID)
 	router.Get("/users/{userID}/followers", userHandler.GetUserFollowers)
@@ -42,7 +100,7 @@ func NewRouter(userHandler *handlers.UserHandler, followHandler *handlers.Follow
 	router.Get("/users/{userID}/friends", userHandler.GetUserFriends)
 
 	router.Group(func(r chi.Router) {
-		r.Use(middleware.JWTAuthenticator)
+		r.Use(middleware.NewJWTAuthenticator(tokens, sessions))
 
 		// POST /follows - create following
 		r.Post("/follows", followHandler.CreateFollow)
@@ -51,12 +109,39 @@ func NewRouter(userHandler *handlers.UserHandler, followHandler *handlers.Follow
 
 		r.Post("/recommendations", recommendationHandler.CreateRecommendation)
 
+		// POST /reports - пожаловаться на пользователя, рекомендацию или медиа
+		r.Post("/reports", h.Moderation.CreateReport)
+
 		// --- User Routes ---
 		r.Get("/me", userHandler.GetCurrentUser)
 		r.Delete("/me", userHandler.DeleteCurrentUser)
+		// POST /me/deactivate - скрыть аккаунт до следующего входа
+		r.Post("/me/deactivate", userHandler.DeactivateCurrentUser)
 		r.Patch("/me", userHandler.UpdateCurrentUser)
 		r.Put("/me/password", userHandler.ChangeCurrentUserPassword)
 
+		// --- Linked external accounts ---
+		r.Get("/me/identities", h.OAuth.GetCurrentUserIdentities)
+		r.Post("/me/identities/{provider}", h.OAuth.StartLink)
+		r.Delete("/me/identities/{provider}", h.OAuth.UnlinkIdentity)
+
+		// --- Two-factor authentication ---
+		r.Get("/me/2fa", h.TwoFactor.GetStatus)
+		r.Post("/me/2fa/enroll", h.TwoFactor.Enroll)
+		r.Post("/me/2fa/confirm", h.TwoFactor.Confirm)
+		r.Post("/me/2fa/disable", h.TwoFactor.Disable)
+		r.Post("/me/2fa/recovery-codes", h.TwoFactor.RegenerateRecoveryCodes)
+
+		// --- Sessions (devices) ---
+		r.Get("/me/sessions", h.Session.GetCurrentUserSessions)
+		// DELETE /me/sessions - выйти на всех устройствах, кроме текущего
+		r.Delete("/me/sessions", h.Session.RevokeOtherSessions)
+		r.Delete("/me/sessions/{sessionID}", h.Session.RevokeSession)
+
+		// --- Personal data export ---
+		r.Post("/me/exports", h.DataExport.RequestExport)
+		r.Get("/me/exports/{exportID}", h.DataExport.GetExport)
+
 		// --- Follow/Friendship Routes ---
 		r.Get("/me/friends", userHandler.GetCurrentUserFriends)
 
@@ -66,13 +151,130 @@ func NewRouter(userHandler *handlers.UserHandler, followHandler *handlers.Follow
 		r.Get("/me/followings", userHandler.GetCurrentUserFollowings)
 
 		// --- Recommendation Routes ---
+		// GET /me/recommendations?direction=received&state=inbox|unread|read|snoozed|archived
+		// Фильтры: type, user_id, from, to, status; сортировка: sort=date|name|year, order=asc|desc
 		r.Get("/me/recommendations", recommendationHandler.GetCurrentUserRecommendations)
+		r.Get("/me/recommendations/unread-count", recommendationHandler.GetUnreadCount)
+		// POST /me/recommendations/read - пустой список отмечает прочитанными все входящие
+		r.Post("/me/recommendations/read", recommendationHandler.MarkRead)
+		r.Post("/me/recommendations/{recommendation_id}/unread", recommendationHandler.MarkUnread)
+		r.Post("/me/recommendations/{recommendation_id}/archive", recommendationHandler.Archive)
+		r.Delete("/me/recommendations/{recommendation_id}/archive", recommendationHandler.Unarchive)
+		r.Post("/me/recommendations/{recommendation_id}/snooze", recommendationHandler.Snooze)
+		r.Delete("/me/recommendations/{recommendation_id}/snooze", recommendationHandler.Unsnooze)
+		// Скрытие и отклонение - только у получателя; тело {"dont_recommend_again": true} запрещает медиа
+		r.Post("/me/recommendations/{recommendation_id}/hide", recommendationHandler.Hide)
+		r.Delete("/me/recommendations/{recommendation_id}/hide", recommendationHandler.Unhide)
+		r.Post("/me/recommendations/{recommendation_id}/decline", recommendationHandler.Decline)
+		r.Get("/me/recommendation-opt-outs", recommendationHandler.GetOptOuts)
+		r.Put("/me/recommendation-opt-outs/{mediaID}", recommendationHandler.AddOptOut)
+		r.Delete("/me/recommendation-opt-outs/{mediaID}", recommendationHandler.RemoveOptOut)
 		r.Get("/users/{userID}/recommendations", recommendationHandler.GetUserRecommendations)
 		r.Delete("/me/recommendations/{recommendation_id}", recommendationHandler.DeleteRecommendation)
 
+		// --- Share links: рекомендации для тех, кто не в друзьях ---
+		r.Post("/me/shares", h.Share.CreateShareLink)
+		r.Get("/me/shares", h.Share.GetMyShareLinks)
+		r.Delete("/me/shares/{shareID}", h.Share.RevokeShareLink)
+		// POST /shares/{shareID}/accept - принять подборку как полученные рекомендации
+		r.Post("/shares/{shareID}/accept", h.Share.AcceptShareLink)
+
+		// --- Circles: групповые рекомендации ---
+		r.Get("/me/circles", h.Circle.GetMyCircles)
+		r.Post("/circles", h.Circle.CreateCircle)
+		r.Get("/circles/{circleID}", h.Circle.GetCircle)
+		r.Put("/circles/{circleID}", h.Circle.UpdateCircle)
+		r.Delete("/circles/{circleID}", h.Circle.DeleteCircle)
+		r.Post("/circles/{circleID}/members", h.Circle.AddMember)
+		// role=owner передает круг другому участнику
+		r.Put("/circles/{circleID}/members/{userID}/role", h.Circle.SetMemberRole)
+		// DELETE со своим ID - выйти из круга
+		r.Delete("/circles/{circleID}/members/{userID}", h.Circle.RemoveMember)
+		// POST /circles/{circleID}/recommendations - рекомендация всем участникам сразу
+		r.Post("/circles/{circleID}/recommendations", h.Circle.Recommend)
+		r.Get("/circles/{circleID}/feed", h.Circle.GetFeed)
+		r.Put("/circles/{circleID}/posts/{postID}/reaction", h.Circle.React)
+		r.Delete("/circles/{circleID}/posts/{postID}/reaction", h.Circle.Unreact)
+
+		// --- Webhooks: события для внешних интеграций ---
+		r.Get("/me/webhooks", h.Webhook.GetWebhooks)
+		r.Post("/me/webhooks", h.Webhook.CreateWebhook)
+		r.Get("/me/webhooks/{webhookID}", h.Webhook.GetWebhook)
+		r.Put("/me/webhooks/{webhookID}", h.Webhook.UpdateWebhook)
+		r.Delete("/me/webhooks/{webhookID}", h.Webhook.DeleteWebhook)
+		r.Get("/me/webhooks/{webhookID}/deliveries", h.Webhook.GetDeliveries)
+		r.Post("/me/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", h.Webhook.Redeliver)
+
+		// --- Library Routes ---
+		r.Get("/me/library", h.Library.GetMyLibrary)
+		r.Get("/me/library/{mediaID}", h.Library.GetMyLibraryEntry)
+		// PUT /me/library/{mediaID} - добавить медиа или обновить статус и прогресс
+		r.Put("/me/library/{mediaID}", h.Library.SaveMyLibraryEntry)
+		r.Delete("/me/library/{mediaID}", h.Library.DeleteMyLibraryEntry)
+
 		r.Get("/media", mediaHandler.GetMedia)
+		// Рейтинги пересчитываются фоновой задачей, см. RankingService.RefreshRankings
+		r.Get("/media/trending", h.Ranking.GetTrending)
+		r.Get("/media/popular", h.Ranking.GetPopular)
+		// GET /media/{mediaID} - медиа с рекомендациями друзей, своей библиотекой и оценками
+		r.Get("/media/{mediaID}", mediaHandler.GetMediaDetails)
+		// POST /media/{mediaID}/tags - предложить тег, появится после одобрения модератором
+		r.Post("/media/{mediaID}/tags", h.Taxonomy.SuggestTag)
+
+		// --- Genre & Tag Routes ---
+		r.Get("/genres", h.Taxonomy.GetGenres)
+		r.Get("/genres/{slug}/media", h.Taxonomy.GetGenreMedia)
+		r.Get("/tags", h.Taxonomy.GetTags)
+		r.Get("/tags/{slug}/media", h.Taxonomy.GetTagMedia)
+
+	})
+
+	// --- Admin Routes ---
+	// Модераторы и администраторы; смена ролей - только администраторы
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(middleware.NewJWTAuthenticator(tokens, sessions))
+		r.Use(middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+
+		r.Get("/users", h.Admin.ListUsers)
+		r.Get("/users/{userID}", h.Admin.GetUser)
+		r.Post("/users/{userID}/suspend", h.Admin.SuspendUser)
+		r.Post("/users/{userID}/unsuspend", h.Admin.UnsuspendUser)
+		r.With(middleware.RequireRole(models.RoleAdmin)).Put("/users/{userID}/role", h.Admin.SetUserRole)
+
+		r.Get("/users/{userID}/followers", h.Admin.GetUserFollowers)
+		r.Get("/users/{userID}/followings", h.Admin.GetUserFollowings)
+		r.Get("/users/{userID}/recommendations", h.Admin.GetUserRecommendations)
+
+		// Очередь жалоб
+		r.Get("/reports", h.Moderation.ListReports)
+		r.Get("/reports/{reportID}", h.Moderation.GetReport)
+		r.Post("/reports/{reportID}/assign", h.Moderation.AssignReport)
+		r.Post("/reports/{reportID}/resolve", h.Moderation.ResolveReport)
+		r.Post("/reports/{reportID}/dismiss", h.Moderation.DismissReport)
+
+		// Каталог медиа
+		r.Post("/media", h.Media.CreateMedia)
+		r.Put("/media/{mediaID}", h.Media.UpdateMedia)
+		r.Post("/genres", h.Taxonomy.CreateGenre)
+		r.Get("/tag-suggestions", h.Taxonomy.GetTagSuggestions)
+		r.Post("/tag-suggestions/{suggestionID}/approve", h.Taxonomy.ApproveTagSuggestion)
+		r.Post("/tag-suggestions/{suggestionID}/reject", h.Taxonomy.RejectTagSuggestion)
+
+		// Журнал событий безопасности - только администраторы
+		r.With(middleware.RequireRole(models.RoleAdmin)).Get("/audit-events", h.Audit.ListEvents)
 
+		// Вебхуки администраторов - только администраторы
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.RoleAdmin))
+			r.Get("/webhooks", h.AdminWebhook.GetWebhooks)
+			r.Post("/webhooks", h.AdminWebhook.CreateWebhook)
+			r.Get("/webhooks/{webhookID}", h.AdminWebhook.GetWebhook)
+			r.Put("/webhooks/{webhookID}", h.AdminWebhook.UpdateWebhook)
+			r.Delete("/webhooks/{webhookID}", h.AdminWebhook.DeleteWebhook)
+			r.Get("/webhooks/{webhookID}/deliveries", h.AdminWebhook.GetDeliveries)
+			r.Post("/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver", h.AdminWebhook.Redeliver)
+		})
 	})
 
-	return router
+	return root
 }
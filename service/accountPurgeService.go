@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+)
+
+// JobPurgeAccount - задача стирания одного аккаунта по окончании периода восстановления.
+const JobPurgeAccount = "account.purge"
+
+type accountPurgeJob struct {
+	UserID int `json:"user_id"`
+}
+
+// purgeBatchSize ограничивает число аккаунтов, стираемых за один запуск.
+const purgeBatchSize = 100
+
+// AccountSettings - параметры удаления аккаунтов из конфига.
+type AccountSettings struct {
+	// DeletionGracePeriod - сколько удаленный аккаунт можно восстановить входом.
+	DeletionGracePeriod time.Duration
+	// AnonymizeRecommendations оставляет отправленные рекомендации у получателей
+	// без автора вместо удаления.
+	AnonymizeRecommendations bool
+}
+
+// AccountPurgeService окончательно стирает аккаунты, период восстановления которых истек.
+type AccountPurgeService struct {
+	db         *sql.DB
+	userRepo   *repo.UserRepo
+	followRepo *repo.FollowRepo
+	recomRepo  *repo.RecommendationRepo
+	exportRepo *repo.DataExportRepo
+	circleRepo *repo.CircleRepo
+	settings   AccountSettings
+	logger     *slog.Logger
+}
+
+func NewAccountPurgeService(db *sql.DB, userRepo *repo.UserRepo, followRepo *repo.FollowRepo, recomRepo *repo.RecommendationRepo,
+	exportRepo *repo.DataExportRepo, circleRepo *repo.CircleRepo, settings AccountSettings, logger *slog.Logger) *AccountPurgeService {
+	return &AccountPurgeService{
+		db:         db,
+		userRepo:   userRepo,
+		followRepo: followRepo,
+		recomRepo:  recomRepo,
+		exportRepo: exportRepo,
+		circleRepo: circleRepo,
+		settings:   settings,
+		logger:     logger,
+	}
+}
+
+// PurgeAccount - обработчик задачи JobPurgeAccount, которую ставит UserService.DeleteUser.
+// Если аккаунт за это время восстановили, задача ничего не делает.
+func (s *AccountPurgeService) PurgeAccount(ctx context.Context, job models.Job) error {
+	ctx, span := tracer.Start(ctx, "AccountPurgeService.PurgeAccount")
+	defer span.End()
+
+	var payload accountPurgeJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid account purge job payload: %w", err)
+	}
+
+	deleted, err := s.userRepo.IsUserDeletedBefore(ctx, payload.UserID, time.Now().Add(-s.settings.DeletionGracePeriod))
+	if err != nil || !deleted {
+		return err
+	}
+	if err := s.purgeUser(ctx, payload.UserID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Deleted account purged", "user_id", payload.UserID)
+	return nil
+}
+
+// PurgeDeletedUsers стирает аккаунты, удаленные раньше чем DeletionGracePeriod назад, которые
+// не стерла задача JobPurgeAccount (например, удаленные до ее появления). Запускается по расписанию.
+func (s *AccountPurgeService) PurgeDeletedUsers(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "AccountPurgeService.PurgeDeletedUsers")
+	defer span.End()
+
+	cutoff := time.Now().Add(-s.settings.DeletionGracePeriod)
+	for ctx.Err() == nil {
+		userIDs, err := s.userRepo.GetUsersDeletedBefore(ctx, cutoff, purgeBatchSize)
+		if err != nil {
+			s.logger.Error("Failed to get accounts to purge", "error", err)
+			return
+		}
+
+		for _, userID := range userIDs {
+			if err := s.purgeUser(ctx, userID); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				// Остальные аккаунты пачки все равно обрабатываем; этот попадет в следующий запуск
+				s.logger.Error("Failed to purge account", "error", err, "user_id", userID)
+				continue
+			}
+			s.logger.Info("Deleted account purged", "user_id", userID)
+		}
+
+		if len(userIDs) < purgeBatchSize {
+			return
+		}
+	}
+}
+
+// purgeUser стирает данные одного аккаунта в транзакции. Сессии, привязки,
+// 2FA и записи о выгрузках удаляются каскадно вместе с users.
+func (s *AccountPurgeService) purgeUser(ctx context.Context, userID int) error {
+	// Пути нужно получить до удаления: записи о выгрузках удалятся каскадно
+	archives, err := s.exportRepo.GetUserArchivePaths(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	recomRepoTx := s.recomRepo.WithTx(tx)
+	if s.settings.AnonymizeRecommendations {
+		if err := recomRepoTx.AnonymizeSentRecommendations(ctx, userID); err != nil {
+			return err
+		}
+		if err := recomRepoTx.DeleteReceivedRecommendations(ctx, userID); err != nil {
+			return err
+		}
+	} else {
+		if err := recomRepoTx.DeleteAllUserRecommendations(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.followRepo.WithTx(tx).DeleteAllUserFollows(ctx, userID); err != nil {
+		return err
+	}
+	// Без этого круги, которыми владел пользователь, остались бы без владельца
+	if err := s.circleRepo.WithTx(tx).LeaveAllCircles(ctx, userID); err != nil {
+		return err
+	}
+	if err := s.userRepo.WithTx(tx).DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, path := range archives {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("Failed to remove export archive of purged account", "error", err, "user_id", userID)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+)
+
+var (
+	ErrInvalidRole          = errors.New("invalid role")
+	ErrInvalidUserStatus    = errors.New("invalid user status: must be active, deactivated, deleted or suspended")
+	ErrCannotModerateSelf   = errors.New("cannot change the role or suspension of your own account")
+	ErrInsufficientRole     = errors.New("only administrators can manage moderator and administrator accounts")
+	ErrUserAlreadySuspended = errors.New("user is already suspended")
+	ErrUserNotSuspended     = errors.New("user is not suspended")
+)
+
+// AdminService - операции модераторов и администраторов над чужими аккаунтами.
+// Права на сами вызовы проверяет middleware.RequireRole, здесь - только правила
+// вроде "модератор не может заблокировать администратора".
+// Все изменения аккаунтов записываются в журнал действий модераторов.
+type AdminService struct {
+	db             *sql.DB
+	userRepo       *repo.UserRepo
+	recomRepo      *repo.RecommendationRepo
+	moderationRepo *repo.ModerationRepo
+	sessions       *SessionService
+	logger         *slog.Logger
+}
+
+func NewAdminService(db *sql.DB, userRepo *repo.UserRepo, recomRepo *repo.RecommendationRepo, moderationRepo *repo.ModerationRepo,
+	sessions *SessionService, logger *slog.Logger) *AdminService {
+	return &AdminService{
+		db:             db,
+		userRepo:       userRepo,
+		recomRepo:      recomRepo,
+		moderationRepo: moderationRepo,
+		sessions:       sessions,
+		logger:         logger,
+	}
+}
+
+// ListUsers ищет пользователей по всем аккаунтам, включая удаленные, деактивированные и заблокированные.
+func (s *AdminService) ListUsers(ctx context.Context, filter repo.UserFilter, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error) {
+	ctx, span := tracer.Start(ctx, "AdminService.ListUsers")
+	defer span.End()
+
+	if filter.Role != "" && !filter.Role.Valid() {
+		return nil, ErrInvalidRole
+	}
+	switch filter.Status {
+	case "", "active", "deactivated", "deleted", "suspended":
+	default:
+		return nil, ErrInvalidUserStatus
+	}
+
+	users, total, err := s.userRepo.SearchUsers(ctx, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(users, total, page, limit), nil
+}
+
+func (s *AdminService) GetUser(ctx context.Context, userID int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GetUser")
+	defer span.End()
+
+	user, err := s.userRepo.FindUserByIDWithPassword(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
+
+// SuspendUser блокирует аккаунт и завершает все его сессии. Заблокированный
+// пользователь не может войти и скрыт из всех списков.
+func (s *AdminService) SuspendUser(ctx context.Context, actorID int, actorRole models.Role, userID int, reason string) error {
+	ctx, span := tracer.Start(ctx, "AdminService.SuspendUser")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.suspendUserTx(ctx, tx, actorID, actorRole, userID, reason, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	return s.endSuspendedSessions(ctx, actorID, userID)
+}
+
+// suspendUserTx блокирует аккаунт внутри транзакции вызывающего (например, вместе
+// с закрытием жалобы). reportID связывает запись журнала с жалобой. После коммита
+// нужно вызвать endSuspendedSessions.
+func (s *AdminService) suspendUserTx(ctx context.Context, tx *sql.Tx, actorID int, actorRole models.Role, userID int, reason string, reportID *int) error {
+	if err := s.checkCanModerate(ctx, actorID, actorRole, userID); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.WithTx(tx).SuspendUser(ctx, userID, reason); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserAlreadySuspended
+		}
+		return err
+	}
+
+	return s.moderationRepo.WithTx(tx).RecordAction(ctx, models.ModerationAction{
+		ModeratorID: actorID,
+		ReportID:    reportID,
+		Action:      models.ActionSuspendUser,
+		TargetType:  models.ReportTargetUser,
+		TargetID:    userID,
+		Note:        reason,
+	})
+}
+
+func (s *AdminService) endSuspendedSessions(ctx context.Context, actorID, userID int) error {
+	if err := s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+		s.logger.Error("Failed to revoke sessions of suspended user", "error", err, "user_id", userID)
+		return err
+	}
+
+	s.logger.Info("User suspended", "user_id", userID, "actor_id", actorID)
+	return nil
+}
+
+func (s *AdminService) UnsuspendUser(ctx context.Context, actorID int, actorRole models.Role, userID int) error {
+	ctx, span := tracer.Start(ctx, "AdminService.UnsuspendUser")
+	defer span.End()
+
+	if err := s.checkCanModerate(ctx, actorID, actorRole, userID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.userRepo.WithTx(tx).UnsuspendUser(ctx, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotSuspended
+		}
+		return err
+	}
+	err = s.moderationRepo.WithTx(tx).RecordAction(ctx, models.ModerationAction{
+		ModeratorID: actorID,
+		Action:      models.ActionUnsuspendUser,
+		TargetType:  models.ReportTargetUser,
+		TargetID:    userID,
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Info("User unsuspended", "user_id", userID, "actor_id", actorID)
+	return nil
+}
+
+// SetUserRole меняет роль пользователя. Роль записана в токене, поэтому сессии
+// пользователя отзываются: новая роль начнет действовать со следующего входа.
+func (s *AdminService) SetUserRole(ctx context.Context, actorID, userID int, role models.Role) error {
+	ctx, span := tracer.Start(ctx, "AdminService.SetUserRole")
+	defer span.End()
+
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+	// Иначе последний администратор может случайно лишить себя прав
+	if actorID == userID {
+		return ErrCannotModerateSelf
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.userRepo.WithTx(tx).SetUserRole(ctx, userID, role); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	err = s.moderationRepo.WithTx(tx).RecordAction(ctx, models.ModerationAction{
+		ModeratorID: actorID,
+		Action:      models.ActionSetUserRole,
+		TargetType:  models.ReportTargetUser,
+		TargetID:    userID,
+		Note:        string(role),
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	if err := s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+		s.logger.Error("Failed to revoke sessions after role change", "error", err, "user_id", userID)
+		return err
+	}
+
+	s.logger.Info("User role changed", "user_id", userID, "role", role, "actor_id", actorID)
+	return nil
+}
+
+// checkCanModerate запрещает действия над собой, а модераторам - над другими модераторами и администраторами.
+func (s *AdminService) checkCanModerate(ctx context.Context, actorID int, actorRole models.Role, userID int) error {
+	if actorID == userID {
+		return ErrCannotModerateSelf
+	}
+
+	target, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if target.Role.IsStaff() && actorRole != models.RoleAdmin {
+		return ErrInsufficientRole
+	}
+	return nil
+}
+
+// GetUserFollowers и GetUserFollowings показывают подписки любого пользователя,
+// в том числе скрытого от остальных. В самих списках - только видимые аккаунты.
+func (s *AdminService) GetUserFollowers(ctx context.Context, userID, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GetUserFollowers")
+	defer span.End()
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	users, total, err := s.userRepo.GetUserFollowers(ctx, userID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(users, total, page, limit), nil
+}
+
+func (s *AdminService) GetUserFollowings(ctx context.Context, userID, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GetUserFollowings")
+	defer span.End()
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	users, total, err := s.userRepo.GetUserFollowings(ctx, userID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(users, total, page, limit), nil
+}
+
+// GetUserRecommendations возвращает полученные (direction=received, по умолчанию)
+// или отправленные (direction=sent) пользователем рекомендации.
+func (s *AdminService) GetUserRecommendations(ctx context.Context, userID int, direction string) ([]models.RecommendationDetails, error) {
+	ctx, span := tracer.Start(ctx, "AdminService.GetUserRecommendations")
+	defer span.End()
+
+	if _, err := s.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if direction == "sent" {
+		return s.recomRepo.GetSentRecommendations(ctx, userID, repo.RecommendationFilter{})
+	}
+	recommendations, err := s.recomRepo.GetReceivedRecommendations(ctx, userID, repo.RecommendationFilter{IncludeHidden: true})
+	if err != nil {
+		return nil, err
+	}
+	return withoutInboxState(recommendations), nil
+}
+
+func newPage[T any](data []T, total int64, page, limit int) *dtos.PaginatedResponseDTO[T] {
+	// Вычисляем общее количество страниц
+	totalPages := 0
+	if total > 0 {
+		totalPages = int((total + int64(limit) - 1) / int64(limit))
+	}
+
+	return &dtos.PaginatedResponseDTO[T]{
+		Data:       data,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		TotalPages: totalPages,
+	}
+}
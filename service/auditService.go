@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/requestmeta"
+)
+
+var ErrInvalidTimeRange = errors.New("invalid time range: 'from' must be before 'to'")
+
+// AuditService ведет журнал событий безопасности. Сервисы пишут в него в той же
+// транзакции, что и само действие: если запись в журнал не удалась, действие откатывается.
+type AuditService struct {
+	r      *repo.AuditRepo
+	logger *slog.Logger
+}
+
+func NewAuditService(r *repo.AuditRepo, logger *slog.Logger) *AuditService {
+	return &AuditService{r: r, logger: logger}
+}
+
+// record добавляет событие в журнал; IP и идентификатор запроса берутся из контекста.
+// tx = nil - запись вне транзакции (для событий, которые ничего не меняют, например неудачный вход).
+// Нулевые actorID и targetID означают "неизвестно".
+func (s *AuditService) record(ctx context.Context, tx *sql.Tx, actorID int, action models.AuditAction,
+	targetType string, targetID int, metadata map[string]any) error {
+	meta := requestmeta.FromContext(ctx)
+	event := models.AuditEvent{
+		Action:     action,
+		TargetType: targetType,
+		IPAddress:  meta.IP,
+		RequestID:  meta.RequestID,
+		Metadata:   metadata,
+	}
+	if actorID != 0 {
+		event.ActorID = &actorID
+	}
+	if targetID != 0 {
+		event.TargetID = &targetID
+	}
+
+	r := s.r
+	if tx != nil {
+		r = r.WithTx(tx)
+	}
+	return r.RecordEvent(ctx, event)
+}
+
+// ListEvents - выборка журнала для администраторов.
+func (s *AuditService) ListEvents(ctx context.Context, filter repo.AuditFilter, page, limit int) (*dtos.PaginatedResponseDTO[models.AuditEvent], error) {
+	ctx, span := tracer.Start(ctx, "AuditService.ListEvents")
+	defer span.End()
+
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return nil, ErrInvalidTimeRange
+	}
+
+	events, total, err := s.r.ListEvents(ctx, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(events, total, page, limit), nil
+}
@@ -0,0 +1,436 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+)
+
+var (
+	ErrCircleNotFound         = errors.New("circle not found")
+	ErrCircleForbidden        = errors.New("your role in this circle does not allow this action")
+	ErrInvalidCircleName      = errors.New("circle name must be from 1 to 80 characters")
+	ErrInvalidCircleNote      = errors.New("circle description or note is too long")
+	ErrInvalidCircleRole      = errors.New("role must be owner, admin or member")
+	ErrAlreadyCircleMember    = errors.New("user is already a member of this circle")
+	ErrCircleMemberNotFound   = errors.New("circle member not found")
+	ErrCircleOwnerCannotLeave = errors.New("circle owner must transfer ownership before leaving")
+	ErrCircleFull             = errors.New("circle has reached the member limit")
+	ErrCirclePostNotFound     = errors.New("circle post not found")
+	ErrInvalidReaction        = errors.New("invalid reaction")
+	ErrReactionNotFound       = errors.New("reaction not found")
+)
+
+const (
+	maxCircleNameLength = 80
+	maxCircleTextLength = 500
+	maxCircleMembers    = 100
+)
+
+// CircleService управляет кругами: участниками, лентой и реакциями.
+// Рекомендация в круг расходится всем участникам без проверки дружбы с автором:
+// в круг их добавили друзья.
+type CircleService struct {
+	db          *sql.DB
+	r           *repo.CircleRepo
+	followRepo  *repo.FollowRepo
+	mediaRepo   *repo.MediaRepo
+	userService *UserService
+	webhooks    *WebhookService
+	logger      *slog.Logger
+}
+
+func NewCircleService(db *sql.DB, r *repo.CircleRepo, followRepo *repo.FollowRepo, mediaRepo *repo.MediaRepo,
+	userService *UserService, webhooks *WebhookService, logger *slog.Logger) *CircleService {
+	return &CircleService{
+		db:          db,
+		r:           r,
+		followRepo:  followRepo,
+		mediaRepo:   mediaRepo,
+		userService: userService,
+		webhooks:    webhooks,
+		logger:      logger,
+	}
+}
+
+// CreateCircle создает круг; создатель становится владельцем.
+func (s *CircleService) CreateCircle(ctx context.Context, userID int, name, description string) (models.Circle, error) {
+	ctx, span := tracer.Start(ctx, "CircleService.CreateCircle")
+	defer span.End()
+
+	name, description, err := normalizeCircle(name, description)
+	if err != nil {
+		return models.Circle{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Circle{}, err
+	}
+	defer tx.Rollback()
+
+	circles := s.r.WithTx(tx)
+	circle, err := circles.CreateCircle(ctx, name, description)
+	if err != nil {
+		return models.Circle{}, err
+	}
+	if _, err := circles.AddMember(ctx, circle.ID, userID, models.CircleRoleOwner); err != nil {
+		return models.Circle{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Circle{}, err
+	}
+
+	circle.MemberCount = 1
+	circle.MyRole = models.CircleRoleOwner
+	return circle, nil
+}
+
+// GetMyCircles возвращает круги, в которых состоит пользователь.
+func (s *CircleService) GetMyCircles(ctx context.Context, userID int) ([]models.Circle, error) {
+	ctx, span := tracer.Start(ctx, "CircleService.GetMyCircles")
+	defer span.End()
+
+	return s.r.GetUserCircles(ctx, userID)
+}
+
+// GetCircle возвращает круг и его участников. Круг видят только участники.
+func (s *CircleService) GetCircle(ctx context.Context, userID, circleID int) (models.Circle, []models.CircleMember, error) {
+	ctx, span := tracer.Start(ctx, "CircleService.GetCircle")
+	defer span.End()
+
+	role, err := s.requireRole(ctx, s.r, circleID, userID, models.CircleRoleMember)
+	if err != nil {
+		return models.Circle{}, nil, err
+	}
+
+	circle, err := s.r.GetCircle(ctx, circleID)
+	if err != nil {
+		return models.Circle{}, nil, circleError(err)
+	}
+	circle.MyRole = role
+
+	members, err := s.r.GetMembers(ctx, circleID)
+	if err != nil {
+		return models.Circle{}, nil, err
+	}
+	return circle, members, nil
+}
+
+// UpdateCircle меняет название и описание; доступно администраторам.
+func (s *CircleService) UpdateCircle(ctx context.Context, userID, circleID int, name, description string) error {
+	ctx, span := tracer.Start(ctx, "CircleService.UpdateCircle")
+	defer span.End()
+
+	name, description, err := normalizeCircle(name, description)
+	if err != nil {
+		return err
+	}
+	if _, err := s.requireRole(ctx, s.r, circleID, userID, models.CircleRoleAdmin); err != nil {
+		return err
+	}
+	return circleError(s.r.UpdateCircle(ctx, circleID, name, description))
+}
+
+// DeleteCircle удаляет круг вместе с лентой; доступно только владельцу.
+// Разошедшиеся из круга рекомендации остаются у получателей.
+func (s *CircleService) DeleteCircle(ctx context.Context, userID, circleID int) error {
+	ctx, span := tracer.Start(ctx, "CircleService.DeleteCircle")
+	defer span.End()
+
+	if _, err := s.requireRole(ctx, s.r, circleID, userID, models.CircleRoleOwner); err != nil {
+		return err
+	}
+	return circleError(s.r.DeleteCircle(ctx, circleID))
+}
+
+// AddMember добавляет в круг друга того, кто добавляет. Администраторов назначает только владелец.
+func (s *CircleService) AddMember(ctx context.Context, actorID, circleID, userID int, role models.CircleRole) error {
+	ctx, span := tracer.Start(ctx, "CircleService.AddMember")
+	defer span.End()
+
+	if role == "" {
+		role = models.CircleRoleMember
+	}
+	if role != models.CircleRoleMember && role != models.CircleRoleAdmin {
+		return ErrInvalidCircleRole
+	}
+
+	actorRole, err := s.requireRole(ctx, s.r, circleID, actorID, models.CircleRoleAdmin)
+	if err != nil {
+		return err
+	}
+	if role == models.CircleRoleAdmin && actorRole != models.CircleRoleOwner {
+		return ErrCircleForbidden
+	}
+
+	if err := s.userService.CheckActiveUser(ctx, userID); err != nil {
+		return err
+	}
+	areFriends, err := s.followRepo.AreUsersFriends(ctx, actorID, userID)
+	if err != nil {
+		return err
+	}
+	if !areFriends {
+		return ErrNotFriends
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	circles := s.r.WithTx(tx)
+	count, err := circles.CountMembers(ctx, circleID)
+	if err != nil {
+		return err
+	}
+	if count >= maxCircleMembers {
+		return ErrCircleFull
+	}
+
+	added, err := circles.AddMember(ctx, circleID, userID, role)
+	if err != nil {
+		return err
+	}
+	if !added {
+		return ErrAlreadyCircleMember
+	}
+	return tx.Commit()
+}
+
+// SetMemberRole меняет роль участника; доступно только владельцу.
+// Назначение другого участника владельцем передает круг, прежний владелец становится администратором.
+func (s *CircleService) SetMemberRole(ctx context.Context, actorID, circleID, userID int, role models.CircleRole) error {
+	ctx, span := tracer.Start(ctx, "CircleService.SetMemberRole")
+	defer span.End()
+
+	if !role.Valid() {
+		return ErrInvalidCircleRole
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	circles := s.r.WithTx(tx)
+	if _, err := s.requireRole(ctx, circles, circleID, actorID, models.CircleRoleOwner); err != nil {
+		return err
+	}
+	if actorID == userID {
+		// Владелец не может понизить себя, не назначив другого владельца
+		return ErrCircleOwnerCannotLeave
+	}
+	if _, err := circles.GetMemberRole(ctx, circleID, userID); err != nil {
+		return memberError(err)
+	}
+
+	if role == models.CircleRoleOwner {
+		// Сначала понижаем прежнего владельца: владелец в круге один (уникальный индекс)
+		if err := circles.SetMemberRole(ctx, circleID, actorID, models.CircleRoleAdmin); err != nil {
+			return err
+		}
+	}
+	if err := circles.SetMemberRole(ctx, circleID, userID, role); err != nil {
+		return memberError(err)
+	}
+	return tx.Commit()
+}
+
+// RemoveMember исключает участника. Участник может выйти сам; исключать других могут
+// только те, чья роль выше роли исключаемого.
+func (s *CircleService) RemoveMember(ctx context.Context, actorID, circleID, userID int) error {
+	ctx, span := tracer.Start(ctx, "CircleService.RemoveMember")
+	defer span.End()
+
+	actorRole, err := s.requireRole(ctx, s.r, circleID, actorID, models.CircleRoleMember)
+	if err != nil {
+		return err
+	}
+
+	if actorID == userID {
+		if actorRole == models.CircleRoleOwner {
+			return ErrCircleOwnerCannotLeave
+		}
+		return memberError(s.r.RemoveMember(ctx, circleID, userID))
+	}
+
+	targetRole, err := s.r.GetMemberRole(ctx, circleID, userID)
+	if err != nil {
+		return memberError(err)
+	}
+	if !actorRole.AtLeast(models.CircleRoleAdmin) || targetRole.AtLeast(actorRole) {
+		return ErrCircleForbidden
+	}
+	return memberError(s.r.RemoveMember(ctx, circleID, userID))
+}
+
+// Recommend публикует медиа в ленту круга и рассылает его участникам как полученные рекомендации.
+func (s *CircleService) Recommend(ctx context.Context, userID, circleID, mediaID int, note string) (models.CirclePost, error) {
+	ctx, span := tracer.Start(ctx, "CircleService.Recommend")
+	defer span.End()
+
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxCircleTextLength {
+		return models.CirclePost{}, ErrInvalidCircleNote
+	}
+	if _, err := s.requireRole(ctx, s.r, circleID, userID, models.CircleRoleMember); err != nil {
+		return models.CirclePost{}, err
+	}
+
+	media, err := s.mediaRepo.GetMedia(ctx, mediaID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.CirclePost{}, ErrMediaNotFound
+		}
+		return models.CirclePost{}, err
+	}
+	author, err := s.userService.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.CirclePost{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.CirclePost{}, err
+	}
+	defer tx.Rollback()
+
+	circles := s.r.WithTx(tx)
+	post, err := circles.CreatePost(ctx, circleID, userID, mediaID, note)
+	if err != nil {
+		return models.CirclePost{}, err
+	}
+	recommendations, err := circles.FanOutPost(ctx, post.ID)
+	if err != nil {
+		return models.CirclePost{}, err
+	}
+	for _, recommendation := range recommendations {
+		err := s.webhooks.publishRecommendation(ctx, tx, recommendation.ID, recommendation.ToUserID, author, media, note, circleID)
+		if err != nil {
+			return models.CirclePost{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return models.CirclePost{}, err
+	}
+
+	post.Author = author
+	post.RecipientCount = int64(len(recommendations))
+	post.Media = media
+	post.Reactions = []models.CirclePostReaction{}
+
+	s.logger.Info("Circle recommendation posted", "user_id", userID, "circle_id", circleID,
+		"post_id", post.ID, "recipients", post.RecipientCount)
+	return post, nil
+}
+
+// GetFeed возвращает ленту круга с реакциями участников.
+func (s *CircleService) GetFeed(ctx context.Context, userID, circleID, page, limit int) (*dtos.PaginatedResponseDTO[models.CirclePost], error) {
+	ctx, span := tracer.Start(ctx, "CircleService.GetFeed")
+	defer span.End()
+
+	if _, err := s.requireRole(ctx, s.r, circleID, userID, models.CircleRoleMember); err != nil {
+		return nil, err
+	}
+
+	posts, total, err := s.r.GetFeed(ctx, circleID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(posts, total, page, limit), nil
+}
+
+// React ставит или меняет реакцию участника на пост.
+func (s *CircleService) React(ctx context.Context, userID, circleID int, postID int64, reaction models.CircleReaction) error {
+	ctx, span := tracer.Start(ctx, "CircleService.React")
+	defer span.End()
+
+	if !reaction.Valid() {
+		return ErrInvalidReaction
+	}
+	if err := s.requirePost(ctx, userID, circleID, postID); err != nil {
+		return err
+	}
+	return s.r.SetReaction(ctx, postID, userID, reaction)
+}
+
+// Unreact снимает реакцию участника с поста.
+func (s *CircleService) Unreact(ctx context.Context, userID, circleID int, postID int64) error {
+	ctx, span := tracer.Start(ctx, "CircleService.Unreact")
+	defer span.End()
+
+	if err := s.requirePost(ctx, userID, circleID, postID); err != nil {
+		return err
+	}
+	if err := s.r.RemoveReaction(ctx, postID, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrReactionNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *CircleService) requirePost(ctx context.Context, userID, circleID int, postID int64) error {
+	if _, err := s.requireRole(ctx, s.r, circleID, userID, models.CircleRoleMember); err != nil {
+		return err
+	}
+	exists, err := s.r.PostExists(ctx, circleID, postID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrCirclePostNotFound
+	}
+	return nil
+}
+
+// requireRole возвращает роль пользователя в круге. Не участнику круг не виден (ErrCircleNotFound),
+// участнику с ролью ниже min возвращается ErrCircleForbidden.
+func (s *CircleService) requireRole(ctx context.Context, r *repo.CircleRepo, circleID, userID int, min models.CircleRole) (models.CircleRole, error) {
+	role, err := r.GetMemberRole(ctx, circleID, userID)
+	if err != nil {
+		return "", circleError(err)
+	}
+	if !role.AtLeast(min) {
+		return "", ErrCircleForbidden
+	}
+	return role, nil
+}
+
+func normalizeCircle(name, description string) (string, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxCircleNameLength {
+		return "", "", ErrInvalidCircleName
+	}
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > maxCircleTextLength {
+		return "", "", ErrInvalidCircleNote
+	}
+	return name, description, nil
+}
+
+func circleError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCircleNotFound
+	}
+	return err
+}
+
+func memberError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrCircleMemberNotFound
+	}
+	return err
+}
@@ -0,0 +1,221 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+)
+
+// buildArchive собирает ZIP со всеми данными пользователя: каждый набор
+// записывается в JSON (для машин) и, если это таблица, в CSV (для людей).
+// Архив пишется во временный файл и переименовывается в конце, чтобы
+// никто не получил недописанный файл.
+func (s *DataExportService) buildArchive(ctx context.Context, userID int, path string) (err error) {
+	user, err := s.userRepo.FindUserByIDWithPassword(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	followers, err := s.followRepo.GetAllFollowers(ctx, userID)
+	if err != nil {
+		return err
+	}
+	followings, err := s.followRepo.GetAllFollowings(ctx, userID)
+	if err != nil {
+		return err
+	}
+	sent, err := s.recomRepo.GetSentRecommendations(ctx, userID, repo.RecommendationFilter{})
+	if err != nil {
+		return err
+	}
+	received, err := s.recomRepo.GetReceivedRecommendations(ctx, userID, repo.RecommendationFilter{IncludeHidden: true})
+	if err != nil {
+		return err
+	}
+	identities, err := s.identityRepo.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+	library, err := s.libraryRepo.GetAllEntries(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create export archive: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			file.Close()
+			os.Remove(tmpPath)
+		}
+	}()
+
+	archive := zip.NewWriter(file)
+
+	profile := dtos.ExportProfileDTO{
+		UserID:               user.ID,
+		UserName:             user.UserName,
+		Email:                user.Email,
+		PasswordLoginEnabled: user.PasswordLoginEnabled,
+		CreatedAt:            user.CreatedAt,
+	}
+	if err = writeJSON(archive, "profile.json", profile); err != nil {
+		return err
+	}
+
+	identityDTOs := make([]dtos.IdentityResponseDTO, 0, len(identities))
+	for _, identity := range identities {
+		identityDTOs = append(identityDTOs, dtos.IdentityResponseDTO{Provider: identity.Provider, Email: identity.Email, CreatedAt: identity.CreatedAt})
+	}
+	if err = writeJSON(archive, "linked_accounts.json", identityDTOs); err != nil {
+		return err
+	}
+
+	if err = writeFollows(archive, "followers", followers); err != nil {
+		return err
+	}
+	if err = writeFollows(archive, "followings", followings); err != nil {
+		return err
+	}
+
+	if err = writeRecommendations(archive, "recommendations_sent", "to_user", sent); err != nil {
+		return err
+	}
+	if err = writeRecommendations(archive, "recommendations_received", "from_user", received); err != nil {
+		return err
+	}
+
+	if err = writeLibrary(archive, "library", library); err != nil {
+		return err
+	}
+
+	if err = archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	if err = file.Close(); err != nil {
+		return fmt.Errorf("failed to write export archive: %w", err)
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+func writeFollows(archive *zip.Writer, name string, follows []models.FollowDetails) error {
+	records := make([]dtos.ExportFollowDTO, 0, len(follows))
+	rows := [][]string{{"user_id", "user_name", "followed_at"}}
+	for _, follow := range follows {
+		records = append(records, dtos.ExportFollowDTO{
+			User:       dtos.ExportUserDTO{UserID: follow.User.ID, UserName: follow.User.UserName},
+			FollowedAt: follow.CreatedAt,
+		})
+		rows = append(rows, []string{strconv.Itoa(follow.User.ID), follow.User.UserName, follow.CreatedAt.Format(time.RFC3339)})
+	}
+
+	if err := writeJSON(archive, name+".json", records); err != nil {
+		return err
+	}
+	return writeCSV(archive, name+".csv", rows)
+}
+
+// userColumn - заголовок колонки со вторым пользователем: получатель или отправитель.
+func writeRecommendations(archive *zip.Writer, name, userColumn string, recommendations []models.RecommendationDetails) error {
+	records := make([]dtos.ExportRecommendationDTO, 0, len(recommendations))
+	rows := [][]string{{"recommendation_id", "created_at", "media_id", "media_type", "media_name", "media_year", "media_author", userColumn + "_id", userColumn + "_name"}}
+	for _, rec := range recommendations {
+		records = append(records, dtos.ExportRecommendationDTO{
+			RecommendationID: rec.RecommendationID,
+			Media: dtos.ExportMediaDTO{
+				MediaID: rec.Media.ID,
+				Type:    string(rec.Media.Type),
+				Name:    rec.Media.Name,
+				Year:    rec.Media.Year,
+				Author:  rec.Media.Author,
+			},
+			User:      dtos.ExportUserDTO{UserID: rec.User.ID, UserName: rec.User.UserName},
+			CreatedAt: rec.CreatedAt,
+		})
+		rows = append(rows, []string{
+			strconv.Itoa(rec.RecommendationID), rec.CreatedAt.Format(time.RFC3339),
+			strconv.Itoa(rec.Media.ID), string(rec.Media.Type), rec.Media.Name, strconv.Itoa(rec.Media.Year), rec.Media.Author,
+			strconv.Itoa(rec.User.ID), rec.User.UserName,
+		})
+	}
+
+	if err := writeJSON(archive, name+".json", records); err != nil {
+		return err
+	}
+	return writeCSV(archive, name+".csv", rows)
+}
+
+func writeLibrary(archive *zip.Writer, name string, entries []models.UserMedia) error {
+	records := make([]dtos.ExportLibraryEntryDTO, 0, len(entries))
+	rows := [][]string{{"media_id", "media_type", "media_name", "status", "progress", "progress_unit", "started_on", "finished_on", "score"}}
+	for _, entry := range entries {
+		record := dtos.ExportLibraryEntryDTO{
+			Media: dtos.ExportMediaDTO{
+				MediaID: entry.Media.ID,
+				Type:    string(entry.Media.Type),
+				Name:    entry.Media.Name,
+				Year:    entry.Media.Year,
+				Author:  entry.Media.Author,
+			},
+			Status:       string(entry.Status),
+			Progress:     entry.Progress,
+			ProgressUnit: entry.Media.Type.ProgressUnit(),
+			Score:        entry.Score,
+			UpdatedAt:    entry.UpdatedAt,
+		}
+		if entry.StartedOn != nil {
+			record.StartedOn = entry.StartedOn.Format(time.DateOnly)
+		}
+		if entry.FinishedOn != nil {
+			record.FinishedOn = entry.FinishedOn.Format(time.DateOnly)
+		}
+		score := ""
+		if entry.Score != nil {
+			score = strconv.Itoa(*entry.Score)
+		}
+		records = append(records, record)
+		rows = append(rows, []string{
+			strconv.Itoa(entry.Media.ID), string(entry.Media.Type), entry.Media.Name, string(entry.Status),
+			strconv.Itoa(entry.Progress), record.ProgressUnit, record.StartedOn, record.FinishedOn, score,
+		})
+	}
+
+	if err := writeJSON(archive, name+".json", records); err != nil {
+		return err
+	}
+	return writeCSV(archive, name+".csv", rows)
+}
+
+func writeJSON(archive *zip.Writer, name string, v any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeCSV(archive *zip.Writer, name string, rows [][]string) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return fmt.Errorf("failed to add %s to export: %w", name, err)
+	}
+	writer := csv.NewWriter(w)
+	if err := writer.WriteAll(rows); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
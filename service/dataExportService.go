@@ -0,0 +1,255 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrExportNotFound      = errors.New("data export not found")
+	ErrInvalidDownloadLink = errors.New("download link is invalid or expired")
+)
+
+// JobBuildDataExport - задача сборки архива выгрузки.
+const JobBuildDataExport = "data_export.build"
+
+type dataExportJob struct {
+	ExportID string `json:"export_id"`
+}
+
+// DataExportSettings - параметры выгрузки из конфига.
+type DataExportSettings struct {
+	// Dir - каталог, где хранятся готовые архивы.
+	Dir        string
+	LinkSecret []byte
+	// LinkTTL - срок действия ссылки на скачивание.
+	LinkTTL time.Duration
+	// Retention - сколько хранится готовый архив.
+	Retention time.Duration
+}
+
+type DataExportService struct {
+	db           *sql.DB
+	r            *repo.DataExportRepo
+	userRepo     *repo.UserRepo
+	followRepo   *repo.FollowRepo
+	recomRepo    *repo.RecommendationRepo
+	identityRepo *repo.IdentityRepo
+	libraryRepo  *repo.LibraryRepo
+	jobs         *JobService
+	settings     DataExportSettings
+	logger       *slog.Logger
+}
+
+func NewDataExportService(db *sql.DB, r *repo.DataExportRepo, userRepo *repo.UserRepo, followRepo *repo.FollowRepo, recomRepo *repo.RecommendationRepo,
+	identityRepo *repo.IdentityRepo, libraryRepo *repo.LibraryRepo, jobs *JobService, settings DataExportSettings, logger *slog.Logger) *DataExportService {
+	return &DataExportService{
+		db:           db,
+		r:            r,
+		userRepo:     userRepo,
+		followRepo:   followRepo,
+		recomRepo:    recomRepo,
+		identityRepo: identityRepo,
+		libraryRepo:  libraryRepo,
+		jobs:         jobs,
+		settings:     settings,
+		logger:       logger,
+	}
+}
+
+// RequestExport ставит выгрузку в очередь. Если предыдущая выгрузка пользователя
+// еще собирается, возвращается она, чтобы не собирать архив дважды.
+func (s *DataExportService) RequestExport(ctx context.Context, userID int) (dtos.DataExportResponseDTO, error) {
+	ctx, span := tracer.Start(ctx, "DataExportService.RequestExport")
+	defer span.End()
+
+	export, err := s.r.FindUnfinishedExport(ctx, userID)
+	if err == nil {
+		return s.toDTO(export), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return dtos.DataExportResponseDTO{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return dtos.DataExportResponseDTO{}, err
+	}
+	defer tx.Rollback()
+
+	export, err = s.r.WithTx(tx).CreateExport(ctx, userID)
+	if err != nil {
+		return dtos.DataExportResponseDTO{}, err
+	}
+	if err := s.jobs.enqueue(ctx, tx, JobBuildDataExport, dataExportJob{ExportID: export.ID}, time.Time{}); err != nil {
+		return dtos.DataExportResponseDTO{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return dtos.DataExportResponseDTO{}, err
+	}
+
+	s.logger.Info("Data export requested", "user_id", userID, "export_id", export.ID)
+	return s.toDTO(export), nil
+}
+
+func (s *DataExportService) GetExport(ctx context.Context, userID int, exportID string) (dtos.DataExportResponseDTO, error) {
+	ctx, span := tracer.Start(ctx, "DataExportService.GetExport")
+	defer span.End()
+
+	if _, err := uuid.Parse(exportID); err != nil {
+		return dtos.DataExportResponseDTO{}, ErrExportNotFound
+	}
+
+	export, err := s.r.GetExport(ctx, userID, exportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return dtos.DataExportResponseDTO{}, ErrExportNotFound
+	}
+	if err != nil {
+		return dtos.DataExportResponseDTO{}, err
+	}
+
+	return s.toDTO(export), nil
+}
+
+// OpenDownload проверяет подписанную ссылку и открывает архив.
+// Файл закрывает вызывающий.
+func (s *DataExportService) OpenDownload(ctx context.Context, exportID, expires, signature string) (*os.File, models.DataExport, error) {
+	ctx, span := tracer.Start(ctx, "DataExportService.OpenDownload")
+	defer span.End()
+
+	if !utils.VerifyLink(s.settings.LinkSecret, exportID, expires, signature, time.Now()) {
+		return nil, models.DataExport{}, ErrInvalidDownloadLink
+	}
+
+	export, err := s.r.GetExportByID(ctx, exportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, models.DataExport{}, ErrExportNotFound
+	}
+	if err != nil {
+		return nil, models.DataExport{}, err
+	}
+	if export.Status != models.ExportReady {
+		return nil, models.DataExport{}, ErrExportNotFound
+	}
+
+	file, err := os.Open(export.FilePath)
+	if err != nil {
+		return nil, models.DataExport{}, fmt.Errorf("failed to open export archive: %w", err)
+	}
+
+	return file, export, nil
+}
+
+func (s *DataExportService) toDTO(export models.DataExport) dtos.DataExportResponseDTO {
+	response := dtos.DataExportResponseDTO{
+		ID:          export.ID,
+		Status:      string(export.Status),
+		CreatedAt:   export.CreatedAt,
+		CompletedAt: export.CompletedAt,
+		ExpiresAt:   export.ExpiresAt,
+	}
+
+	if export.Status == models.ExportReady && export.ExpiresAt != nil {
+		// Ссылка не должна пережить сам архив
+		linkExpires := time.Now().Add(s.settings.LinkTTL)
+		if export.ExpiresAt.Before(linkExpires) {
+			linkExpires = *export.ExpiresAt
+		}
+		query := url.Values{}
+		query.Set("expires", strconv.FormatInt(linkExpires.Unix(), 10))
+		query.Set("signature", utils.SignLink(s.settings.LinkSecret, export.ID, linkExpires))
+		response.DownloadURL = "/exports/" + export.ID + "/download?" + query.Encode()
+	}
+
+	return response
+}
+
+// BuildExport - обработчик задачи JobBuildDataExport: собирает архив выгрузки.
+func (s *DataExportService) BuildExport(ctx context.Context, job models.Job) error {
+	ctx, span := tracer.Start(ctx, "DataExportService.BuildExport")
+	defer span.End()
+
+	var payload dataExportJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid data export job payload: %w", err)
+	}
+
+	export, err := s.r.StartExport(ctx, payload.ExportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Выгрузка уже собрана или аккаунт стерт
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.build(ctx, export); err != nil {
+		return err
+	}
+
+	s.logger.Info("Data export ready", "export_id", export.ID, "user_id", export.UserID)
+	return nil
+}
+
+// FailExport - OnFailure задачи JobBuildDataExport: выгрузка, которую так и не удалось
+// собрать, помечается как failed, чтобы пользователь мог запросить новую.
+func (s *DataExportService) FailExport(ctx context.Context, job models.Job) {
+	var payload dataExportJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		s.logger.Error("Invalid data export job payload", "error", err, "job_id", job.ID)
+		return
+	}
+
+	if err := s.r.FailExport(ctx, payload.ExportID); err != nil {
+		s.logger.Error("Failed to mark data export as failed", "error", err, "export_id", payload.ExportID)
+		return
+	}
+	s.logger.Warn("Data export failed", "export_id", payload.ExportID, "attempts", job.Attempts)
+}
+
+func (s *DataExportService) build(ctx context.Context, export models.DataExport) error {
+	if err := os.MkdirAll(s.settings.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	path := filepath.Join(s.settings.Dir, export.ID+".zip")
+	if err := s.buildArchive(ctx, export.UserID, path); err != nil {
+		return err
+	}
+
+	return s.r.CompleteExport(ctx, export.ID, path, time.Now().Add(s.settings.Retention))
+}
+
+// RemoveExpired удаляет архивы, срок хранения которых истек. Запускается по расписанию.
+func (s *DataExportService) RemoveExpired(ctx context.Context) {
+	exports, err := s.r.GetExpiredExports(ctx)
+	if err != nil {
+		s.logger.Error("Failed to get expired exports", "error", err)
+		return
+	}
+
+	for _, export := range exports {
+		if err := os.Remove(export.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Error("Failed to remove export archive", "error", err, "export_id", export.ID)
+			continue
+		}
+		if err := s.r.SetExportStatus(ctx, export.ID, models.ExportExpired); err != nil {
+			s.logger.Error("Failed to mark export as expired", "error", err, "export_id", export.ID)
+		}
+	}
+}
@@ -6,30 +6,64 @@ import (
 	"errors"
 	"log/slog"
 
+	"github.com/cobrich/recommendo/models"
 	"github.com/cobrich/recommendo/repo"
 )
 
 var ErrFollowNotFound = errors.New("follow relationship not found")
 
 type FollowService struct {
-	r      *repo.FollowRepo
-	logger *slog.Logger
+	db          *sql.DB
+	r           *repo.FollowRepo
+	userService *UserService
+	audit       *AuditService
+	webhooks    *WebhookService
+	logger      *slog.Logger
 }
 
-func NewFollowService(r *repo.FollowRepo, logger *slog.Logger) *FollowService {
-	return &FollowService{r: r, logger: logger}
+func NewFollowService(db *sql.DB, r *repo.FollowRepo, userService *UserService, audit *AuditService, webhooks *WebhookService,
+	logger *slog.Logger) *FollowService {
+	return &FollowService{db: db, r: r, userService: userService, audit: audit, webhooks: webhooks, logger: logger}
 }
 
 func (s *FollowService) CreateFollow(ctx context.Context, fromId, toID int) error {
-	err := s.r.CreateFollow(ctx, fromId, toID)
+	ctx, span := tracer.Start(ctx, "FollowService.CreateFollow")
+	defer span.End()
+
+	// Подписаться на удаленный или деактивированный аккаунт нельзя
+	if err := s.userService.CheckActiveUser(ctx, toID); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	err = s.r.WithTx(tx).CreateFollow(ctx, fromId, toID)
+	if err != nil {
+		return err
+	}
+	if err := s.webhooks.publishFollow(ctx, tx, fromId, toID); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *FollowService) DeleteFollow(ctx context.Context, fromId, toID int) error {
-	err := s.r.DeleteFollow(ctx, fromId, toID)
+// DeleteFollow удаляет подписку fromId на toID. actorID - кто удаляет: сам подписчик
+// (отписка) или toID (удаление подписчика).
+func (s *FollowService) DeleteFollow(ctx context.Context, actorID, fromId, toID int) error {
+	ctx, span := tracer.Start(ctx, "FollowService.DeleteFollow")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = s.r.WithTx(tx).DeleteFollow(ctx, fromId, toID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			// И переводит ее в понятную для хендлера ошибку бизнес-логики
@@ -38,10 +72,27 @@ func (s *FollowService) DeleteFollow(ctx context.Context, fromId, toID int) erro
 		// Все остальные ошибки пробрасываем как есть (это могут быть ошибки БД)
 		return err
 	}
-	return nil
+
+	// Объект действия - второй участник подписки
+	targetID := toID
+	if actorID == toID {
+		targetID = fromId
+	}
+	err = s.audit.record(ctx, tx, actorID, models.AuditFollowDeleted, models.AuditTargetUser, targetID, map[string]any{
+		"follower_id":  fromId,
+		"following_id": toID,
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (s *FollowService) AreUsersFriends(ctx context.Context, userID1, userID2 int) (bool, error) {
+	ctx, span := tracer.Start(ctx, "FollowService.AreUsersFriends")
+	defer span.End()
+
 	areFriends, err := s.r.AreUsersFriends(ctx, userID1, userID2)
 	if err != nil {
 		return false, err
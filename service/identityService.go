@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/oauth"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/utils"
+)
+
+var (
+	ErrIdentityAlreadyLinked   = errors.New("this external account is already linked to another user")
+	ErrProviderAlreadyLinked   = errors.New("an account of this provider is already linked")
+	ErrIdentityEmailTaken      = errors.New("an account with this email already exists: log in and link the provider instead")
+	ErrIdentityEmailUnverified = errors.New("the provider did not return a verified email")
+	ErrIdentityNotFound        = errors.New("identity not found")
+	ErrLastLoginMethod         = errors.New("cannot unlink the only way to log in to this account")
+)
+
+type IdentityService struct {
+	db          *sql.DB
+	r           *repo.IdentityRepo
+	userRepo    *repo.UserRepo
+	userService *UserService
+	logger      *slog.Logger
+}
+
+func NewIdentityService(db *sql.DB, r *repo.IdentityRepo, userRepo *repo.UserRepo, userService *UserService, logger *slog.Logger) *IdentityService {
+	return &IdentityService{db: db, r: r, userRepo: userRepo, userService: userService, logger: logger}
+}
+
+// LoginWithIdentity входит по внешней учетной записи. Если она еще не привязана,
+// создается новый пользователь. Существующий аккаунт с тем же email автоматически
+// не привязывается: владелец должен войти по паролю и привязать провайдера сам.
+func (s *IdentityService) LoginWithIdentity(ctx context.Context, identity oauth.Identity) (dtos.LoginResponseDTO, error) {
+	ctx, span := tracer.Start(ctx, "IdentityService.LoginWithIdentity")
+	defer span.End()
+
+	// 1. Already linked - just log in
+	linked, err := s.r.FindIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		user, err := s.userRepo.FindUserByIDWithPassword(ctx, linked.UserID)
+		if err != nil {
+			return dtos.LoginResponseDTO{}, fmt.Errorf("failed to load linked user: %w", err)
+		}
+		return s.userService.completeLogin(ctx, user)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return dtos.LoginResponseDTO{}, err
+	}
+
+	// 2. New identity - register a user for it
+	if !identity.EmailVerified {
+		return dtos.LoginResponseDTO{}, ErrIdentityEmailUnverified
+	}
+	email, err := utils.CleanAndValidateEmail(identity.Email)
+	if err != nil {
+		return dtos.LoginResponseDTO{}, ErrIdentityEmailUnverified
+	}
+
+	_, err = s.userRepo.FindUserByEmail(ctx, email)
+	if err == nil {
+		return dtos.LoginResponseDTO{}, ErrIdentityEmailTaken
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return dtos.LoginResponseDTO{}, err
+	}
+
+	user, err := s.registerWithIdentity(ctx, identity, email)
+	if err != nil {
+		return dtos.LoginResponseDTO{}, err
+	}
+
+	s.logger.Info("User registered via external provider", "user_id", user.ID, "provider", identity.Provider)
+	return s.userService.completeLogin(ctx, user)
+}
+
+// registerWithIdentity создает пользователя и привязку в одной транзакции.
+func (s *IdentityService) registerWithIdentity(ctx context.Context, identity oauth.Identity, email string) (models.User, error) {
+	// Пароль никто не знает: вход по паролю для такого аккаунта выключен
+	randomPassword, err := randomHex(32)
+	if err != nil {
+		return models.User{}, err
+	}
+	hashedPassword, err := utils.GetPasswordHash(randomPassword)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	user, err := s.userRepo.WithTx(tx).CreateUser(ctx, models.User{
+		UserName:             userNameFromIdentity(identity, email),
+		Email:                email,
+		PasswordHash:         []byte(hashedPassword),
+		PasswordLoginEnabled: false,
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	_, err = s.r.WithTx(tx).CreateIdentity(ctx, models.UserIdentity{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	err = s.userService.audit.record(ctx, tx, user.ID, models.AuditUserRegistered, models.AuditTargetUser, user.ID, map[string]any{
+		"provider": identity.Provider,
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
+	return user, tx.Commit()
+}
+
+// LinkIdentity привязывает внешнюю учетную запись к уже вошедшему пользователю.
+func (s *IdentityService) LinkIdentity(ctx context.Context, userID int, identity oauth.Identity) (models.UserIdentity, error) {
+	ctx, span := tracer.Start(ctx, "IdentityService.LinkIdentity")
+	defer span.End()
+
+	linked, err := s.r.FindIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		if linked.UserID == userID {
+			return linked, nil
+		}
+		return models.UserIdentity{}, ErrIdentityAlreadyLinked
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.UserIdentity{}, err
+	}
+
+	existing, err := s.r.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return models.UserIdentity{}, err
+	}
+	for _, e := range existing {
+		if e.Provider == identity.Provider {
+			return models.UserIdentity{}, ErrProviderAlreadyLinked
+		}
+	}
+
+	created, err := s.r.CreateIdentity(ctx, models.UserIdentity{
+		UserID:   userID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    strings.ToLower(identity.Email),
+	})
+	if err != nil {
+		return models.UserIdentity{}, err
+	}
+
+	s.logger.Info("External identity linked", "user_id", userID, "provider", identity.Provider)
+	return created, nil
+}
+
+func (s *IdentityService) GetUserIdentities(ctx context.Context, userID int) ([]models.UserIdentity, error) {
+	ctx, span := tracer.Start(ctx, "IdentityService.GetUserIdentities")
+	defer span.End()
+
+	return s.r.GetUserIdentities(ctx, userID)
+}
+
+func (s *IdentityService) UnlinkIdentity(ctx context.Context, userID int, provider string) error {
+	ctx, span := tracer.Start(ctx, "IdentityService.UnlinkIdentity")
+	defer span.End()
+
+	user, err := s.userRepo.FindUserByIDWithPassword(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+
+	identities, err := s.r.GetUserIdentities(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Без пароля последняя привязка - единственный способ войти
+	if !user.PasswordLoginEnabled && len(identities) <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	if err := s.r.DeleteIdentity(ctx, userID, provider); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func userNameFromIdentity(identity oauth.Identity, email string) string {
+	if name := strings.TrimSpace(identity.Name); name != "" {
+		return name
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return local
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/utils"
+)
+
+// maxJobErrorLength - сколько текста ошибки сохраняется в задаче
+const maxJobErrorLength = 1000
+
+// ErrJobTimeout - причина отмены ctx обработчика, не уложившегося в таймаут вида задач.
+var ErrJobTimeout = errors.New("job timed out")
+
+// JobSettings - параметры фоновых задач из конфига.
+type JobSettings struct {
+	// Workers - сколько задач выполняется одновременно.
+	Workers int
+	// PollInterval - как часто проверяется очередь, если обработчик не разбудили.
+	PollInterval time.Duration
+	// Timeout и MaxAttempts - значения по умолчанию для видов задач без своих.
+	Timeout     time.Duration
+	MaxAttempts int
+	// RetryBaseDelay - пауза после первой неудачи; дальше удваивается до MaxRetryDelay.
+	RetryBaseDelay time.Duration
+	MaxRetryDelay  time.Duration
+	// Retention - сколько хранятся законченные задачи.
+	Retention time.Duration
+}
+
+// JobHandler выполняет задачу. Ошибка означает повтор, пока не кончатся попытки.
+// ctx отменяется по таймауту попытки (context.Cause - ErrJobTimeout) и при остановке
+// сервера; во втором случае задача вернется в очередь без потери попытки.
+type JobHandler func(ctx context.Context, job models.Job) error
+
+// JobOptions - параметры вида задач; нулевые поля берутся из JobSettings.
+type JobOptions struct {
+	Timeout     time.Duration
+	MaxAttempts int
+	// RetryBaseDelay и MaxRetryDelay - пауза перед повтором, см. utils.RetryDelay.
+	RetryBaseDelay time.Duration
+	MaxRetryDelay  time.Duration
+	// OnFailure вызывается, когда попытки задачи кончились: после ошибки или таймаута
+	// последней попытки и когда процесс упал во время нее. Необязателен.
+	OnFailure func(ctx context.Context, job models.Job)
+}
+
+type jobKind struct {
+	handler JobHandler
+	options JobOptions
+}
+
+type jobSchedule struct {
+	name     string
+	spec     string
+	schedule utils.CronSchedule
+}
+
+// JobService - очередь фоновых задач в таблице jobs. Сервисы ставят задачи через enqueue
+// в своей транзакции; Run выполняет их и ставит периодические задачи по расписанию.
+// Виды задач и расписания регистрируются до запуска Run.
+type JobService struct {
+	db        *sql.DB
+	r         *repo.JobRepo
+	kinds     map[string]jobKind
+	schedules []jobSchedule
+	settings  JobSettings
+	// wake будит обработчик, когда освободилось место или появилась задача
+	wake   chan struct{}
+	logger *slog.Logger
+}
+
+func NewJobService(db *sql.DB, r *repo.JobRepo, settings JobSettings, logger *slog.Logger) *JobService {
+	return &JobService{
+		db:       db,
+		r:        r,
+		kinds:    make(map[string]jobKind),
+		settings: settings,
+		wake:     make(chan struct{}, 1),
+		logger:   logger,
+	}
+}
+
+// Register добавляет вид задач. Вызывается при старте, до Run.
+func (s *JobService) Register(kind string, options JobOptions, handler JobHandler) {
+	if options.Timeout == 0 {
+		options.Timeout = s.settings.Timeout
+	}
+	if options.MaxAttempts == 0 {
+		options.MaxAttempts = s.settings.MaxAttempts
+	}
+	if options.RetryBaseDelay == 0 {
+		options.RetryBaseDelay = s.settings.RetryBaseDelay
+	}
+	if options.MaxRetryDelay == 0 {
+		options.MaxRetryDelay = max(s.settings.MaxRetryDelay, options.RetryBaseDelay)
+	}
+	s.kinds[kind] = jobKind{handler: handler, options: options}
+}
+
+// Schedule регистрирует периодическую задачу name по расписанию cron (см. utils.ParseCron).
+// Интервальные расписания ("@every 1h") впервые срабатывают сразу после первого запуска.
+// Следующий запуск не ставится, пока не закончился текущий.
+func (s *JobService) Schedule(name, spec string, fn func(ctx context.Context)) error {
+	schedule, err := utils.ParseCron(spec)
+	if err != nil {
+		return err
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return fmt.Errorf("cron spec %q of %s never fires", spec, name)
+	}
+
+	s.Register(name, JobOptions{MaxAttempts: 1}, func(ctx context.Context, _ models.Job) error {
+		fn(ctx)
+		return nil
+	})
+	s.schedules = append(s.schedules, jobSchedule{name: name, spec: spec, schedule: schedule})
+	return nil
+}
+
+// enqueue ставит задачу kind с данными payload на время runAt (нулевое - сразу).
+// tx = nil - вне транзакции. Задача из откатившейся транзакции не выполнится.
+func (s *JobService) enqueue(ctx context.Context, tx *sql.Tx, kind string, payload any, runAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode job payload: %w", err)
+	}
+	if runAt.IsZero() {
+		runAt = time.Now()
+	}
+
+	maxAttempts := s.settings.MaxAttempts
+	if k, ok := s.kinds[kind]; ok {
+		maxAttempts = k.options.MaxAttempts
+	}
+
+	r := s.r
+	if tx != nil {
+		r = r.WithTx(tx)
+	}
+	if _, err := r.Enqueue(ctx, kind, data, maxAttempts, runAt, ""); err != nil {
+		return err
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run выполняет задачи, пока не отменен ctx, и дожидается уже начатых.
+// Запускается один раз при старте через worker.Group.
+func (s *JobService) Run(ctx context.Context) {
+	s.saveSchedules(ctx)
+
+	kinds := make([]string, 0, len(s.kinds))
+	// Задача закреплена за обработчиком, пока не истечет самый долгий таймаут
+	var lease time.Duration
+	for kind, k := range s.kinds {
+		kinds = append(kinds, kind)
+		lease = max(lease, k.options.Timeout)
+	}
+	lease += time.Minute
+
+	ticker := time.NewTicker(s.settings.PollInterval)
+	defer ticker.Stop()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	slots := make(chan struct{}, s.settings.Workers)
+
+	for {
+		s.enqueueScheduled(ctx)
+
+		if free := cap(slots) - len(slots); free > 0 && ctx.Err() == nil {
+			jobs, err := s.r.ClaimJobs(ctx, kinds, free, lease)
+			if err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to claim jobs", "error", err)
+			}
+			for _, job := range jobs {
+				slots <- struct{}{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					s.run(ctx, job)
+					<-slots
+					select {
+					case s.wake <- struct{}{}:
+					default:
+					}
+				}()
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// RemoveFinishedJobs удаляет законченные задачи старше срока хранения.
+func (s *JobService) RemoveFinishedJobs(ctx context.Context) {
+	removed, err := s.r.DeleteFinishedJobs(ctx, time.Now().Add(-s.settings.Retention))
+	if err != nil {
+		s.logger.Error("Failed to remove finished jobs", "error", err)
+		return
+	}
+	if removed > 0 {
+		s.logger.Info("Removed finished jobs", "count", removed)
+	}
+}
+
+func (s *JobService) run(ctx context.Context, job models.Job) {
+	ctx, span := tracer.Start(ctx, "JobService.run "+job.Kind)
+	defer span.End()
+
+	// Обработчик не успел закончить последнюю попытку до истечения блокировки
+	if job.Attempts > job.MaxAttempts {
+		s.finish(ctx, job, errors.New("job lock expired on the last attempt"))
+		return
+	}
+
+	k := s.kinds[job.Kind]
+	jobCtx, cancel := context.WithTimeoutCause(ctx, k.options.Timeout, ErrJobTimeout)
+	err := k.handler(jobCtx, job)
+	if err != nil && context.Cause(jobCtx) == ErrJobTimeout {
+		err = fmt.Errorf("%w: %w", ErrJobTimeout, err)
+	}
+	cancel()
+
+	if ctx.Err() != nil {
+		// Сервер останавливается: задача будет выполнена после перезапуска
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := s.r.ReleaseJob(releaseCtx, job.ID); err != nil {
+			s.logger.Error("Failed to release interrupted job", "error", err, "job_id", job.ID, "kind", job.Kind)
+		}
+		return
+	}
+	s.finish(ctx, job, err)
+}
+
+func (s *JobService) finish(ctx context.Context, job models.Job, err error) {
+	if err == nil {
+		if err := s.r.CompleteJob(ctx, job.ID); err != nil {
+			s.logger.Error("Failed to complete job", "error", err, "job_id", job.ID, "kind", job.Kind)
+		}
+		return
+	}
+
+	options := s.kinds[job.Kind].options
+	var retryAt *time.Time
+	if !job.LastAttempt() {
+		next := time.Now().Add(utils.RetryDelay(options.RetryBaseDelay, options.MaxRetryDelay, job.Attempts))
+		retryAt = &next
+	}
+	s.logger.Error("Job failed", "error", err, "job_id", job.ID, "kind", job.Kind,
+		"attempt", job.Attempts, "max_attempts", job.MaxAttempts, "will_retry", retryAt != nil)
+
+	lastError := err.Error()
+	if len(lastError) > maxJobErrorLength {
+		lastError = lastError[:maxJobErrorLength]
+	}
+	if err := s.r.FailJob(ctx, job.ID, lastError, retryAt); err != nil {
+		s.logger.Error("Failed to record job failure", "error", err, "job_id", job.ID, "kind", job.Kind)
+	}
+
+	if retryAt == nil && options.OnFailure != nil {
+		options.OnFailure(ctx, job)
+	}
+}
+
+func (s *JobService) saveSchedules(ctx context.Context) {
+	now := time.Now()
+	for _, sch := range s.schedules {
+		next := sch.schedule.Next(now)
+		if strings.HasPrefix(sch.spec, "@every ") {
+			next = now
+		}
+		if err := s.r.SaveSchedule(ctx, sch.name, sch.spec, next); err != nil {
+			s.logger.Error("Failed to save job schedule", "error", err, "schedule", sch.name)
+		}
+	}
+}
+
+// enqueueScheduled ставит периодические задачи, время которых пришло. Перенос
+// расписания и постановка задачи идут в одной транзакции, поэтому при нескольких
+// экземплярах задача ставится один раз.
+func (s *JobService) enqueueScheduled(ctx context.Context) {
+	for _, sch := range s.schedules {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.enqueueSchedule(ctx, sch); err != nil {
+			s.logger.Error("Failed to enqueue scheduled job", "error", err, "schedule", sch.name)
+		}
+	}
+}
+
+func (s *JobService) enqueueSchedule(ctx context.Context, sch jobSchedule) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	jobs := s.r.WithTx(tx)
+	due, err := jobs.AdvanceSchedule(ctx, sch.name, sch.schedule.Next(time.Now()))
+	if err != nil || !due {
+		return err
+	}
+	// unique_key = имя расписания: пока идет прошлый запуск, новый не ставится
+	if _, err := jobs.Enqueue(ctx, sch.name, []byte("{}"), 1, time.Now(), sch.name); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
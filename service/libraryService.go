@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+)
+
+var (
+	ErrInvalidLibraryStatus = errors.New("invalid library status: must be planned, in_progress, completed or dropped")
+	ErrInvalidProgress      = errors.New("progress must not be negative")
+	ErrInvalidScore         = errors.New("score must be between 1 and 10")
+	ErrInvalidLibraryDate   = errors.New("dates must be in YYYY-MM-DD format")
+	ErrInvalidLibraryDates  = errors.New("started_on must not be after finished_on")
+	ErrLibraryEntryNotFound = errors.New("media item is not in the library")
+)
+
+type LibraryService struct {
+	db        *sql.DB
+	r         *repo.LibraryRepo
+	mediaRepo *repo.MediaRepo
+	recomRepo *repo.RecommendationRepo
+	logger    *slog.Logger
+}
+
+func NewLibraryService(db *sql.DB, r *repo.LibraryRepo, mediaRepo *repo.MediaRepo, recomRepo *repo.RecommendationRepo, logger *slog.Logger) *LibraryService {
+	return &LibraryService{db: db, r: r, mediaRepo: mediaRepo, recomRepo: recomRepo, logger: logger}
+}
+
+// GetLibrary возвращает библиотеку пользователя. Пустой status - все записи.
+func (s *LibraryService) GetLibrary(ctx context.Context, userID int, status models.LibraryStatus, page, limit int) (*dtos.PaginatedResponseDTO[models.UserMedia], error) {
+	ctx, span := tracer.Start(ctx, "LibraryService.GetLibrary")
+	defer span.End()
+
+	if status != "" && !status.Valid() {
+		return nil, ErrInvalidLibraryStatus
+	}
+
+	entries, total, err := s.r.GetEntries(ctx, userID, status, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(entries, total, page, limit), nil
+}
+
+func (s *LibraryService) GetEntry(ctx context.Context, userID, mediaID int) (models.UserMedia, error) {
+	ctx, span := tracer.Start(ctx, "LibraryService.GetEntry")
+	defer span.End()
+
+	entry, err := s.r.GetEntry(ctx, userID, mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserMedia{}, ErrLibraryEntryNotFound
+	}
+	if err != nil {
+		return models.UserMedia{}, err
+	}
+	return entry, nil
+}
+
+// SaveEntry добавляет медиа в библиотеку или обновляет запись. При статусе
+// completed полученные рекомендации этого медиа отмечаются выполненными
+// в той же транзакции.
+func (s *LibraryService) SaveEntry(ctx context.Context, userID, mediaID int, body dtos.SaveLibraryEntryDTO) (models.UserMedia, error) {
+	ctx, span := tracer.Start(ctx, "LibraryService.SaveEntry")
+	defer span.End()
+
+	entry := models.UserMedia{
+		UserID:   userID,
+		Status:   models.LibraryStatus(body.Status),
+		Progress: body.Progress,
+		Score:    body.Score,
+	}
+	if !entry.Status.Valid() {
+		return models.UserMedia{}, ErrInvalidLibraryStatus
+	}
+	if entry.Progress < 0 {
+		return models.UserMedia{}, ErrInvalidProgress
+	}
+	if entry.Score != nil && (*entry.Score < 1 || *entry.Score > 10) {
+		return models.UserMedia{}, ErrInvalidScore
+	}
+
+	var err error
+	if entry.StartedOn, err = parseLibraryDate(body.StartedOn); err != nil {
+		return models.UserMedia{}, err
+	}
+	if entry.FinishedOn, err = parseLibraryDate(body.FinishedOn); err != nil {
+		return models.UserMedia{}, err
+	}
+	// Дата окончания по умолчанию - день, когда медиа отметили завершенным
+	if entry.Status == models.LibraryCompleted && entry.FinishedOn == nil {
+		today, _ := time.Parse(time.DateOnly, time.Now().UTC().Format(time.DateOnly))
+		entry.FinishedOn = &today
+	}
+	if entry.StartedOn != nil && entry.FinishedOn != nil && entry.StartedOn.After(*entry.FinishedOn) {
+		return models.UserMedia{}, ErrInvalidLibraryDates
+	}
+
+	entry.Media, err = s.mediaRepo.GetMedia(ctx, mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserMedia{}, ErrMediaNotFound
+	}
+	if err != nil {
+		return models.UserMedia{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.UserMedia{}, err
+	}
+	defer tx.Rollback()
+
+	entry, err = s.r.WithTx(tx).SaveEntry(ctx, entry)
+	if err != nil {
+		return models.UserMedia{}, err
+	}
+
+	if entry.Status == models.LibraryCompleted {
+		completed, err := s.recomRepo.WithTx(tx).CompleteRecommendationsForMedia(ctx, userID, mediaID)
+		if err != nil {
+			return models.UserMedia{}, err
+		}
+		if completed > 0 {
+			s.logger.Debug("Recommendations completed", "user_id", userID, "media_id", mediaID, "count", completed)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.UserMedia{}, err
+	}
+	return entry, nil
+}
+
+func (s *LibraryService) RemoveEntry(ctx context.Context, userID, mediaID int) error {
+	ctx, span := tracer.Start(ctx, "LibraryService.RemoveEntry")
+	defer span.End()
+
+	err := s.r.DeleteEntry(ctx, userID, mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrLibraryEntryNotFound
+	}
+	return err
+}
+
+func parseLibraryDate(value *string) (*time.Time, error) {
+	if value == nil || *value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse(time.DateOnly, *value)
+	if err != nil {
+		return nil, ErrInvalidLibraryDate
+	}
+	return &date, nil
+}
@@ -2,25 +2,344 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
+	"github.com/cobrich/recommendo/dtos"
 	"github.com/cobrich/recommendo/models"
 	"github.com/cobrich/recommendo/repo"
 )
 
+var (
+	ErrInvalidMediaType     = errors.New("invalid media type: must be film, anime, book, game or series")
+	ErrInvalidMediaMetadata = errors.New("invalid media metadata")
+)
+
+// Ограничения на сведения о медиа (длины - в символах).
+const (
+	maxMediaTitleLength       = 300
+	maxMediaDescriptionLength = 5000
+	maxAlternativeTitles      = 20
+	maxMediaGenres            = 10
+	maxMediaTags              = 30
+	maxMediaLabelLength       = 50
+	maxMediaPlatforms         = 20
+	maxMediaCreators          = 50
+	maxCreatorNameLength      = 200
+	maxExternalIDLength       = 100
+	maxCoverURLLength         = 2048
+)
+
 type MediaService struct {
-	r      *repo.MediaRepo
-	logger *slog.Logger
+	db          *sql.DB
+	r           *repo.MediaRepo
+	recomRepo   *repo.RecommendationRepo
+	libraryRepo *repo.LibraryRepo
+	taxonomy    *TaxonomyService
+	logger      *slog.Logger
 }
 
-func NewMediaService(r *repo.MediaRepo, logger *slog.Logger) *MediaService {
-	return &MediaService{r: r, logger: logger}
+func NewMediaService(db *sql.DB, r *repo.MediaRepo, recomRepo *repo.RecommendationRepo, libraryRepo *repo.LibraryRepo,
+	taxonomy *TaxonomyService, logger *slog.Logger) *MediaService {
+	return &MediaService{db: db, r: r, recomRepo: recomRepo, libraryRepo: libraryRepo, taxonomy: taxonomy, logger: logger}
 }
 
 func (s *MediaService) FindMedia(ctx context.Context, mtype, name string) ([]models.MediaItem, error) {
+	ctx, span := tracer.Start(ctx, "MediaService.FindMedia")
+	defer span.End()
+
 	media_items, err := s.r.FindMedia(ctx, mtype, name)
 	if err != nil {
 		return nil, err
 	}
 	return media_items, nil
 }
+
+// GetMediaDetails возвращает медиа вместе с тем, что о нем знает пользователь:
+// рекомендации его друзей, его собственные рекомендации, запись в библиотеке
+// и общую сводку оценок.
+func (s *MediaService) GetMediaDetails(ctx context.Context, userID, mediaID int) (models.MediaDetails, error) {
+	ctx, span := tracer.Start(ctx, "MediaService.GetMediaDetails")
+	defer span.End()
+
+	media, err := s.r.GetMedia(ctx, mediaID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.MediaDetails{}, ErrMediaNotFound
+	}
+	if err != nil {
+		return models.MediaDetails{}, err
+	}
+
+	details := models.MediaDetails{
+		Media:                 media,
+		FriendRecommendations: []models.MediaRecommendation{},
+		SentByMe:              []models.MediaRecommendation{},
+		ReceivedByMe:          []models.MediaRecommendation{},
+	}
+
+	recommendations, err := s.recomRepo.GetMediaRecommendationsForUser(ctx, mediaID, userID)
+	if err != nil {
+		return models.MediaDetails{}, err
+	}
+	for _, rec := range recommendations {
+		switch {
+		case rec.From.ID == userID:
+			details.SentByMe = append(details.SentByMe, rec)
+		case rec.To.ID == userID:
+			details.ReceivedByMe = append(details.ReceivedByMe, rec)
+		default:
+			details.FriendRecommendations = append(details.FriendRecommendations, rec)
+		}
+	}
+
+	entry, err := s.libraryRepo.GetEntry(ctx, userID, mediaID)
+	switch {
+	case err == nil:
+		details.Library = &entry
+	case !errors.Is(err, sql.ErrNoRows):
+		return models.MediaDetails{}, err
+	}
+
+	if details.Ratings, err = s.libraryRepo.GetMediaRatings(ctx, mediaID); err != nil {
+		return models.MediaDetails{}, err
+	}
+	if details.RecommendedToCount, err = s.recomRepo.CountMediaRecipients(ctx, mediaID); err != nil {
+		return models.MediaDetails{}, err
+	}
+
+	return details, nil
+}
+
+// CreateMedia добавляет медиа в каталог вместе со списком создателей, жанрами и тегами.
+func (s *MediaService) CreateMedia(ctx context.Context, body dtos.SaveMediaDTO) (models.MediaItem, error) {
+	ctx, span := tracer.Start(ctx, "MediaService.CreateMedia")
+	defer span.End()
+
+	media, err := mediaFromDTO(body)
+	if err != nil {
+		return models.MediaItem{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MediaItem{}, err
+	}
+	defer tx.Rollback()
+
+	created, err := s.r.WithTx(tx).CreateMedia(ctx, media)
+	if err != nil {
+		return models.MediaItem{}, err
+	}
+	if err := s.r.WithTx(tx).ReplaceCreators(ctx, created.ID, created.Creators); err != nil {
+		return models.MediaItem{}, err
+	}
+	if err := s.taxonomy.setMediaTermsTx(ctx, tx, created.ID, media.Genres, media.Tags); err != nil {
+		return models.MediaItem{}, err
+	}
+
+	created, err = s.r.WithTx(tx).GetMedia(ctx, created.ID)
+	if err != nil {
+		return models.MediaItem{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MediaItem{}, err
+	}
+	return created, nil
+}
+
+// UpdateMedia целиком заменяет сведения о медиа, включая создателей, жанры и теги.
+func (s *MediaService) UpdateMedia(ctx context.Context, mediaID int, body dtos.SaveMediaDTO) (models.MediaItem, error) {
+	ctx, span := tracer.Start(ctx, "MediaService.UpdateMedia")
+	defer span.End()
+
+	media, err := mediaFromDTO(body)
+	if err != nil {
+		return models.MediaItem{}, err
+	}
+	media.ID = mediaID
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.MediaItem{}, err
+	}
+	defer tx.Rollback()
+
+	if err := s.r.WithTx(tx).UpdateMedia(ctx, media); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.MediaItem{}, ErrMediaNotFound
+		}
+		return models.MediaItem{}, err
+	}
+	if err := s.r.WithTx(tx).ReplaceCreators(ctx, mediaID, media.Creators); err != nil {
+		return models.MediaItem{}, err
+	}
+	if err := s.taxonomy.setMediaTermsTx(ctx, tx, mediaID, media.Genres, media.Tags); err != nil {
+		return models.MediaItem{}, err
+	}
+
+	updated, err := s.r.WithTx(tx).GetMedia(ctx, mediaID)
+	if err != nil {
+		return models.MediaItem{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.MediaItem{}, err
+	}
+	return updated, nil
+}
+
+// mediaFromDTO нормализует и проверяет сведения о медиа с учетом его типа.
+func mediaFromDTO(body dtos.SaveMediaDTO) (models.MediaItem, error) {
+	media := models.MediaItem{
+		Type:          models.MediaType(body.Type),
+		Name:          strings.TrimSpace(body.Name),
+		Year:          body.Year,
+		Author:        strings.TrimSpace(body.Author),
+		OriginalTitle: strings.TrimSpace(body.OriginalTitle),
+		Description:   strings.TrimSpace(body.Description),
+		CoverURL:      strings.TrimSpace(body.CoverURL),
+		Metadata: models.MediaMetadata{
+			RuntimeMinutes: body.RuntimeMinutes,
+			EpisodeCount:   body.EpisodeCount,
+			PageCount:      body.PageCount,
+		},
+		Creators: models.MediaCreators{},
+	}
+
+	if !media.Type.Valid() {
+		return models.MediaItem{}, ErrInvalidMediaType
+	}
+	if media.Name == "" || utf8.RuneCountInString(media.Name) > maxMediaTitleLength {
+		return models.MediaItem{}, metadataError("name is required and must be at most %d characters", maxMediaTitleLength)
+	}
+	if media.Year < 0 || media.Year > time.Now().Year()+10 {
+		return models.MediaItem{}, metadataError("year is out of range")
+	}
+	if utf8.RuneCountInString(media.OriginalTitle) > maxMediaTitleLength {
+		return models.MediaItem{}, metadataError("original_title must be at most %d characters", maxMediaTitleLength)
+	}
+	if utf8.RuneCountInString(media.Description) > maxMediaDescriptionLength {
+		return models.MediaItem{}, metadataError("description must be at most %d characters", maxMediaDescriptionLength)
+	}
+	if media.CoverURL != "" {
+		cover, err := url.Parse(media.CoverURL)
+		if err != nil || (cover.Scheme != "http" && cover.Scheme != "https") || cover.Host == "" || len(media.CoverURL) > maxCoverURLLength {
+			return models.MediaItem{}, metadataError("cover_url must be an absolute http(s) URL")
+		}
+	}
+
+	var err error
+	if media.Metadata.AlternativeTitles, err = normalizeList("alternative_titles", body.AlternativeTitles, maxAlternativeTitles, maxMediaTitleLength); err != nil {
+		return models.MediaItem{}, err
+	}
+	// Жанры и теги пока остаются названиями: в слаги их переводит setMediaTermsTx
+	if media.Genres, err = normalizeList("genres", body.Genres, maxMediaGenres, maxMediaLabelLength); err != nil {
+		return models.MediaItem{}, err
+	}
+	if media.Tags, err = normalizeList("tags", body.Tags, maxMediaTags, maxMediaLabelLength); err != nil {
+		return models.MediaItem{}, err
+	}
+	if media.Metadata.Platforms, err = normalizeList("platforms", body.Platforms, maxMediaPlatforms, maxMediaLabelLength); err != nil {
+		return models.MediaItem{}, err
+	}
+
+	if err := validateTypeSpecificFields(media.Type, media.Metadata); err != nil {
+		return models.MediaItem{}, err
+	}
+
+	if len(body.Creators) > maxMediaCreators {
+		return models.MediaItem{}, metadataError("at most %d creators are allowed", maxMediaCreators)
+	}
+	roles := media.Type.CreatorRoles()
+	for _, creator := range body.Creators {
+		name := strings.TrimSpace(creator.Name)
+		role := models.CreatorRole(creator.Role)
+		if name == "" || utf8.RuneCountInString(name) > maxCreatorNameLength {
+			return models.MediaItem{}, metadataError("creator name is required and must be at most %d characters", maxCreatorNameLength)
+		}
+		if !slices.Contains(roles, role) {
+			return models.MediaItem{}, metadataError("creator role %q is not valid for %s", creator.Role, media.Type)
+		}
+		media.Creators = append(media.Creators, models.MediaCreator{Name: name, Role: role})
+	}
+
+	if len(body.ExternalIDs) > 0 {
+		sources := media.Type.ExternalIDSources()
+		media.Metadata.ExternalIDs = make(map[string]string, len(body.ExternalIDs))
+		for source, id := range body.ExternalIDs {
+			source = strings.ToLower(strings.TrimSpace(source))
+			id = strings.TrimSpace(id)
+			if !slices.Contains(sources, source) {
+				return models.MediaItem{}, metadataError("external id source %q is not valid for %s", source, media.Type)
+			}
+			if id == "" || len(id) > maxExternalIDLength {
+				return models.MediaItem{}, metadataError("external id for %s must be 1 to %d characters", source, maxExternalIDLength)
+			}
+			media.Metadata.ExternalIDs[source] = id
+		}
+	}
+
+	// Основной создатель подставляется в author, если его не указали явно
+	if media.Author == "" {
+		var primary []string
+		for _, creator := range media.Creators {
+			if creator.Role == roles[0] {
+				primary = append(primary, creator.Name)
+			}
+		}
+		media.Author = strings.Join(primary, ", ")
+	}
+
+	return media, nil
+}
+
+// validateTypeSpecificFields запрещает поля, которые не имеют смысла для типа медиа.
+func validateTypeSpecificFields(mediaType models.MediaType, metadata models.MediaMetadata) error {
+	if metadata.RuntimeMinutes < 0 || metadata.EpisodeCount < 0 || metadata.PageCount < 0 {
+		return metadataError("runtime_minutes, episode_count and page_count must not be negative")
+	}
+	if metadata.RuntimeMinutes > 0 && mediaType != models.TypeFilm {
+		return metadataError("runtime_minutes applies only to films")
+	}
+	if metadata.EpisodeCount > 0 && mediaType != models.TypeAnime && mediaType != models.TypeSeries {
+		return metadataError("episode_count applies only to anime and series")
+	}
+	if metadata.PageCount > 0 && mediaType != models.TypeBook {
+		return metadataError("page_count applies only to books")
+	}
+	if len(metadata.Platforms) > 0 && mediaType != models.TypeGame {
+		return metadataError("platforms apply only to games")
+	}
+	return nil
+}
+
+// normalizeList убирает пробелы и дубликаты.
+func normalizeList(field string, values []string, maxItems, maxLength int) ([]string, error) {
+	if len(values) > maxItems {
+		return nil, metadataError("at most %d %s are allowed", maxItems, field)
+	}
+
+	var result []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || utf8.RuneCountInString(value) > maxLength {
+			return nil, metadataError("%s entries must be 1 to %d characters", field, maxLength)
+		}
+		if !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result, nil
+}
+
+func metadataError(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMediaMetadata, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"unicode/utf8"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+)
+
+var (
+	ErrInvalidReportTarget  = errors.New("invalid report target: must be user, recommendation or media")
+	ErrInvalidReportReason  = errors.New("invalid report reason: must be spam, harassment, inappropriate, impersonation or other")
+	ErrInvalidReportStatus  = errors.New("invalid report status: must be open, resolved or dismissed")
+	ErrReportDetailsTooLong = errors.New("report details are too long")
+	ErrReportTargetNotFound = errors.New("reported object not found")
+	ErrCannotReportSelf     = errors.New("cannot report your own account")
+	ErrAlreadyReported      = errors.New("you have already reported this and the report is awaiting review")
+	ErrReportNotFound       = errors.New("report not found")
+	ErrReportClosed         = errors.New("report is already closed")
+	ErrInvalidAssignee      = errors.New("reports can only be assigned to moderators and administrators")
+	ErrInvalidReportAction  = errors.New("this action cannot be applied to the reported object")
+)
+
+// maxReportDetailsLength - предел длины пояснения к жалобе (в символах).
+const maxReportDetailsLength = 2000
+
+// Решения по жалобе. Действие выполняется через сервис, который отвечает за объект,
+// и записывается в журнал вместе с закрытием жалобы в одной транзакции.
+const (
+	// ReportActionNone - жалоба обоснована, но мер не требуется (или они приняты вручную)
+	ReportActionNone = "none"
+	// ReportActionSuspendUser блокирует пользователя, на которого (или на чью рекомендацию) пожаловались
+	ReportActionSuspendUser = "suspend_user"
+	// ReportActionRemoveContent удаляет рекомендацию
+	ReportActionRemoveContent = "remove_content"
+)
+
+type ModerationService struct {
+	db              *sql.DB
+	r               *repo.ModerationRepo
+	userRepo        *repo.UserRepo
+	mediaRepo       *repo.MediaRepo
+	recomRepo       *repo.RecommendationRepo
+	admin           *AdminService
+	recommendations *RecommendationService
+	logger          *slog.Logger
+}
+
+func NewModerationService(db *sql.DB, r *repo.ModerationRepo, userRepo *repo.UserRepo, mediaRepo *repo.MediaRepo, recomRepo *repo.RecommendationRepo,
+	admin *AdminService, recommendations *RecommendationService, logger *slog.Logger) *ModerationService {
+	return &ModerationService{
+		db:              db,
+		r:               r,
+		userRepo:        userRepo,
+		mediaRepo:       mediaRepo,
+		recomRepo:       recomRepo,
+		admin:           admin,
+		recommendations: recommendations,
+		logger:          logger,
+	}
+}
+
+// CreateReport принимает жалобу пользователя и ставит ее в очередь модераторов.
+func (s *ModerationService) CreateReport(ctx context.Context, reporterID int, dto dtos.CreateReportDTO) (models.Report, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.CreateReport")
+	defer span.End()
+
+	report := models.Report{
+		ReporterID: &reporterID,
+		TargetType: models.ReportTargetType(dto.TargetType),
+		TargetID:   dto.TargetID,
+		Reason:     models.ReportReason(dto.Reason),
+		Details:    dto.Details,
+	}
+
+	switch report.Reason {
+	case models.ReportReasonSpam, models.ReportReasonHarassment, models.ReportReasonInappropriate,
+		models.ReportReasonImpersonation, models.ReportReasonOther:
+	default:
+		return models.Report{}, ErrInvalidReportReason
+	}
+	if utf8.RuneCountInString(report.Details) > maxReportDetailsLength {
+		return models.Report{}, ErrReportDetailsTooLong
+	}
+	if report.TargetType == models.ReportTargetUser && report.TargetID == reporterID {
+		return models.Report{}, ErrCannotReportSelf
+	}
+	if err := s.checkTargetExists(ctx, report.TargetType, report.TargetID); err != nil {
+		return models.Report{}, err
+	}
+
+	created, err := s.r.CreateReport(ctx, report)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Report{}, ErrAlreadyReported
+	}
+	if err != nil {
+		return models.Report{}, err
+	}
+
+	s.logger.Info("Report created", "report_id", created.ID, "target_type", created.TargetType, "target_id", created.TargetID)
+	return created, nil
+}
+
+// checkTargetExists проверяет, что объект жалобы существует. Скрытые пользователи
+// считаются несуществующими: пожаловаться можно только на то, что видно.
+func (s *ModerationService) checkTargetExists(ctx context.Context, targetType models.ReportTargetType, targetID int) error {
+	var err error
+	switch targetType {
+	case models.ReportTargetUser:
+		_, err = s.userRepo.GetUserByID(ctx, targetID)
+	case models.ReportTargetRecommendation:
+		_, err = s.recomRepo.GetRecommendationByID(ctx, targetID)
+	case models.ReportTargetMedia:
+		_, err = s.mediaRepo.GetMedia(ctx, targetID)
+	default:
+		return ErrInvalidReportTarget
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrReportTargetNotFound
+	}
+	return err
+}
+
+func (s *ModerationService) ListReports(ctx context.Context, filter repo.ReportFilter, page, limit int) (*dtos.PaginatedResponseDTO[models.Report], error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.ListReports")
+	defer span.End()
+
+	switch filter.Status {
+	case "", models.ReportOpen, models.ReportResolved, models.ReportDismissed:
+	default:
+		return nil, ErrInvalidReportStatus
+	}
+	switch filter.TargetType {
+	case "", models.ReportTargetUser, models.ReportTargetRecommendation, models.ReportTargetMedia:
+	default:
+		return nil, ErrInvalidReportTarget
+	}
+
+	reports, total, err := s.r.ListReports(ctx, filter, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(reports, total, page, limit), nil
+}
+
+// GetReport возвращает жалобу вместе с историей действий по ней.
+func (s *ModerationService) GetReport(ctx context.Context, reportID int) (models.Report, []models.ModerationAction, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.GetReport")
+	defer span.End()
+
+	report, err := s.r.GetReport(ctx, reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Report{}, nil, ErrReportNotFound
+	}
+	if err != nil {
+		return models.Report{}, nil, err
+	}
+
+	actions, err := s.r.GetReportActions(ctx, reportID)
+	if err != nil {
+		return models.Report{}, nil, err
+	}
+	return report, actions, nil
+}
+
+// AssignReport назначает открытую жалобу модератору (по умолчанию - себе).
+func (s *ModerationService) AssignReport(ctx context.Context, actorID, reportID, assigneeID int) (models.Report, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.AssignReport")
+	defer span.End()
+
+	if assigneeID == 0 {
+		assigneeID = actorID
+	}
+	assignee, err := s.userRepo.FindUserByIDWithPassword(ctx, assigneeID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (!assignee.Role.IsStaff() || assignee.SuspendedAt != nil)) {
+		return models.Report{}, ErrInvalidAssignee
+	}
+	if err != nil {
+		return models.Report{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Report{}, err
+	}
+	defer tx.Rollback()
+
+	report, err := s.r.WithTx(tx).AssignReport(ctx, reportID, assigneeID)
+	if err != nil {
+		return models.Report{}, s.closedReportError(ctx, reportID, err)
+	}
+	err = s.r.WithTx(tx).RecordAction(ctx, models.ModerationAction{
+		ModeratorID: actorID,
+		ReportID:    &report.ID,
+		Action:      models.ActionAssignReport,
+		TargetType:  report.TargetType,
+		TargetID:    report.TargetID,
+	})
+	if err != nil {
+		return models.Report{}, err
+	}
+
+	return report, tx.Commit()
+}
+
+// ResolveReport закрывает жалобу как обоснованную и применяет выбранное действие.
+func (s *ModerationService) ResolveReport(ctx context.Context, actorID int, actorRole models.Role, reportID int, action, note string) (models.Report, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.ResolveReport")
+	defer span.End()
+
+	report, err := s.r.GetReport(ctx, reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Report{}, ErrReportNotFound
+	}
+	if err != nil {
+		return models.Report{}, err
+	}
+	if report.Status != models.ReportOpen {
+		return models.Report{}, ErrReportClosed
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Report{}, err
+	}
+	defer tx.Rollback()
+
+	suspendedUserID := 0
+	switch action {
+	case "", ReportActionNone:
+	case ReportActionSuspendUser:
+		suspendedUserID, err = s.reportedUserID(ctx, report)
+		if err != nil {
+			return models.Report{}, err
+		}
+		if err := s.admin.suspendUserTx(ctx, tx, actorID, actorRole, suspendedUserID, note, &report.ID); err != nil {
+			return models.Report{}, err
+		}
+	case ReportActionRemoveContent:
+		if report.TargetType != models.ReportTargetRecommendation {
+			return models.Report{}, ErrInvalidReportAction
+		}
+		if err := s.recommendations.removeRecommendationTx(ctx, tx, actorID, report.TargetID); err != nil {
+			return models.Report{}, err
+		}
+		err = s.r.WithTx(tx).RecordAction(ctx, models.ModerationAction{
+			ModeratorID: actorID,
+			ReportID:    &report.ID,
+			Action:      models.ActionRemoveRecommendation,
+			TargetType:  report.TargetType,
+			TargetID:    report.TargetID,
+			Note:        note,
+		})
+		if err != nil {
+			return models.Report{}, err
+		}
+	default:
+		return models.Report{}, ErrInvalidReportAction
+	}
+
+	closed, err := s.closeReportTx(ctx, tx, actorID, report, models.ReportResolved, models.ActionResolveReport, note)
+	if err != nil {
+		return models.Report{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Report{}, err
+	}
+
+	if suspendedUserID != 0 {
+		if err := s.admin.endSuspendedSessions(ctx, actorID, suspendedUserID); err != nil {
+			return models.Report{}, err
+		}
+	}
+
+	s.logger.Info("Report resolved", "report_id", reportID, "action", action, "moderator_id", actorID)
+	return closed, nil
+}
+
+// DismissReport закрывает жалобу как необоснованную.
+func (s *ModerationService) DismissReport(ctx context.Context, actorID, reportID int, note string) (models.Report, error) {
+	ctx, span := tracer.Start(ctx, "ModerationService.DismissReport")
+	defer span.End()
+
+	report, err := s.r.GetReport(ctx, reportID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Report{}, ErrReportNotFound
+	}
+	if err != nil {
+		return models.Report{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Report{}, err
+	}
+	defer tx.Rollback()
+
+	closed, err := s.closeReportTx(ctx, tx, actorID, report, models.ReportDismissed, models.ActionDismissReport, note)
+	if err != nil {
+		return models.Report{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.Report{}, err
+	}
+
+	s.logger.Info("Report dismissed", "report_id", reportID, "moderator_id", actorID)
+	return closed, nil
+}
+
+func (s *ModerationService) closeReportTx(ctx context.Context, tx *sql.Tx, actorID int, report models.Report,
+	status models.ReportStatus, action models.ModerationActionType, note string) (models.Report, error) {
+	closed, err := s.r.WithTx(tx).CloseReport(ctx, report.ID, actorID, status, note)
+	if err != nil {
+		return models.Report{}, s.closedReportError(ctx, report.ID, err)
+	}
+
+	err = s.r.WithTx(tx).RecordAction(ctx, models.ModerationAction{
+		ModeratorID: actorID,
+		ReportID:    &report.ID,
+		Action:      action,
+		TargetType:  report.TargetType,
+		TargetID:    report.TargetID,
+		Note:        note,
+	})
+	if err != nil {
+		return models.Report{}, err
+	}
+	return closed, nil
+}
+
+// closedReportError уточняет sql.ErrNoRows от условного UPDATE: жалобы нет или она уже закрыта.
+func (s *ModerationService) closedReportError(ctx context.Context, reportID int, err error) error {
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if _, err := s.r.GetReport(ctx, reportID); errors.Is(err, sql.ErrNoRows) {
+		return ErrReportNotFound
+	}
+	return ErrReportClosed
+}
+
+// reportedUserID - пользователь, отвечающий за объект жалобы: сам пользователь
+// или автор рекомендации.
+func (s *ModerationService) reportedUserID(ctx context.Context, report models.Report) (int, error) {
+	switch report.TargetType {
+	case models.ReportTargetUser:
+		return report.TargetID, nil
+	case models.ReportTargetRecommendation:
+		recommendation, err := s.recomRepo.GetRecommendationByID(ctx, report.TargetID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrReportTargetNotFound
+		}
+		if err != nil {
+			return 0, err
+		}
+		// У анонимизированной рекомендации автора уже нет
+		if recommendation.FromUserID == 0 {
+			return 0, ErrInvalidReportAction
+		}
+		return recommendation.FromUserID, nil
+	default:
+		return 0, ErrInvalidReportAction
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+)
+
+var ErrInvalidRankingWindow = errors.New("invalid window: must be 24h, 7d or 30d (popular also supports all)")
+
+// DefaultRankingWindow - окно, если в запросе его не указали.
+const DefaultRankingWindow = models.Window7d
+
+// RankingService пересчитывает рейтинги медиа по расписанию и отдает их из кэш-таблицы.
+type RankingService struct {
+	db     *sql.DB
+	r      *repo.RankingRepo
+	size   int
+	logger *slog.Logger
+}
+
+// size - сколько медиа каждого типа попадает в рейтинг.
+func NewRankingService(db *sql.DB, r *repo.RankingRepo, size int, logger *slog.Logger) *RankingService {
+	return &RankingService{db: db, r: r, size: size, logger: logger}
+}
+
+// RefreshRankings пересчитывает все рейтинги в одной транзакции, чтобы читатели
+// видели либо старые, либо новые данные целиком. Запускается по расписанию.
+func (s *RankingService) RefreshRankings(ctx context.Context) {
+	ctx, span := tracer.Start(ctx, "RankingService.RefreshRankings")
+	defer span.End()
+
+	started := time.Now()
+	if err := s.refresh(ctx); err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to refresh media rankings", "error", err)
+		}
+		return
+	}
+	s.logger.Info("Media rankings refreshed", "duration", time.Since(started))
+}
+
+func (s *RankingService) refresh(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, kind := range []models.RankingKind{models.RankingTrending, models.RankingPopular} {
+		for _, window := range kind.Windows() {
+			if _, err := s.r.WithTx(tx).RebuildRanking(ctx, kind, window, s.size); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetRanking возвращает страницу рейтинга и время его последнего пересчета
+// (nil, если рейтинг еще не считался или за окно не было рекомендаций).
+func (s *RankingService) GetRanking(ctx context.Context, kind models.RankingKind, window models.RankingWindow, mediaType models.MediaType,
+	page, limit int) (*dtos.PaginatedResponseDTO[models.RankedMedia], *time.Time, error) {
+	ctx, span := tracer.Start(ctx, "RankingService.GetRanking")
+	defer span.End()
+
+	if !slices.Contains(kind.Windows(), window) {
+		return nil, nil, ErrInvalidRankingWindow
+	}
+	if mediaType != "" && !mediaType.Valid() {
+		return nil, nil, ErrInvalidMediaType
+	}
+
+	items, total, err := s.r.GetRanking(ctx, kind, window, mediaType, page, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	computedAt, err := s.r.GetComputedAt(ctx, kind, window)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newPage(items, total, page, limit), computedAt, nil
+}
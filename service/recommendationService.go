@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/cobrich/recommendo/models"
 	"github.com/cobrich/recommendo/repo"
@@ -18,9 +19,28 @@ var (
 	ErrAlreadyRecommended     = errors.New("this media has already been recommended to this user")
 	ErrUserNotAuthor          = errors.New("current user not created this recommendation")
 	ErrRecommendationNotFound = errors.New("recommendation not found")
+	ErrMediaOptedOut          = errors.New("recipient does not want this media recommended")
+	ErrOptOutNotFound         = errors.New("opt-out not found")
+	ErrCannotDecline          = errors.New("completed recommendation cannot be declined")
+
+	ErrInvalidRecommendationStatus = errors.New("status must be pending, completed or declined")
+	ErrInvalidRecommendationSort   = errors.New("sort must be date, name or year and order must be asc or desc")
+
+	ErrInvalidInboxState        = errors.New("invalid inbox state")
+	ErrInboxStateForSent        = errors.New("state filter is only available for received recommendations")
+	ErrInvalidSnooze            = errors.New("snooze time must be in the future and within a year")
+	ErrTooManyRecommendationIDs = errors.New("too many recommendation ids")
+)
+
+const (
+	// maxSnooze - насколько далеко можно отложить рекомендацию
+	maxSnooze = 365 * 24 * time.Hour
+	// maxBulkRecommendationIDs - ограничение на размер пакетной отметки прочитанными
+	maxBulkRecommendationIDs = 500
 )
 
 type RecommendationService struct {
+	db *sql.DB
 	// Собственные зависимости (репозитории)
 	r         *repo.RecommendationRepo
 	mediaRepo *repo.MediaRepo // Допустим, он может сам создавать медиа
@@ -28,33 +48,42 @@ type RecommendationService struct {
 	// Зависимости от ДРУГИХ СЕРВИСОВ
 	userService   *UserService
 	followService *FollowService
+	audit         *AuditService
+	webhooks      *WebhookService
 	logger        *slog.Logger
 }
 
 // Конструктор теперь принимает все нужные зависимости
-func NewRecommendationService(rRepo *repo.RecommendationRepo, mRepo *repo.MediaRepo, uService *UserService, fService *FollowService, logger *slog.Logger) *RecommendationService {
+func NewRecommendationService(db *sql.DB, rRepo *repo.RecommendationRepo, mRepo *repo.MediaRepo, uService *UserService, fService *FollowService,
+	audit *AuditService, webhooks *WebhookService, logger *slog.Logger) *RecommendationService {
 	return &RecommendationService{
+		db:            db,
 		r:             rRepo,
 		mediaRepo:     mRepo,
 		userService:   uService,
 		followService: fService,
+		audit:         audit,
+		webhooks:      webhooks,
 		logger:        logger,
 	}
 }
 
 func (s *RecommendationService) CreateRecommendation(ctx context.Context, fromID, toID, mediaID int) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.CreateRecommendation")
+	defer span.End()
+
 	// 1. Check existance of users
-	_, err := s.userService.GetUserByID(ctx, fromID)
+	from, err := s.userService.GetUserByID(ctx, fromID)
 	if err != nil {
 		return ErrTargetUserNotFound
 	}
-	_, err = s.userService.GetUserByID(ctx, toID)
-	if err != nil {
-		return ErrTargetUserNotFound
+	// Деактивированный получатель не принимает рекомендации: отдаем отдельную ошибку
+	if err := s.userService.CheckActiveUser(ctx, toID); err != nil {
+		return err
 	}
 
 	// 2. Check existance of media
-	_, err = s.mediaRepo.GetMedia(ctx, mediaID)
+	media, err := s.mediaRepo.GetMedia(ctx, mediaID)
 	if err != nil {
 		return ErrMediaNotFound
 	}
@@ -68,7 +97,16 @@ func (s *RecommendationService) CreateRecommendation(ctx context.Context, fromID
 		return ErrNotFriends
 	}
 
-	// 4. Check is it recommandation first time
+	// 4. Check recipient did not opt out of this media
+	optedOut, err := s.r.HasOptOut(ctx, toID, mediaID)
+	if err != nil {
+		return err
+	}
+	if optedOut {
+		return ErrMediaOptedOut
+	}
+
+	// 5. Check is it recommandation first time
 	err = s.r.GetRecommendation(ctx, fromID, toID, mediaID)
 	if err == nil {
 		return ErrAlreadyRecommended
@@ -77,19 +115,265 @@ func (s *RecommendationService) CreateRecommendation(ctx context.Context, fromID
 		return fmt.Errorf("unexpected error when checking for existing recommendation: %w", err)
 	}
 
-	// 5. If not exists, and there is no problems create recomm
-	return s.r.CreateRecommendation(ctx, fromID, toID, mediaID)
+	// 6. If not exists, and there is no problems create recomm
+	// together with the webhook event for the recipient
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	recommendationID, err := s.r.WithTx(tx).CreateRecommendation(ctx, fromID, toID, mediaID)
+	if err != nil {
+		return err
+	}
+	if err := s.webhooks.publishRecommendation(ctx, tx, recommendationID, toID, from, media, "", 0); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (s *RecommendationService) GetRecommendations(ctx context.Context, userID int, direction string) ([]models.RecommendationDetails, error) {
+// GetRecommendations возвращает рекомендации пользователя для чужих глаз:
+// состояние во входящих видит и фильтрует только сам получатель.
+func (s *RecommendationService) GetRecommendations(ctx context.Context, userID int, direction string, filter repo.RecommendationFilter) ([]models.RecommendationDetails, error) {
+	ctx, span := tracer.Start(ctx, "RecommendationService.GetRecommendations")
+	defer span.End()
+
+	filter.State = ""
+	if err := validateRecommendationFilter(filter); err != nil {
+		return nil, err
+	}
+
 	if direction == "sent" {
-		return s.r.GetSentRecommendations(ctx, userID)
+		return s.r.GetSentRecommendations(ctx, userID, filter)
+	}
+
+	recommendations, err := s.r.GetReceivedRecommendations(ctx, userID, filter)
+	if err != nil {
+		return nil, err
+	}
+	return withoutInboxState(recommendations), nil
+}
+
+// GetMyRecommendations возвращает рекомендации текущего пользователя.
+// Для полученных можно отфильтровать по состоянию во входящих.
+func (s *RecommendationService) GetMyRecommendations(ctx context.Context, userID int, direction string, filter repo.RecommendationFilter) ([]models.RecommendationDetails, error) {
+	ctx, span := tracer.Start(ctx, "RecommendationService.GetMyRecommendations")
+	defer span.End()
+
+	if filter.State != "" && !filter.State.Valid() {
+		return nil, ErrInvalidInboxState
+	}
+	if err := validateRecommendationFilter(filter); err != nil {
+		return nil, err
+	}
+
+	if direction == "sent" {
+		if filter.State != "" {
+			return nil, ErrInboxStateForSent
+		}
+		return s.r.GetSentRecommendations(ctx, userID, filter)
+	}
+
+	return s.r.GetReceivedRecommendations(ctx, userID, filter)
+}
+
+func validateRecommendationFilter(filter repo.RecommendationFilter) error {
+	if filter.MediaType != "" && !filter.MediaType.Valid() {
+		return ErrInvalidMediaType
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		return ErrInvalidRecommendationStatus
+	}
+	if !filter.From.IsZero() && !filter.To.IsZero() && !filter.From.Before(filter.To) {
+		return ErrInvalidTimeRange
+	}
+	switch filter.Sort {
+	case "", repo.RecommendationSortDate, repo.RecommendationSortName, repo.RecommendationSortYear:
+	default:
+		return ErrInvalidRecommendationSort
+	}
+	switch filter.Order {
+	case "", repo.SortAsc, repo.SortDesc:
+	default:
+		return ErrInvalidRecommendationSort
+	}
+	return nil
+}
+
+// CountUnread возвращает число непрочитанных рекомендаций во входящих.
+func (s *RecommendationService) CountUnread(ctx context.Context, userID int) (int64, error) {
+	ctx, span := tracer.Start(ctx, "RecommendationService.CountUnread")
+	defer span.End()
+
+	return s.r.CountUnreadRecommendations(ctx, userID)
+}
+
+// MarkRead отмечает прочитанными указанные рекомендации или, если список пуст, все непрочитанные.
+func (s *RecommendationService) MarkRead(ctx context.Context, userID int, recomIDs []int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "RecommendationService.MarkRead")
+	defer span.End()
+
+	if len(recomIDs) > maxBulkRecommendationIDs {
+		return 0, ErrTooManyRecommendationIDs
+	}
+	if recomIDs == nil {
+		recomIDs = []int64{}
+	}
+	return s.r.MarkRecommendationsRead(ctx, userID, recomIDs)
+}
+
+// MarkUnread возвращает полученной рекомендации статус непрочитанной.
+func (s *RecommendationService) MarkUnread(ctx context.Context, userID, recomID int) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.MarkUnread")
+	defer span.End()
+
+	return inboxError(s.r.MarkRecommendationUnread(ctx, userID, recomID))
+}
+
+// SetArchived убирает полученную рекомендацию в архив или возвращает во входящие.
+func (s *RecommendationService) SetArchived(ctx context.Context, userID, recomID int, archived bool) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.SetArchived")
+	defer span.End()
+
+	return inboxError(s.r.SetRecommendationArchived(ctx, userID, recomID, archived))
+}
+
+// Snooze откладывает полученную рекомендацию до until; nil возвращает ее сразу.
+func (s *RecommendationService) Snooze(ctx context.Context, userID, recomID int, until *time.Time) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.Snooze")
+	defer span.End()
+
+	if until != nil {
+		now := time.Now()
+		if !until.After(now) || until.Sub(now) > maxSnooze {
+			return ErrInvalidSnooze
+		}
+	}
+	return inboxError(s.r.SnoozeRecommendation(ctx, userID, recomID, until))
+}
+
+// Hide скрывает полученную рекомендацию; optOut дополнительно запрещает рекомендовать это медиа снова.
+func (s *RecommendationService) Hide(ctx context.Context, userID, recomID int, optOut bool) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.Hide")
+	defer span.End()
+
+	return s.dismiss(ctx, userID, recomID, optOut, false)
+}
+
+// Unhide возвращает скрытую рекомендацию во входящие. Отклонение при этом не отменяется.
+func (s *RecommendationService) Unhide(ctx context.Context, userID, recomID int) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.Unhide")
+	defer span.End()
+
+	return inboxError(s.r.SetRecommendationHidden(ctx, userID, recomID, false))
+}
+
+// Decline отклоняет полученную рекомендацию: она скрывается, а отправитель видит статус declined.
+func (s *RecommendationService) Decline(ctx context.Context, userID, recomID int, optOut bool) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.Decline")
+	defer span.End()
+
+	return s.dismiss(ctx, userID, recomID, optOut, true)
+}
+
+// dismiss проверяет, что рекомендация получена пользователем, и в одной транзакции
+// скрывает или отклоняет ее и, если нужно, запрещает медиа.
+func (s *RecommendationService) dismiss(ctx context.Context, userID, recomID int, optOut, decline bool) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	recommendations := s.r.WithTx(tx)
+	recommendation, err := recommendations.GetRecommendationByID(ctx, recomID)
+	if err != nil {
+		return inboxError(err)
+	}
+	if recommendation.ToUserID != userID {
+		return ErrRecommendationNotFound
+	}
+
+	if decline {
+		if recommendation.Status == models.RecommendationCompleted {
+			return ErrCannotDecline
+		}
+		err = recommendations.DeclineRecommendation(ctx, userID, recomID)
+	} else {
+		err = recommendations.SetRecommendationHidden(ctx, userID, recomID, true)
 	}
+	if err != nil {
+		return inboxError(err)
+	}
+	if optOut {
+		if err := recommendations.AddOptOut(ctx, userID, recommendation.MediaID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
 
-	return s.r.GetReceivedRecommendations(ctx, userID)
+// GetOptOuts возвращает медиа, которые пользователь запретил ему рекомендовать.
+func (s *RecommendationService) GetOptOuts(ctx context.Context, userID int) ([]models.RecommendationOptOut, error) {
+	ctx, span := tracer.Start(ctx, "RecommendationService.GetOptOuts")
+	defer span.End()
+
+	return s.r.GetOptOuts(ctx, userID)
 }
 
+// AddOptOut запрещает рекомендовать пользователю медиа.
+func (s *RecommendationService) AddOptOut(ctx context.Context, userID, mediaID int) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.AddOptOut")
+	defer span.End()
+
+	if _, err := s.mediaRepo.GetMedia(ctx, mediaID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrMediaNotFound
+		}
+		return err
+	}
+	return s.r.AddOptOut(ctx, userID, mediaID)
+}
+
+// RemoveOptOut снова разрешает рекомендовать пользователю медиа.
+func (s *RecommendationService) RemoveOptOut(ctx context.Context, userID, mediaID int) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.RemoveOptOut")
+	defer span.End()
+
+	if err := s.r.RemoveOptOut(ctx, userID, mediaID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOptOutNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func inboxError(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRecommendationNotFound
+	}
+	return err
+}
+
+// withoutInboxState стирает состояние во входящих перед показом списка не получателю.
+func withoutInboxState(recommendations []models.RecommendationDetails) []models.RecommendationDetails {
+	for i := range recommendations {
+		recommendations[i].ReadAt = nil
+		recommendations[i].ArchivedAt = nil
+		recommendations[i].SnoozedUntil = nil
+		recommendations[i].HiddenAt = nil
+	}
+	return recommendations
+}
+
+// DeleteRecommendation удаляет рекомендацию автора. Для получателя удаление означает
+// скрытие: у отправителя рекомендация остается в списке отправленных.
 func (s *RecommendationService) DeleteRecommendation(ctx context.Context, currentUserID, recomID int) error {
+	ctx, span := tracer.Start(ctx, "RecommendationService.DeleteRecommendation")
+	defer span.End()
+
 	// 2. Check existing recommendation
 	recommendation, err := s.r.GetRecommendationByID(ctx, recomID)
 	if err != nil{
@@ -100,15 +384,53 @@ func (s *RecommendationService) DeleteRecommendation(ctx context.Context, curren
 	}
 	
 	// 3. Check owner is current ?
+	if currentUserID == recommendation.ToUserID && currentUserID != recommendation.FromUserID {
+		return inboxError(s.r.SetRecommendationHidden(ctx, currentUserID, recomID, true))
+	}
 	if currentUserID != recommendation.FromUserID {
 		return ErrUserNotAuthor
 	}
 	
-	// 4. Delete
-	if err = s.r.DeleteRecommendation(ctx, recomID); err != nil{
+	// 4. Delete together with the audit record
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
-	
+	defer tx.Rollback()
+
+	if err = s.r.WithTx(tx).DeleteRecommendation(ctx, recomID); err != nil{
+		return err
+	}
+	if err = s.recordDeletion(ctx, tx, currentUserID, recommendation, false); err != nil {
+		return err
+	}
+
 	// 5. Return error or nil
-	return nil
+	return tx.Commit()
+}
+
+func (s *RecommendationService) recordDeletion(ctx context.Context, tx *sql.Tx, actorID int, recommendation models.Recommendation, moderation bool) error {
+	return s.audit.record(ctx, tx, actorID, models.AuditRecommendationDeleted, models.AuditTargetRecommendation, recommendation.ID, map[string]any{
+		"from_user_id": recommendation.FromUserID,
+		"to_user_id":   recommendation.ToUserID,
+		"media_id":     recommendation.MediaID,
+		"moderation":   moderation,
+	})
+}
+
+// removeRecommendationTx удаляет рекомендацию по решению модератора (без проверки автора)
+// внутри транзакции вызывающего.
+func (s *RecommendationService) removeRecommendationTx(ctx context.Context, tx *sql.Tx, moderatorID, recomID int) error {
+	recommendation, err := s.r.WithTx(tx).GetRecommendationByID(ctx, recomID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecommendationNotFound
+		}
+		return err
+	}
+
+	if err := s.r.WithTx(tx).DeleteRecommendation(ctx, recomID); err != nil {
+		return err
+	}
+	return s.recordDeletion(ctx, tx, moderatorID, recommendation, true)
 }
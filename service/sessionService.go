@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/cobrich/recommendo/jwt"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/requestmeta"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/google/uuid"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+// sessionTouchInterval - как часто обновлять last_seen_at. Запись при каждом
+// запросе не нужна: точности до минуты для списка устройств достаточно.
+const sessionTouchInterval = time.Minute
+
+type SessionService struct {
+	db       *sql.DB
+	r        *repo.SessionRepo
+	userRepo *repo.UserRepo
+	tokens   *jwt.TokenManager
+	audit    *AuditService
+	logger   *slog.Logger
+}
+
+func NewSessionService(db *sql.DB, r *repo.SessionRepo, userRepo *repo.UserRepo, tokens *jwt.TokenManager, audit *AuditService, logger *slog.Logger) *SessionService {
+	return &SessionService{db: db, r: r, userRepo: userRepo, tokens: tokens, audit: audit, logger: logger}
+}
+
+// IssueToken создает сессию для устройства, с которого пришел запрос,
+// и выдает привязанный к ней токен доступа. Это последний шаг любого входа,
+// поэтому здесь же восстанавливается удаленный (но еще не стертый) или
+// деактивированный аккаунт, а заблокированному отказывается во входе.
+func (s *SessionService) IssueToken(ctx context.Context, userID int) (string, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.IssueToken")
+	defer span.End()
+
+	user, err := s.userRepo.FindUserByIDWithPassword(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.SuspendedAt != nil {
+		return "", ErrUserSuspended
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	restored, err := s.userRepo.WithTx(tx).RestoreUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	meta := requestmeta.FromContext(ctx)
+	session, err := s.r.WithTx(tx).CreateSession(ctx, models.UserSession{
+		UserID:    userID,
+		Device:    utils.DescribeDevice(meta.UserAgent),
+		UserAgent: meta.UserAgent,
+		IPAddress: meta.IP,
+		ExpiresAt: time.Now().Add(s.tokens.TTL()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	err = s.audit.record(ctx, tx, userID, models.AuditLoginSucceeded, models.AuditTargetUser, userID, map[string]any{
+		"session_id":       session.ID,
+		"account_restored": restored,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+	if restored {
+		s.logger.Info("Account restored by login", "user_id", userID)
+	}
+
+	return s.tokens.GenerateToken(userID, session.ID, string(user.Role))
+}
+
+// IsSessionActive проверяет, что сессия токена существует и не отозвана.
+// Вызывается middleware аутентификации на каждый запрос.
+func (s *SessionService) IsSessionActive(ctx context.Context, userID int, sessionID string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.IsSessionActive")
+	defer span.End()
+
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return false, nil
+	}
+
+	session, err := s.r.GetSession(ctx, userID, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to check session", "error", err, "session_id", sessionID)
+		return false, err
+	}
+	if session.RevokedAt != nil || time.Now().After(session.ExpiresAt) {
+		return false, nil
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		// Ошибка обновления времени активности не должна мешать запросу
+		if err := s.r.TouchSession(ctx, sessionID); err != nil {
+			s.logger.Warn("Failed to update session activity", "error", err, "session_id", sessionID)
+		}
+	}
+
+	return true, nil
+}
+
+func (s *SessionService) GetSessions(ctx context.Context, userID int) ([]models.UserSession, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.GetSessions")
+	defer span.End()
+
+	return s.r.GetActiveSessions(ctx, userID)
+}
+
+// RevokeSession завершает сессию на одном устройстве (в том числе текущую - это выход).
+func (s *SessionService) RevokeSession(ctx context.Context, userID int, sessionID string) error {
+	ctx, span := tracer.Start(ctx, "SessionService.RevokeSession")
+	defer span.End()
+
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+
+	if err := s.r.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return err
+	}
+
+	s.logger.Info("Session revoked", "user_id", userID, "session_id", sessionID)
+	return nil
+}
+
+// RevokeOtherSessions выходит со всех устройств, кроме текущего.
+func (s *SessionService) RevokeOtherSessions(ctx context.Context, userID int, currentSessionID string) (int64, error) {
+	ctx, span := tracer.Start(ctx, "SessionService.RevokeOtherSessions")
+	defer span.End()
+
+	revoked, err := s.r.RevokeOtherSessions(ctx, userID, currentSessionID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("Other sessions revoked", "user_id", userID, "count", revoked)
+	return revoked, nil
+}
+
+func (s *SessionService) RevokeAllSessions(ctx context.Context, userID int) error {
+	ctx, span := tracer.Start(ctx, "SessionService.RevokeAllSessions")
+	defer span.End()
+
+	return s.r.RevokeAllSessions(ctx, userID)
+}
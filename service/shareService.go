@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/utils"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrShareLinkNotFound  = errors.New("share link not found")
+	ErrInvalidShareLink   = errors.New("share link is invalid or expired")
+	ErrInvalidShareMedia  = errors.New("share link must contain from 1 to 50 distinct media items")
+	ErrInvalidShareNote   = errors.New("share note is too long")
+	ErrInvalidShareExpiry = errors.New("share link expiry must be in the future and within the allowed period")
+	ErrOwnShareLink       = errors.New("cannot accept your own share link")
+)
+
+const (
+	maxShareMedia      = 50
+	maxShareNoteLength = 500
+)
+
+// ShareSettings - параметры ссылок из конфига.
+type ShareSettings struct {
+	LinkSecret []byte
+	// LinkTTL - срок ссылки по умолчанию.
+	LinkTTL time.Duration
+	// MaxLinkTTL - максимальный срок, который может выбрать автор.
+	MaxLinkTTL time.Duration
+}
+
+// ShareService выпускает ссылки на подборки медиа. В отличие от CreateRecommendation,
+// принять ссылку может кто угодно, без взаимной подписки с автором.
+type ShareService struct {
+	db         *sql.DB
+	r          *repo.ShareRepo
+	recomRepo  *repo.RecommendationRepo
+	followRepo *repo.FollowRepo
+	mediaRepo  *repo.MediaRepo
+	webhooks   *WebhookService
+	settings   ShareSettings
+	logger     *slog.Logger
+}
+
+func NewShareService(db *sql.DB, r *repo.ShareRepo, recomRepo *repo.RecommendationRepo, followRepo *repo.FollowRepo, mediaRepo *repo.MediaRepo,
+	webhooks *WebhookService, settings ShareSettings, logger *slog.Logger) *ShareService {
+	return &ShareService{
+		db:         db,
+		r:          r,
+		recomRepo:  recomRepo,
+		followRepo: followRepo,
+		mediaRepo:  mediaRepo,
+		webhooks:   webhooks,
+		settings:   settings,
+		logger:     logger,
+	}
+}
+
+// CreateShareLink выпускает ссылку на медиа. expiresAt = nil - срок по умолчанию.
+func (s *ShareService) CreateShareLink(ctx context.Context, ownerID int, mediaIDs []int, note string, promptFollow bool, expiresAt *time.Time) (models.ShareLink, error) {
+	ctx, span := tracer.Start(ctx, "ShareService.CreateShareLink")
+	defer span.End()
+
+	if len(mediaIDs) == 0 || len(mediaIDs) > maxShareMedia {
+		return models.ShareLink{}, ErrInvalidShareMedia
+	}
+	seen := make(map[int]bool, len(mediaIDs))
+	for _, mediaID := range mediaIDs {
+		if seen[mediaID] {
+			return models.ShareLink{}, ErrInvalidShareMedia
+		}
+		seen[mediaID] = true
+
+		if _, err := s.mediaRepo.GetMedia(ctx, mediaID); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return models.ShareLink{}, fmt.Errorf("%w: %d", ErrMediaNotFound, mediaID)
+			}
+			return models.ShareLink{}, err
+		}
+	}
+
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > maxShareNoteLength {
+		return models.ShareLink{}, ErrInvalidShareNote
+	}
+
+	now := time.Now()
+	expires := now.Add(s.settings.LinkTTL)
+	if expiresAt != nil {
+		if !expiresAt.After(now) || expiresAt.Sub(now) > s.settings.MaxLinkTTL {
+			return models.ShareLink{}, ErrInvalidShareExpiry
+		}
+		expires = *expiresAt
+	}
+	// В подписи срок хранится с точностью до секунды
+	expires = expires.Truncate(time.Second)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	defer tx.Rollback()
+
+	link, err := s.r.WithTx(tx).CreateShareLink(ctx, ownerID, note, promptFollow, expires, mediaIDs)
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.ShareLink{}, err
+	}
+
+	s.logger.Info("Share link created", "user_id", ownerID, "share_id", link.ID, "media_count", len(mediaIDs))
+	return link, nil
+}
+
+// GetShareLinks возвращает ссылки автора.
+func (s *ShareService) GetShareLinks(ctx context.Context, ownerID int) ([]models.ShareLink, error) {
+	ctx, span := tracer.Start(ctx, "ShareService.GetShareLinks")
+	defer span.End()
+
+	return s.r.GetUserShareLinks(ctx, ownerID)
+}
+
+// RevokeShareLink отзывает ссылку: открыть и принять ее больше нельзя,
+// уже принятые рекомендации остаются.
+func (s *ShareService) RevokeShareLink(ctx context.Context, ownerID int, shareID string) error {
+	ctx, span := tracer.Start(ctx, "ShareService.RevokeShareLink")
+	defer span.End()
+
+	if _, err := uuid.Parse(shareID); err != nil {
+		return ErrShareLinkNotFound
+	}
+	if err := s.r.RevokeShareLink(ctx, ownerID, shareID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrShareLinkNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// OpenShareLink проверяет подпись и возвращает ссылку. Токен не нужен.
+func (s *ShareService) OpenShareLink(ctx context.Context, shareID, expires, signature string) (models.ShareLink, error) {
+	ctx, span := tracer.Start(ctx, "ShareService.OpenShareLink")
+	defer span.End()
+
+	return s.openShareLink(ctx, s.r, shareID, expires, signature)
+}
+
+// AcceptShareLink превращает ссылку в полученные пользователем рекомендации от автора.
+// Медиа, которое автор уже рекомендовал пользователю или которое пользователь запретил,
+// пропускается. follow - заодно подписаться на автора. Возвращает число новых рекомендаций
+// и то, появилась ли подписка.
+func (s *ShareService) AcceptShareLink(ctx context.Context, userID int, shareID, expires, signature string, follow bool) (int, bool, error) {
+	ctx, span := tracer.Start(ctx, "ShareService.AcceptShareLink")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	link, err := s.openShareLink(ctx, s.r.WithTx(tx), shareID, expires, signature)
+	if err != nil {
+		return 0, false, err
+	}
+	if link.OwnerID == userID {
+		return 0, false, ErrOwnShareLink
+	}
+
+	recommendations := s.recomRepo.WithTx(tx)
+	created := 0
+	for _, media := range link.Media {
+		err := recommendations.GetRecommendation(ctx, link.OwnerID, userID, media.ID)
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return 0, false, err
+		}
+
+		optedOut, err := recommendations.HasOptOut(ctx, userID, media.ID)
+		if err != nil {
+			return 0, false, err
+		}
+		if optedOut {
+			continue
+		}
+
+		// recommendation.received здесь не публикуется: пользователь сам принял подборку
+		if err := recommendations.CreateSharedRecommendation(ctx, link.OwnerID, userID, media.ID, link.Note, link.ID); err != nil {
+			return 0, false, err
+		}
+		created++
+	}
+
+	followed := false
+	if follow {
+		follows := s.followRepo.WithTx(tx)
+		following, err := follows.IsFollowing(ctx, userID, link.OwnerID)
+		if err != nil {
+			return 0, false, err
+		}
+		if !following {
+			if err := follows.CreateFollow(ctx, userID, link.OwnerID); err != nil {
+				return 0, false, err
+			}
+			if err := s.webhooks.publishFollow(ctx, tx, userID, link.OwnerID); err != nil {
+				return 0, false, err
+			}
+			followed = true
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+
+	s.logger.Info("Share link accepted", "user_id", userID, "share_id", link.ID, "created", created, "followed", followed)
+	return created, followed, nil
+}
+
+// LinkURL возвращает подписанный путь ссылки; срок подписи совпадает со сроком ссылки.
+func (s *ShareService) LinkURL(link models.ShareLink) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(link.ExpiresAt.Unix(), 10))
+	query.Set("signature", utils.SignLink(s.settings.LinkSecret, shareResource(link.ID), link.ExpiresAt))
+	return "/shares/" + link.ID + "?" + query.Encode()
+}
+
+func (s *ShareService) openShareLink(ctx context.Context, r *repo.ShareRepo, shareID, expires, signature string) (models.ShareLink, error) {
+	if _, err := uuid.Parse(shareID); err != nil {
+		return models.ShareLink{}, ErrShareLinkNotFound
+	}
+	if !utils.VerifyLink(s.settings.LinkSecret, shareResource(shareID), expires, signature, time.Now()) {
+		return models.ShareLink{}, ErrInvalidShareLink
+	}
+
+	link, err := r.GetShareLink(ctx, shareID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.ShareLink{}, ErrShareLinkNotFound
+	}
+	if err != nil {
+		return models.ShareLink{}, err
+	}
+	// Отозванная ссылка выглядит так же, как несуществующая
+	if !link.Active(time.Now()) {
+		return models.ShareLink{}, ErrShareLinkNotFound
+	}
+	return link, nil
+}
+
+// shareResource отделяет подписи ссылок на подборки от других подписанных ссылок.
+func shareResource(shareID string) string {
+	return "share:" + shareID
+}
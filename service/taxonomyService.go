@@ -0,0 +1,283 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/utils"
+)
+
+var (
+	ErrGenreNotFound              = errors.New("genre not found")
+	ErrTagNotFound                = errors.New("tag not found")
+	ErrGenreExists                = errors.New("genre already exists")
+	ErrInvalidTermName            = errors.New("genre and tag names must be 1 to 50 characters and contain letters or digits")
+	ErrInvalidSort                = errors.New("invalid sort: must be popular, recent or name")
+	ErrTagAlreadyApplied          = errors.New("media item already has this tag")
+	ErrTagAlreadySuggested        = errors.New("this tag has already been suggested for the media item and is awaiting review")
+	ErrTagSuggestionNotFound      = errors.New("tag suggestion not found")
+	ErrTagSuggestionReviewed      = errors.New("tag suggestion has already been reviewed")
+	ErrInvalidTagSuggestionStatus = errors.New("invalid tag suggestion status: must be pending, approved or rejected")
+)
+
+type TaxonomyService struct {
+	db        *sql.DB
+	r         *repo.TaxonomyRepo
+	mediaRepo *repo.MediaRepo
+	logger    *slog.Logger
+}
+
+func NewTaxonomyService(db *sql.DB, r *repo.TaxonomyRepo, mediaRepo *repo.MediaRepo, logger *slog.Logger) *TaxonomyService {
+	return &TaxonomyService{db: db, r: r, mediaRepo: mediaRepo, logger: logger}
+}
+
+// GetTerms возвращает жанры или теги с числом медиа у каждого.
+func (s *TaxonomyService) GetTerms(ctx context.Context, kind models.TaxonomyKind, query, sort string) ([]models.Term, error) {
+	ctx, span := tracer.Start(ctx, "TaxonomyService.GetTerms")
+	defer span.End()
+
+	if sort == "" {
+		sort = repo.TermSortPopular
+	}
+	if sort != repo.TermSortPopular && sort != repo.TermSortName {
+		return nil, ErrInvalidSort
+	}
+
+	return s.r.GetTerms(ctx, kind, strings.TrimSpace(query), sort)
+}
+
+// GetMediaByTerm возвращает медиа с жанром или тегом. По умолчанию популярные первыми.
+func (s *TaxonomyService) GetMediaByTerm(ctx context.Context, kind models.TaxonomyKind, slug string, mediaType models.MediaType,
+	sort string, page, limit int) (*dtos.PaginatedResponseDTO[models.MediaPopularity], error) {
+	ctx, span := tracer.Start(ctx, "TaxonomyService.GetMediaByTerm")
+	defer span.End()
+
+	if sort == "" {
+		sort = repo.MediaSortPopular
+	}
+	if sort != repo.MediaSortPopular && sort != repo.MediaSortRecent {
+		return nil, ErrInvalidSort
+	}
+	if mediaType != "" && !mediaType.Valid() {
+		return nil, ErrInvalidMediaType
+	}
+
+	term, err := s.r.GetTerm(ctx, kind, slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, termNotFound(kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, total, err := s.r.GetMediaByTerm(ctx, kind, term.ID, mediaType, sort, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(items, total, page, limit), nil
+}
+
+// CreateGenre добавляет жанр в закрытый список.
+func (s *TaxonomyService) CreateGenre(ctx context.Context, name string) (models.Term, error) {
+	ctx, span := tracer.Start(ctx, "TaxonomyService.CreateGenre")
+	defer span.End()
+
+	name, slug, err := normalizeTermName(name)
+	if err != nil {
+		return models.Term{}, err
+	}
+
+	genre, err := s.r.CreateTerm(ctx, models.TaxonomyGenre, slug, name)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Term{}, ErrGenreExists
+	}
+	if err != nil {
+		return models.Term{}, fmt.Errorf("failed to create genre: %w", err)
+	}
+	return genre, nil
+}
+
+// SuggestTag сохраняет предложенный пользователем тег. У медиа он появится после одобрения модератором.
+func (s *TaxonomyService) SuggestTag(ctx context.Context, userID, mediaID int, name string) (models.TagSuggestion, error) {
+	ctx, span := tracer.Start(ctx, "TaxonomyService.SuggestTag")
+	defer span.End()
+
+	name, slug, err := normalizeTermName(name)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+
+	if _, err := s.mediaRepo.GetMedia(ctx, mediaID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TagSuggestion{}, ErrMediaNotFound
+		}
+		return models.TagSuggestion{}, err
+	}
+
+	applied, err := s.r.HasTerm(ctx, models.TaxonomyTag, mediaID, slug)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+	if applied {
+		return models.TagSuggestion{}, ErrTagAlreadyApplied
+	}
+
+	suggestion, err := s.r.CreateTagSuggestion(ctx, mediaID, userID, name, slug)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.TagSuggestion{}, ErrTagAlreadySuggested
+	}
+	if err != nil {
+		return models.TagSuggestion{}, fmt.Errorf("failed to create tag suggestion: %w", err)
+	}
+	return suggestion, nil
+}
+
+func (s *TaxonomyService) GetTagSuggestions(ctx context.Context, status models.TagSuggestionStatus, page, limit int) (*dtos.PaginatedResponseDTO[models.TagSuggestion], error) {
+	ctx, span := tracer.Start(ctx, "TaxonomyService.GetTagSuggestions")
+	defer span.End()
+
+	switch status {
+	case "", models.TagSuggestionPending, models.TagSuggestionApproved, models.TagSuggestionRejected:
+	default:
+		return nil, ErrInvalidTagSuggestionStatus
+	}
+
+	suggestions, total, err := s.r.GetTagSuggestions(ctx, status, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(suggestions, total, page, limit), nil
+}
+
+// ApproveTagSuggestion создает тег (если его еще нет) и добавляет его медиа
+// в одной транзакции с закрытием предложения.
+func (s *TaxonomyService) ApproveTagSuggestion(ctx context.Context, reviewerID, suggestionID int) (models.TagSuggestion, error) {
+	ctx, span := tracer.Start(ctx, "TaxonomyService.ApproveTagSuggestion")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+	defer tx.Rollback()
+
+	suggestion, err := s.reviewTagSuggestion(ctx, tx, reviewerID, suggestionID, models.TagSuggestionApproved)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+
+	tag, err := s.r.WithTx(tx).EnsureTerm(ctx, models.TaxonomyTag, suggestion.Slug, suggestion.Name)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+	if err := s.r.WithTx(tx).LinkTerm(ctx, models.TaxonomyTag, suggestion.MediaID, tag.ID); err != nil {
+		return models.TagSuggestion{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TagSuggestion{}, err
+	}
+	return suggestion, nil
+}
+
+func (s *TaxonomyService) RejectTagSuggestion(ctx context.Context, reviewerID, suggestionID int) (models.TagSuggestion, error) {
+	ctx, span := tracer.Start(ctx, "TaxonomyService.RejectTagSuggestion")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+	defer tx.Rollback()
+
+	suggestion, err := s.reviewTagSuggestion(ctx, tx, reviewerID, suggestionID, models.TagSuggestionRejected)
+	if err != nil {
+		return models.TagSuggestion{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.TagSuggestion{}, err
+	}
+	return suggestion, nil
+}
+
+func (s *TaxonomyService) reviewTagSuggestion(ctx context.Context, tx *sql.Tx, reviewerID, suggestionID int,
+	status models.TagSuggestionStatus) (models.TagSuggestion, error) {
+	suggestion, err := s.r.WithTx(tx).ReviewTagSuggestion(ctx, suggestionID, reviewerID, status)
+	if err == nil {
+		return suggestion, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return models.TagSuggestion{}, err
+	}
+
+	// Различаем "нет такого предложения" и "уже рассмотрено"
+	if _, err := s.r.WithTx(tx).GetTagSuggestion(ctx, suggestionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return models.TagSuggestion{}, ErrTagSuggestionNotFound
+		}
+		return models.TagSuggestion{}, err
+	}
+	return models.TagSuggestion{}, ErrTagSuggestionReviewed
+}
+
+// setMediaTermsTx заменяет жанры и теги медиа внутри транзакции вызывающего.
+// Жанры должны уже существовать, теги от модераторов создаются сразу.
+func (s *TaxonomyService) setMediaTermsTx(ctx context.Context, tx *sql.Tx, mediaID int, genres, tags []string) error {
+	taxonomy := s.r.WithTx(tx)
+
+	genreIDs := make([]int, 0, len(genres))
+	for _, name := range genres {
+		genre, err := taxonomy.GetTerm(ctx, models.TaxonomyGenre, utils.Slugify(name))
+		if errors.Is(err, sql.ErrNoRows) {
+			return metadataError("unknown genre %q", name)
+		}
+		if err != nil {
+			return err
+		}
+		genreIDs = append(genreIDs, genre.ID)
+	}
+
+	tagIDs := make([]int, 0, len(tags))
+	for _, raw := range tags {
+		name, slug, err := normalizeTermName(raw)
+		if err != nil {
+			return metadataError("invalid tag %q", raw)
+		}
+		tag, err := taxonomy.EnsureTerm(ctx, models.TaxonomyTag, slug, name)
+		if err != nil {
+			return err
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	if err := taxonomy.SetMediaTerms(ctx, models.TaxonomyGenre, mediaID, genreIDs); err != nil {
+		return err
+	}
+	return taxonomy.SetMediaTerms(ctx, models.TaxonomyTag, mediaID, tagIDs)
+}
+
+// normalizeTermName убирает лишние пробелы и строит слаг.
+func normalizeTermName(name string) (string, string, error) {
+	name = strings.Join(strings.Fields(name), " ")
+	slug := utils.Slugify(name)
+	if slug == "" || utf8.RuneCountInString(name) > maxMediaLabelLength {
+		return "", "", ErrInvalidTermName
+	}
+	return name, slug, nil
+}
+
+func termNotFound(kind models.TaxonomyKind) error {
+	if kind == models.TaxonomyGenre {
+		return ErrGenreNotFound
+	}
+	return ErrTagNotFound
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/jwt"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrTwoFactorAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrTwoFactorNotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrTwoFactorNotEnrolled    = errors.New("two-factor enrollment has not been started")
+	ErrInvalidTwoFactorCode    = errors.New("invalid two-factor code")
+	ErrTwoFactorLocked         = errors.New("too many invalid two-factor codes, try again later")
+	ErrInvalidChallenge        = errors.New("login challenge is invalid or expired")
+)
+
+// recoveryCodeCount - сколько кодов восстановления выдается за раз.
+const recoveryCodeCount = 10
+
+// TwoFactorSettings - параметры 2FA из конфига.
+type TwoFactorSettings struct {
+	// Issuer показывается в приложении-аутентификаторе рядом с email.
+	Issuer       string
+	ChallengeTTL time.Duration
+	MaxAttempts  int
+	Lockout      time.Duration
+}
+
+type TwoFactorService struct {
+	db       *sql.DB
+	r        *repo.TwoFactorRepo
+	userRepo *repo.UserRepo
+	sessions *SessionService
+	tokens   *jwt.TokenManager
+	audit    *AuditService
+	settings TwoFactorSettings
+	logger   *slog.Logger
+}
+
+func NewTwoFactorService(db *sql.DB, r *repo.TwoFactorRepo, userRepo *repo.UserRepo, sessions *SessionService, tokens *jwt.TokenManager,
+	audit *AuditService, settings TwoFactorSettings, logger *slog.Logger) *TwoFactorService {
+	return &TwoFactorService{db: db, r: r, userRepo: userRepo, sessions: sessions, tokens: tokens, audit: audit, settings: settings, logger: logger}
+}
+
+// IsEnabled сообщает, нужен ли пользователю второй шаг входа.
+func (s *TwoFactorService) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	totp, err := s.r.GetTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.Enabled(), nil
+}
+
+// NewChallenge выдает промежуточный токен, который обменивается на токен доступа
+// после проверки кода.
+func (s *TwoFactorService) NewChallenge(userID int) (string, error) {
+	return s.tokens.GenerateChallengeToken(userID, s.settings.ChallengeTTL)
+}
+
+func (s *TwoFactorService) Status(ctx context.Context, userID int) (dtos.TwoFactorStatusResponseDTO, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Status")
+	defer span.End()
+
+	enabled, err := s.IsEnabled(ctx, userID)
+	if err != nil || !enabled {
+		return dtos.TwoFactorStatusResponseDTO{}, err
+	}
+
+	left, err := s.r.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil {
+		return dtos.TwoFactorStatusResponseDTO{}, err
+	}
+
+	return dtos.TwoFactorStatusResponseDTO{Enabled: true, RecoveryCodesLeft: left}, nil
+}
+
+// Enroll начинает настройку: создает секрет и otpauth URI для QR-кода.
+// 2FA включится только после Confirm.
+func (s *TwoFactorService) Enroll(ctx context.Context, userID int) (dtos.TwoFactorEnrollResponseDTO, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Enroll")
+	defer span.End()
+
+	user, err := s.userRepo.FindUserByIDWithPassword(ctx, userID)
+	if err != nil {
+		return dtos.TwoFactorEnrollResponseDTO{}, ErrUserNotFound
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return dtos.TwoFactorEnrollResponseDTO{}, err
+	}
+
+	if err := s.r.SavePendingTOTP(ctx, userID, secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dtos.TwoFactorEnrollResponseDTO{}, ErrTwoFactorAlreadyEnabled
+		}
+		return dtos.TwoFactorEnrollResponseDTO{}, err
+	}
+
+	return dtos.TwoFactorEnrollResponseDTO{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(s.settings.Issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm включает 2FA, если код из приложения совпал, и возвращает коды
+// восстановления. Коды показываются только один раз.
+func (s *TwoFactorService) Confirm(ctx context.Context, userID int, code string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Confirm")
+	defer span.End()
+
+	totp, err := s.r.GetTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.Enabled() {
+		return nil, ErrTwoFactorAlreadyEnabled
+	}
+
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.r.WithTx(tx).ConfirmTOTP(ctx, userID, step); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Параллельный запрос уже подтвердил настройку
+			return nil, ErrTwoFactorAlreadyEnabled
+		}
+		return nil, err
+	}
+	if err := s.r.WithTx(tx).ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Two-factor authentication enabled", "user_id", userID)
+	return codes, nil
+}
+
+// Disable выключает 2FA. Нужен действующий код (или код восстановления),
+// а для аккаунтов со входом по паролю - еще и пароль.
+func (s *TwoFactorService) Disable(ctx context.Context, userID int, disableDTO dtos.DisableTwoFactorDTO) error {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.Disable")
+	defer span.End()
+
+	user, err := s.userRepo.FindUserByIDWithPassword(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if user.PasswordLoginEnabled {
+		if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(disableDTO.Password)); err != nil {
+			return ErrInvalidCredentials
+		}
+	}
+
+	totp, err := s.getEnabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyCode(ctx, totp, disableDTO.Code); err != nil {
+		return err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := s.r.WithTx(tx).DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	s.logger.Info("Two-factor authentication disabled", "user_id", userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes выдает новый набор кодов восстановления взамен старого.
+func (s *TwoFactorService) RegenerateRecoveryCodes(ctx context.Context, userID int, code string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	totp, err := s.getEnabledTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.verifyCode(ctx, totp, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := s.r.WithTx(tx).ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// VerifyChallenge завершает вход: проверяет промежуточный токен и код
+// и выдает токен доступа.
+func (s *TwoFactorService) VerifyChallenge(ctx context.Context, loginDTO dtos.TwoFactorLoginDTO) (string, error) {
+	ctx, span := tracer.Start(ctx, "TwoFactorService.VerifyChallenge")
+	defer span.End()
+
+	claims, err := s.tokens.ParseChallengeToken(loginDTO.ChallengeToken)
+	if err != nil {
+		return "", ErrInvalidChallenge
+	}
+
+	totp, err := s.getEnabledTOTP(ctx, claims.UserID)
+	if err != nil {
+		// 2FA выключили, пока пользователь вводил код
+		return "", ErrInvalidChallenge
+	}
+	if err := s.verifyCode(ctx, totp, loginDTO.Code); err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidTwoFactorCode):
+			s.recordLoginFailure(ctx, claims.UserID, "wrong_two_factor_code")
+		case errors.Is(err, ErrTwoFactorLocked):
+			s.recordLoginFailure(ctx, claims.UserID, "two_factor_locked")
+		}
+		return "", err
+	}
+
+	return s.sessions.IssueToken(ctx, claims.UserID)
+}
+
+// recordLoginFailure пишет неудачный второй шаг входа в журнал аудита, как
+// UserService.recordLoginFailure: вход и так отклоняется, ошибка записи только логируется.
+func (s *TwoFactorService) recordLoginFailure(ctx context.Context, userID int, reason string) {
+	err := s.audit.record(ctx, nil, 0, models.AuditLoginFailed, models.AuditTargetUser, userID, map[string]any{
+		"reason": reason,
+	})
+	if err != nil {
+		s.logger.Error("Failed to record failed login", "error", err, "user_id", userID)
+	}
+}
+
+func (s *TwoFactorService) getEnabledTOTP(ctx context.Context, userID int) (models.UserTOTP, error) {
+	totp, err := s.r.GetTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.UserTOTP{}, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return models.UserTOTP{}, err
+	}
+	if !totp.Enabled() {
+		return models.UserTOTP{}, ErrTwoFactorNotEnabled
+	}
+	return totp, nil
+}
+
+// verifyCode принимает код из приложения или неиспользованный код восстановления.
+// Каждая неудача учитывается: после MaxAttempts подряд проверка блокируется на Lockout.
+func (s *TwoFactorService) verifyCode(ctx context.Context, totp models.UserTOTP, code string) error {
+	if totp.LockedUntil != nil && time.Now().Before(*totp.LockedUntil) {
+		return ErrTwoFactorLocked
+	}
+
+	ok, err := s.checkCode(ctx, totp, code)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return nil
+	}
+
+	if err := s.r.RecordFailedAttempt(ctx, totp.UserID, s.settings.MaxAttempts, s.settings.Lockout); err != nil {
+		return err
+	}
+	s.logger.Warn("Invalid two-factor code", "user_id", totp.UserID)
+	return ErrInvalidTwoFactorCode
+}
+
+func (s *TwoFactorService) checkCode(ctx context.Context, totp models.UserTOTP, code string) (bool, error) {
+	if step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now()); ok {
+		// Код, который уже принимали, считается неверным
+		return s.r.UseTOTPStep(ctx, totp.UserID, step)
+	}
+
+	used, err := s.r.UseRecoveryCode(ctx, totp.UserID, utils.HashRecoveryCode(code))
+	if err != nil || !used {
+		return false, err
+	}
+
+	s.logger.Info("Recovery code used", "user_id", totp.UserID)
+	if err := s.r.ResetFailedAttempts(ctx, totp.UserID); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// newRecoveryCodes возвращает коды для показа пользователю и их хеши для хранения.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for range recoveryCodeCount {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, utils.HashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
@@ -6,9 +6,9 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"time"
 
 	"github.com/cobrich/recommendo/dtos"
-	"github.com/cobrich/recommendo/jwt"
 	"github.com/cobrich/recommendo/models"
 	"github.com/cobrich/recommendo/repo"
 	"github.com/cobrich/recommendo/utils"
@@ -20,28 +20,39 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials") // Для логина
 	ErrUserNotFound       = errors.New("user not found")
 	ErrFailedHashPassword = errors.New("failed to hash password")
+	ErrUserDeactivated    = errors.New("user account is deactivated")
+	ErrUserSuspended      = errors.New("user account is suspended")
 )
 
 type UserService struct {
-	db *sql.DB // <-- Добавляем это поле
-	r  *repo.UserRepo
-	// Добавляем зависимости от других репозиториев
-	followRepo *repo.FollowRepo
-	recomRepo  *repo.RecommendationRepo
-	logger     *slog.Logger
+	db        *sql.DB
+	r         *repo.UserRepo
+	twoFactor *TwoFactorService
+	sessions  *SessionService
+	audit     *AuditService
+	jobs      *JobService
+	accounts  AccountSettings
+	logger    *slog.Logger
 }
 
-func NewUserService(db *sql.DB, userRepo *repo.UserRepo, followRepo *repo.FollowRepo, recomRepo *repo.RecommendationRepo, logger *slog.Logger) *UserService {
+func NewUserService(db *sql.DB, userRepo *repo.UserRepo, twoFactor *TwoFactorService, sessions *SessionService, audit *AuditService,
+	jobs *JobService, accounts AccountSettings, logger *slog.Logger) *UserService {
 	return &UserService{
-		db:         db,
-		r:          userRepo,
-		followRepo: followRepo,
-		recomRepo:  recomRepo,
-		logger:     logger,
+		db:        db,
+		r:         userRepo,
+		twoFactor: twoFactor,
+		sessions:  sessions,
+		audit:     audit,
+		jobs:      jobs,
+		accounts:  accounts,
+		logger:    logger,
 	}
 }
 
 func (s *UserService) Register(ctx context.Context, registerDTO dtos.RegisterUserDTO) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Register")
+	defer span.End()
+
 	s.logger.Info("Register: Starting user registration", "user_name", registerDTO.UserName, "email", registerDTO.Email)
 
 	// 1. Validation fields
@@ -80,45 +91,116 @@ func (s *UserService) Register(ctx context.Context, registerDTO dtos.RegisterUse
 
 	// 4. Creating new user object
 	userToCreate := models.User{
-		UserName:     registerDTO.UserName,
-		Email:        email,
-		PasswordHash: []byte(hashedPassword), // Убедитесь, что тип совпадает с моделью
+		UserName:             registerDTO.UserName,
+		Email:                email,
+		PasswordHash:         []byte(hashedPassword), // Убедитесь, что тип совпадает с моделью
+		PasswordLoginEnabled: true,
 	}
 
 	// 5. Сохранение в репозитории
 	s.logger.Info("Register: Creating user in database", "user_name", userToCreate.UserName, "email", userToCreate.Email)
-	createdUser, err := s.r.CreateUser(ctx, userToCreate)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.User{}, err
+	}
+	defer tx.Rollback()
+
+	createdUser, err := s.r.WithTx(tx).CreateUser(ctx, userToCreate)
 	if err != nil {
 		s.logger.Error("Register: Failed to create user in database", "error", err, "user_name", userToCreate.UserName, "email", userToCreate.Email)
 		return models.User{}, err
 	}
+	if err := s.audit.record(ctx, tx, createdUser.ID, models.AuditUserRegistered, models.AuditTargetUser, createdUser.ID, nil); err != nil {
+		return models.User{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.User{}, err
+	}
 
 	s.logger.Info("Register: User created successfully", "user_id", createdUser.ID, "user_name", createdUser.UserName, "email", createdUser.Email)
 	return createdUser, nil
 }
 
-func (s *UserService) Login(ctx context.Context, loginDTO dtos.LoginUserDTO) (string, error) {
+func (s *UserService) Login(ctx context.Context, loginDTO dtos.LoginUserDTO) (dtos.LoginResponseDTO, error) {
+	ctx, span := tracer.Start(ctx, "UserService.Login")
+	defer span.End()
+
 	// 1. Validate fields for empty
 	if loginDTO.Email == "" || loginDTO.Password == "" {
-		return "", fmt.Errorf("email and/or password are/is empty")
+		return dtos.LoginResponseDTO{}, fmt.Errorf("email and/or password are/is empty")
 	}
 
 	// 2. Finding user in db
 	user, err := s.r.FindUserByEmail(ctx, loginDTO.Email)
 	if err == sql.ErrNoRows {
-		return "", ErrInvalidCredentials
+		s.recordLoginFailure(ctx, 0, "unknown_email", loginDTO.Email)
+		return dtos.LoginResponseDTO{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return dtos.LoginResponseDTO{}, err
+	}
+	// Аккаунты, созданные через внешнего провайдера, не имеют пароля
+	if !user.PasswordLoginEnabled {
+		s.recordLoginFailure(ctx, user.ID, "password_login_disabled", loginDTO.Email)
+		return dtos.LoginResponseDTO{}, ErrInvalidCredentials
 	}
 	if err := bcrypt.CompareHashAndPassword(user.PasswordHash, []byte(loginDTO.Password)); err != nil {
-		return "", ErrInvalidCredentials
+		s.recordLoginFailure(ctx, user.ID, "wrong_password", loginDTO.Email)
+		return dtos.LoginResponseDTO{}, ErrInvalidCredentials
 	}
-	tokenString, err := jwt.GenerateToken(user.ID)
+	return s.completeLogin(ctx, user)
+}
+
+// recordLoginFailure пишет неудачный вход в журнал аудита. Вход и так отклоняется,
+// поэтому ошибка записи только логируется.
+func (s *UserService) recordLoginFailure(ctx context.Context, userID int, reason, email string) {
+	err := s.audit.record(ctx, nil, 0, models.AuditLoginFailed, models.AuditTargetUser, userID, map[string]any{
+		"reason": reason,
+		"email":  email,
+	})
 	if err != nil {
-		return "", err
+		s.logger.Error("Failed to record failed login", "error", err, "user_id", userID)
 	}
-	return tokenString, nil
+}
+
+// completeLogin - общий путь после успешной аутентификации любым способом:
+// выдает токен доступа или, если включена 2FA, промежуточный токен для второго шага.
+// Удаленный аккаунт восстанавливается при выдаче токена доступа (см. SessionService.IssueToken).
+func (s *UserService) completeLogin(ctx context.Context, user models.User) (dtos.LoginResponseDTO, error) {
+	// Период восстановления истек: аккаунт ждет стирания и войти в него нельзя
+	if user.DeletedAt != nil && time.Since(*user.DeletedAt) > s.accounts.DeletionGracePeriod {
+		s.recordLoginFailure(ctx, user.ID, "account_purge_pending", user.Email)
+		return dtos.LoginResponseDTO{}, ErrInvalidCredentials
+	}
+	// Заблокированному аккаунту не выдаем даже промежуточный токен 2FA
+	if user.SuspendedAt != nil {
+		s.recordLoginFailure(ctx, user.ID, "account_suspended", user.Email)
+		return dtos.LoginResponseDTO{}, ErrUserSuspended
+	}
+
+	enabled, err := s.twoFactor.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return dtos.LoginResponseDTO{}, err
+	}
+	if enabled {
+		challenge, err := s.twoFactor.NewChallenge(user.ID)
+		if err != nil {
+			return dtos.LoginResponseDTO{}, err
+		}
+		return dtos.LoginResponseDTO{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	tokenString, err := s.sessions.IssueToken(ctx, user.ID)
+	if err != nil {
+		return dtos.LoginResponseDTO{}, err
+	}
+	return dtos.LoginResponseDTO{Token: tokenString}, nil
 }
 
 func (s *UserService) GetUsers(ctx context.Context, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUsers")
+	defer span.End()
+
 	users, total, err := s.r.GetUsers(ctx, page, limit)
 	if err != nil {
 		return nil, err
@@ -140,6 +222,9 @@ func (s *UserService) GetUsers(ctx context.Context, page, limit int) (*dtos.Pagi
 }
 
 func (s *UserService) GetUserByID(ctx context.Context, id int) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
 	user, err := s.r.GetUserByID(ctx, id)
 	if err != nil {
 		return models.User{}, err
@@ -148,6 +233,9 @@ func (s *UserService) GetUserByID(ctx context.Context, id int) (models.User, err
 }
 
 func (s *UserService) GetUserFriends(ctx context.Context, userID, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserFriends")
+	defer span.End()
+
 	// Вызываем обновленный метод репозитория
 	users, total, err := s.r.GetUserFriends(ctx, userID, page, limit)
 	if err != nil {
@@ -172,6 +260,9 @@ func (s *UserService) GetUserFriends(ctx context.Context, userID, page, limit in
 }
 
 func (s *UserService) GetUserFollowers(ctx context.Context, userID, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserFollowers")
+	defer span.End()
+
 	users, total, err := s.r.GetUserFollowers(ctx, userID, page, limit)
 	if err != nil {
 		return nil, err
@@ -194,6 +285,9 @@ func (s *UserService) GetUserFollowers(ctx context.Context, userID, page, limit
 }
 
 func (s *UserService) GetUserFollowings(ctx context.Context, userID, page, limit int) (*dtos.PaginatedResponseDTO[models.User], error) {
+	ctx, span := tracer.Start(ctx, "UserService.GetUserFollowings")
+	defer span.End()
+
 	users, total, err := s.r.GetUserFollowings(ctx, userID, page, limit)
 	if err != nil {
 		return nil, err
@@ -215,42 +309,109 @@ func (s *UserService) GetUserFollowings(ctx context.Context, userID, page, limit
 	}, nil
 }
 
-func (s *UserService) DeleteUser(ctx context.Context, userID int) error {
-	// 1. Начинаем транзакцию
+// DeleteUser помечает аккаунт удаленным и завершает все его сессии. Данные стираются
+// через DeletionGracePeriod (AccountPurgeService), а до этого вход восстанавливает аккаунт.
+func (s *UserService) DeleteUser(ctx context.Context, userID int) (dtos.AccountDeletionResponseDTO, error) {
+	ctx, span := tracer.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		s.logger.Error("Failed to begin transaction", "error", err)
-		return err
+		return dtos.AccountDeletionResponseDTO{}, err
 	}
-	// defer с recover гарантирует, что если что-то пойдет не так, транзакция будет отменена
 	defer tx.Rollback()
 
-	// 2. Создаем экземпляры репозиториев, работающие ВНУТРИ этой транзакции
-	userRepoTx := s.r.WithTx(tx)
-	followRepoTx := s.followRepo.WithTx(tx)
-	recomRepoTx := s.recomRepo.WithTx(tx)
+	deletedAt, err := s.r.WithTx(tx).SoftDeleteUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dtos.AccountDeletionResponseDTO{}, ErrUserNotFound
+		}
+		s.logger.Error("Failed to delete user", "error", err, "userID", userID)
+		return dtos.AccountDeletionResponseDTO{}, err
+	}
+	if err := s.audit.record(ctx, tx, userID, models.AuditAccountDeleted, models.AuditTargetUser, userID, nil); err != nil {
+		return dtos.AccountDeletionResponseDTO{}, err
+	}
+	purgeAfter := deletedAt.Add(s.accounts.DeletionGracePeriod)
+	if err := s.jobs.enqueue(ctx, tx, JobPurgeAccount, accountPurgeJob{UserID: userID}, purgeAfter); err != nil {
+		return dtos.AccountDeletionResponseDTO{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return dtos.AccountDeletionResponseDTO{}, err
+	}
+
+	if err := s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+		s.logger.Error("Failed to revoke sessions of deleted user", "error", err, "userID", userID)
+		return dtos.AccountDeletionResponseDTO{}, err
+	}
+
+	s.logger.Info("User account deleted, waiting for grace period", "userID", userID)
+	return dtos.AccountDeletionResponseDTO{
+		DeletedAt:  deletedAt,
+		PurgeAfter: purgeAfter,
+	}, nil
+}
 
-	// 3. Выполняем операции в правильном порядке (от зависимых к основной)
-	if err := recomRepoTx.DeleteAllUserRecommendations(ctx, userID); err != nil {
-		s.logger.Error("Failed to delete user recommendations", "error", err, "userID", userID)
+// DeactivateUser временно скрывает аккаунт: профиль пропадает из списков, новые подписки
+// и рекомендации ему не принимаются. Все сессии завершаются; вход снимает деактивацию.
+func (s *UserService) DeactivateUser(ctx context.Context, userID int) error {
+	ctx, span := tracer.Start(ctx, "UserService.DeactivateUser")
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	if err := followRepoTx.DeleteAllUserFollows(ctx, userID); err != nil {
-		s.logger.Error("Failed to delete user follows", "error", err, "userID", userID)
+	if err := s.r.WithTx(tx).DeactivateUser(ctx, userID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if err := s.audit.record(ctx, tx, userID, models.AuditAccountDeactivated, models.AuditTargetUser, userID, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		return err
 	}
 
-	if err := userRepoTx.DeleteUser(ctx, userID); err != nil {
-		s.logger.Error("Failed to delete user", "error", err, "userID", userID)
+	if err := s.sessions.RevokeAllSessions(ctx, userID); err != nil {
+		s.logger.Error("Failed to revoke sessions of deactivated user", "error", err, "userID", userID)
 		return err
 	}
 
-	// 4. Если все прошло успешно, коммитим транзакцию
-	return tx.Commit()
+	s.logger.Info("User account deactivated", "userID", userID)
+	return nil
+}
+
+// CheckActiveUser проверяет, что с пользователем можно взаимодействовать (подписаться,
+// отправить рекомендацию). Возвращает ErrTargetUserNotFound или ErrUserDeactivated.
+func (s *UserService) CheckActiveUser(ctx context.Context, userID int) error {
+	ctx, span := tracer.Start(ctx, "UserService.CheckActiveUser")
+	defer span.End()
+
+	user, err := s.r.FindUserByIDWithPassword(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrTargetUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil || user.SuspendedAt != nil {
+		return ErrTargetUserNotFound
+	}
+	if user.DeactivatedAt != nil {
+		return ErrUserDeactivated
+	}
+	return nil
 }
 
 func (s *UserService) UpadeUser(ctx context.Context, userID int, userName string) (models.User, error) {
+	ctx, span := tracer.Start(ctx, "UserService.UpadeUser")
+	defer span.End()
+
 	updatedUser, err := s.r.UpdateUser(ctx, userID, userName)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -262,6 +423,9 @@ func (s *UserService) UpadeUser(ctx context.Context, userID int, userName string
 }
 
 func (s *UserService) ChangeCurrentUserPassword(ctx context.Context, userID int, changePasswordDto dtos.ChangePasswordDTO) error {
+	ctx, span := tracer.Start(ctx, "UserService.ChangeCurrentUserPassword")
+	defer span.End()
+
 	// 1. Get user by id
 	user, err := s.r.FindUserByIDWithPassword(ctx, userID)
 	if err != nil {
@@ -284,9 +448,18 @@ func (s *UserService) ChangeCurrentUserPassword(ctx context.Context, userID int,
 		return err
 	}
 
-	if err = s.r.UpdatePassword(ctx, userID, []byte(hashedPassword)); err != nil {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
 
-	return nil
+	if err = s.r.WithTx(tx).UpdatePassword(ctx, userID, []byte(hashedPassword)); err != nil {
+		return err
+	}
+	if err := s.audit.record(ctx, tx, userID, models.AuditPasswordChanged, models.AuditTargetUser, userID, nil); err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
@@ -0,0 +1,586 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/cobrich/recommendo/dtos"
+	"github.com/cobrich/recommendo/models"
+	"github.com/cobrich/recommendo/repo"
+	"github.com/cobrich/recommendo/utils"
+)
+
+var (
+	ErrWebhookNotFound           = errors.New("webhook not found")
+	ErrWebhookDeliveryNotFound   = errors.New("webhook delivery not found")
+	ErrInvalidWebhookURL         = errors.New("webhook url must be an absolute http or https url of a public host")
+	ErrInvalidWebhookEvents      = errors.New("webhook must subscribe to at least one known event")
+	ErrInvalidWebhookDescription = errors.New("webhook description is too long")
+	ErrTooManyWebhooks           = errors.New("webhook limit reached")
+	ErrWebhookDisabled           = errors.New("webhook is disabled")
+	errWebhookDestinationBlocked = errors.New("destination address is not allowed")
+)
+
+const (
+	maxWebhookURLLength         = 2048
+	maxWebhookDescriptionLength = 200
+	// maxWebhookErrorLength - сколько текста ошибки попадает в журнал доставок
+	maxWebhookErrorLength = 500
+)
+
+const (
+	// JobDispatchWebhookEvent - задача раздачи события из outbox вебхукам.
+	JobDispatchWebhookEvent = "webhook.dispatch"
+	// JobDeliverWebhook - задача отправки одной доставки; ее повторы - повторы доставки.
+	JobDeliverWebhook = "webhook.deliver"
+)
+
+type webhookEventJob struct {
+	EventID int64 `json:"event_id"`
+}
+
+type webhookDeliveryJob struct {
+	DeliveryID int64 `json:"delivery_id"`
+}
+
+// WebhookSettings - параметры вебхуков из конфига.
+type WebhookSettings struct {
+	// Timeout - сколько ждем ответа получателя.
+	Timeout time.Duration
+	// RetryBaseDelay и MaxRetryDelay - паузы между попытками; должны совпадать
+	// с JobOptions задачи JobDeliverWebhook, по ним показывается время следующей попытки.
+	RetryBaseDelay time.Duration
+	MaxRetryDelay  time.Duration
+	// Retention - сколько хранятся события и журнал доставок.
+	Retention time.Duration
+	// MaxPerOwner - сколько вебхуков может быть у пользователя (и у администраторов вместе).
+	MaxPerOwner int
+	// AllowPrivateNetworks разрешает адреса в локальной сети и loopback.
+	AllowPrivateNetworks bool
+}
+
+// WebhookService управляет вебхуками и доставляет события. Сервисы публикуют события
+// через publish в своей транзакции; раздача и отправка с повторами идут задачами JobService.
+type WebhookService struct {
+	db         *sql.DB
+	r          *repo.WebhookRepo
+	userRepo   *repo.UserRepo
+	followRepo *repo.FollowRepo
+	jobs       *JobService
+	client     *http.Client
+	settings   WebhookSettings
+	logger     *slog.Logger
+}
+
+func NewWebhookService(db *sql.DB, r *repo.WebhookRepo, userRepo *repo.UserRepo, followRepo *repo.FollowRepo,
+	jobs *JobService, settings WebhookSettings, logger *slog.Logger) *WebhookService {
+	s := &WebhookService{
+		db:         db,
+		r:          r,
+		userRepo:   userRepo,
+		followRepo: followRepo,
+		jobs:       jobs,
+		settings:   settings,
+		logger:     logger,
+	}
+
+	// Адрес проверяется при подключении, а не при сохранении: иначе DNS-запись
+	// можно поменять на внутренний адрес уже после проверки
+	dialer := &net.Dialer{Timeout: settings.Timeout, Control: s.checkDestination}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	s.client = &http.Client{
+		Timeout:   settings.Timeout,
+		Transport: transport,
+		// Редирект считается неудачей: получатель должен указать окончательный адрес
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return s
+}
+
+// GetWebhooks возвращает вебхуки владельца; ownerID = nil - вебхуки администраторов.
+func (s *WebhookService) GetWebhooks(ctx context.Context, ownerID *int) ([]models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhooks")
+	defer span.End()
+
+	return s.r.GetWebhooks(ctx, ownerID)
+}
+
+func (s *WebhookService) GetWebhook(ctx context.Context, ownerID *int, webhookID int64) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetWebhook")
+	defer span.End()
+
+	webhook, err := s.r.GetWebhook(ctx, ownerID, webhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+	return webhook, err
+}
+
+// CreateWebhook регистрирует вебхук и выдает ему секрет для проверки подписи.
+func (s *WebhookService) CreateWebhook(ctx context.Context, ownerID *int, rawURL string, events []string, description string) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.CreateWebhook")
+	defer span.End()
+
+	webhook, err := s.validate(models.Webhook{OwnerID: ownerID, Active: true}, rawURL, events, description)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	count, err := s.r.CountWebhooks(ctx, ownerID)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if count >= s.settings.MaxPerOwner {
+		return models.Webhook{}, ErrTooManyWebhooks
+	}
+
+	if webhook.Secret, err = utils.GenerateWebhookSecret(); err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+
+	webhook, err = s.r.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	s.logger.Info("Webhook created", "webhook_id", webhook.ID, "owner_id", ownerID, "events", webhook.Events)
+	return webhook, nil
+}
+
+// UpdateWebhook меняет адрес, события, описание и активность. Выключение вебхука
+// отменяет его еще не доставленные события.
+func (s *WebhookService) UpdateWebhook(ctx context.Context, ownerID *int, webhookID int64,
+	rawURL string, events []string, description string, active bool) (models.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.UpdateWebhook")
+	defer span.End()
+
+	webhook, err := s.validate(models.Webhook{ID: webhookID, OwnerID: ownerID, Active: active}, rawURL, events, description)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	defer tx.Rollback()
+
+	webhooks := s.r.WithTx(tx)
+	webhook, err = webhooks.UpdateWebhook(ctx, webhook)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.Webhook{}, ErrWebhookNotFound
+	}
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	if !webhook.Active {
+		if err := webhooks.CancelPendingDeliveries(ctx, webhook.ID, "webhook disabled"); err != nil {
+			return models.Webhook{}, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, ownerID *int, webhookID int64) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.DeleteWebhook")
+	defer span.End()
+
+	err := s.r.DeleteWebhook(ctx, ownerID, webhookID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrWebhookNotFound
+	}
+	return err
+}
+
+// GetDeliveries возвращает журнал доставок вебхука.
+func (s *WebhookService) GetDeliveries(ctx context.Context, ownerID *int, webhookID int64, page, limit int) (*dtos.PaginatedResponseDTO[models.WebhookDelivery], error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.GetDeliveries")
+	defer span.End()
+
+	if _, err := s.GetWebhook(ctx, ownerID, webhookID); err != nil {
+		return nil, err
+	}
+
+	deliveries, total, err := s.r.GetDeliveries(ctx, webhookID, page, limit)
+	if err != nil {
+		return nil, err
+	}
+	return newPage(deliveries, total, page, limit), nil
+}
+
+// Redeliver ставит событие из журнала в очередь еще раз, отдельной доставкой.
+func (s *WebhookService) Redeliver(ctx context.Context, ownerID *int, webhookID, deliveryID int64) (models.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	webhook, err := s.GetWebhook(ctx, ownerID, webhookID)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if !webhook.Active {
+		return models.WebhookDelivery{}, ErrWebhookDisabled
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	defer tx.Rollback()
+
+	delivery, err := s.r.WithTx(tx).Redeliver(ctx, webhookID, deliveryID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.WebhookDelivery{}, ErrWebhookDeliveryNotFound
+	}
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if err := s.jobs.enqueue(ctx, tx, JobDeliverWebhook, webhookDeliveryJob{DeliveryID: delivery.ID}, time.Time{}); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return models.WebhookDelivery{}, err
+	}
+
+	s.logger.Info("Webhook redelivery requested", "webhook_id", webhookID, "delivery_id", deliveryID, "new_delivery_id", delivery.ID)
+	return delivery, nil
+}
+
+func (s *WebhookService) validate(webhook models.Webhook, rawURL string, events []string, description string) (models.Webhook, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	if len(rawURL) > maxWebhookURLLength {
+		return models.Webhook{}, ErrInvalidWebhookURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" || parsed.User != nil {
+		return models.Webhook{}, ErrInvalidWebhookURL
+	}
+	// Явно внутренний адрес отклоняем сразу, не дожидаясь первой доставки
+	if !s.settings.AllowPrivateNetworks {
+		if parsed.Hostname() == "localhost" {
+			return models.Webhook{}, ErrInvalidWebhookURL
+		}
+		if addr, err := netip.ParseAddr(parsed.Hostname()); err == nil && !publicAddr(addr) {
+			return models.Webhook{}, ErrInvalidWebhookURL
+		}
+	}
+
+	if len(events) == 0 {
+		return models.Webhook{}, ErrInvalidWebhookEvents
+	}
+	seen := make(map[models.WebhookEventType]bool, len(events))
+	for _, name := range events {
+		event := models.WebhookEventType(name)
+		if !event.Valid() {
+			return models.Webhook{}, ErrInvalidWebhookEvents
+		}
+		if !seen[event] {
+			seen[event] = true
+			webhook.Events = append(webhook.Events, event)
+		}
+	}
+
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > maxWebhookDescriptionLength {
+		return models.Webhook{}, ErrInvalidWebhookDescription
+	}
+
+	webhook.URL = parsed.String()
+	webhook.Description = description
+	return webhook, nil
+}
+
+// publish добавляет событие пользователя userID в outbox и ставит задачу его раздачи
+// в транзакции tx. Если транзакция откатится, событие не будет отправлено.
+func (s *WebhookService) publish(ctx context.Context, tx *sql.Tx, eventType models.WebhookEventType, userID int, data map[string]any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	eventID, err := s.r.WithTx(tx).RecordEvent(ctx, eventType, userID, payload)
+	if err != nil {
+		return err
+	}
+	return s.jobs.enqueue(ctx, tx, JobDispatchWebhookEvent, webhookEventJob{EventID: eventID}, time.Time{})
+}
+
+// publishRecommendation публикует recommendation.received для получателя.
+// circleID != 0 - рекомендация пришла всему кругу.
+func (s *WebhookService) publishRecommendation(ctx context.Context, tx *sql.Tx, recommendationID, toID int,
+	from models.User, media models.MediaItem, note string, circleID int) error {
+	data := map[string]any{
+		"recommendation_id": recommendationID,
+		"from":              dtos.UserSummaryDTO{ID: from.ID, UserName: from.UserName},
+		"media": map[string]any{
+			"media_id": media.ID,
+			"type":     media.Type,
+			"name":     media.Name,
+			"year":     media.Year,
+		},
+		"note": note,
+	}
+	if circleID != 0 {
+		data["circle_id"] = circleID
+	}
+	return s.publish(ctx, tx, models.WebhookRecommendationReceived, toID, data)
+}
+
+// publishFollow публикует follower.new для followingID и, если подписка стала
+// взаимной, friend.new для обоих. Вызывается после создания подписки в tx.
+func (s *WebhookService) publishFollow(ctx context.Context, tx *sql.Tx, followerID, followingID int) error {
+	users := s.userRepo.WithTx(tx)
+	follower, err := users.GetUserByID(ctx, followerID)
+	if err != nil {
+		return err
+	}
+	following, err := users.GetUserByID(ctx, followingID)
+	if err != nil {
+		return err
+	}
+
+	err = s.publish(ctx, tx, models.WebhookFollowerNew, followingID, map[string]any{
+		"follower": dtos.UserSummaryDTO{ID: follower.ID, UserName: follower.UserName},
+	})
+	if err != nil {
+		return err
+	}
+
+	friends, err := s.followRepo.WithTx(tx).AreUsersFriends(ctx, followerID, followingID)
+	if err != nil || !friends {
+		return err
+	}
+	for _, pair := range [][2]models.User{{follower, following}, {following, follower}} {
+		err := s.publish(ctx, tx, models.WebhookFriendNew, pair[0].ID, map[string]any{
+			"friend": dtos.UserSummaryDTO{ID: pair[1].ID, UserName: pair[1].UserName},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveOldEvents удаляет события и журнал доставок старше срока хранения. Запускается по расписанию.
+func (s *WebhookService) RemoveOldEvents(ctx context.Context) {
+	removed, err := s.r.DeleteEventsBefore(ctx, time.Now().Add(-s.settings.Retention))
+	if err != nil {
+		s.logger.Error("Failed to remove old webhook events", "error", err)
+		return
+	}
+	if removed > 0 {
+		s.logger.Info("Removed old webhook events", "count", removed)
+	}
+}
+
+// DispatchEvent - обработчик задачи JobDispatchWebhookEvent: создает доставки события
+// подходящим вебхукам и ставит задачу отправки каждой из них.
+func (s *WebhookService) DispatchEvent(ctx context.Context, job models.Job) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.DispatchEvent")
+	defer span.End()
+
+	var payload webhookEventJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid webhook event job payload: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	deliveryIDs, err := s.r.WithTx(tx).DispatchEvent(ctx, payload.EventID)
+	if err != nil {
+		return err
+	}
+	for _, deliveryID := range deliveryIDs {
+		if err := s.jobs.enqueue(ctx, tx, JobDeliverWebhook, webhookDeliveryJob{DeliveryID: deliveryID}, time.Time{}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Deliver - обработчик задачи JobDeliverWebhook: одна попытка отправки. Ошибка
+// отправки возвращается, и JobService повторит задачу, пока не кончатся попытки.
+func (s *WebhookService) Deliver(ctx context.Context, job models.Job) error {
+	ctx, span := tracer.Start(ctx, "WebhookService.Deliver")
+	defer span.End()
+
+	var payload webhookDeliveryJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid webhook delivery job payload: %w", err)
+	}
+
+	d, err := s.r.StartAttempt(ctx, payload.DeliveryID, job.Attempts)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Доставку отменили выключением вебхука или удалили вместе с ним
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	responseStatus, sendErr := s.send(ctx, d)
+	if ctx.Err() != nil {
+		// Сервер останавливается (задача вернется в очередь) или попытка не уложилась
+		// в таймаут задачи; после последней попытки доставку завершит FailDelivery
+		return context.Cause(ctx)
+	}
+
+	status := models.DeliverySucceeded
+	nextAttemptAt := time.Now()
+	lastError := ""
+	if sendErr != nil {
+		lastError = sendErr.Error()
+		if len(lastError) > maxWebhookErrorLength {
+			lastError = lastError[:maxWebhookErrorLength]
+		}
+		status = models.DeliveryFailed
+		if !job.LastAttempt() {
+			status = models.DeliveryPending
+			nextAttemptAt = nextAttemptAt.Add(utils.RetryDelay(s.settings.RetryBaseDelay, s.settings.MaxRetryDelay, job.Attempts))
+		}
+		s.logger.Warn("Webhook delivery attempt failed", "error", sendErr, "delivery_id", d.Delivery.ID,
+			"webhook_id", d.Delivery.WebhookID, "attempt", job.Attempts, "status", status)
+	}
+
+	if err := s.r.RecordAttempt(ctx, d.Delivery.ID, status, responseStatus, lastError, nextAttemptAt); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// FailDelivery - OnFailure задачи JobDeliverWebhook: доставка, последняя попытка которой
+// не дошла до записи результата (таймаут задачи, падение процесса), считается проваленной.
+func (s *WebhookService) FailDelivery(ctx context.Context, job models.Job) {
+	var payload webhookDeliveryJob
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		s.logger.Error("Invalid webhook delivery job payload", "error", err, "job_id", job.ID)
+		return
+	}
+
+	if err := s.r.FailDelivery(ctx, payload.DeliveryID, "delivery attempt did not finish"); err != nil {
+		s.logger.Error("Failed to mark webhook delivery as failed", "error", err, "delivery_id", payload.DeliveryID)
+	}
+}
+
+// send отправляет событие и возвращает код ответа, если ответ был.
+// Успех - только ответ 2xx.
+func (s *WebhookService) send(ctx context.Context, d repo.DueDelivery) (*int, error) {
+	event := d.Delivery.Event
+	body, err := json.Marshal(dtos.WebhookPayloadDTO{
+		ID:        event.ID,
+		Type:      string(event.Type),
+		UserID:    event.UserID,
+		CreatedAt: event.CreatedAt,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Recommendo-Webhooks/1.0")
+	req.Header.Set("X-Recommendo-Event", string(event.Type))
+	req.Header.Set("X-Recommendo-Event-ID", strconv.FormatInt(event.ID, 10))
+	req.Header.Set("X-Recommendo-Delivery", strconv.FormatInt(d.Delivery.ID, 10))
+	req.Header.Set("X-Recommendo-Signature", utils.SignWebhook(d.Secret, time.Now(), body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	// Тело ответа не нужно, но дочитываем немного, чтобы соединение вернулось в пул
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return &resp.StatusCode, nil
+}
+
+// checkDestination не дает вебхукам ходить во внутреннюю сеть, если это не разрешено в конфиге.
+func (s *WebhookService) checkDestination(network, address string, _ syscall.RawConn) error {
+	if s.settings.AllowPrivateNetworks {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if addr, err := netip.ParseAddr(host); err != nil || !publicAddr(addr) {
+		return errWebhookDestinationBlocked
+	}
+	return nil
+}
+
+// blockedPrefixes - адреса специального назначения (реестры IANA), куда вебхуки не ходят
+// без allow_private_networks: частные и служебные сети, CGNAT, документация, multicast,
+// а также NAT64 и 6to4, через которые можно добраться до частного IPv4-адреса.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("192.88.99.0/24"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("224.0.0.0/4"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001::/23"),
+	netip.MustParsePrefix("2001:db8::/32"),
+	netip.MustParsePrefix("2002::/16"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("ff00::/8"),
+}
+
+func publicAddr(addr netip.Addr) bool {
+	// IPv4-mapped (::ffff:a.b.c.d) проверяем как IPv4; с зоной Contains всегда false
+	addr = addr.Unmap().WithZone("")
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,13 @@
+package utils
+
+import "time"
+
+// RetryDelay - пауза перед повтором после attempts неудачных попыток:
+// base, 2x, 4x... но не больше limit.
+func RetryDelay(base, limit time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseCronNext(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := func(year int, month time.Month, day, hour, minute int) time.Time {
+		return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"hourly", "@hourly", utc(2024, 1, 1, 10, 15), utc(2024, 1, 1, 11, 0)},
+		{"daily", "@daily", utc(2024, 1, 1, 10, 15), utc(2024, 1, 2, 0, 0)},
+		{"midnight", "@midnight", utc(2024, 1, 1, 0, 0), utc(2024, 1, 2, 0, 0)},
+		{"weekly", "@weekly", utc(2024, 1, 3, 12, 0), utc(2024, 1, 7, 0, 0)},
+		{"monthly", "@monthly", utc(2024, 1, 15, 0, 0), utc(2024, 2, 1, 0, 0)},
+		{"yearly", "@yearly", utc(2024, 3, 1, 0, 0), utc(2025, 1, 1, 0, 0)},
+		{"annually", "@annually", utc(2024, 12, 31, 23, 59), utc(2025, 1, 1, 0, 0)},
+		{"every", "@every 90m", utc(2024, 1, 1, 10, 0), utc(2024, 1, 1, 11, 30)},
+		{"strictly after", "0 10 * * *", utc(2024, 1, 1, 10, 0), utc(2024, 1, 2, 10, 0)},
+		{"seconds ignored", "* * * * *", time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC), utc(2024, 1, 1, 10, 1)},
+		{"step", "*/15 * * * *", utc(2024, 1, 1, 10, 7), utc(2024, 1, 1, 10, 15)},
+		{"step from value", "5/20 * * * *", utc(2024, 1, 1, 10, 26), utc(2024, 1, 1, 10, 45)},
+		{"step wraps hour", "5/20 * * * *", utc(2024, 1, 1, 10, 46), utc(2024, 1, 1, 11, 5)},
+		{"range with step", "0 9-17/4 * * *", utc(2024, 1, 1, 9, 0), utc(2024, 1, 1, 13, 0)},
+		{"range with step wraps day", "0 9-17/4 * * *", utc(2024, 1, 1, 18, 0), utc(2024, 1, 2, 9, 0)},
+		{"list", "0 8,20 * * *", utc(2024, 1, 1, 8, 0), utc(2024, 1, 1, 20, 0)},
+		{"weekday range", "0 0 * * 1-5", utc(2024, 1, 6, 0, 0), utc(2024, 1, 8, 0, 0)},
+		{"sunday as 7", "0 0 * * 7", utc(2024, 1, 3, 0, 0), utc(2024, 1, 7, 0, 0)},
+		{"sunday as 0", "0 0 * * 0", utc(2024, 1, 3, 0, 0), utc(2024, 1, 7, 0, 0)},
+		{"month", "0 0 1 6 *", utc(2024, 1, 1, 0, 0), utc(2024, 6, 1, 0, 0)},
+		{"leap day", "0 0 29 2 *", utc(2024, 3, 1, 0, 0), utc(2028, 2, 29, 0, 0)},
+		// 13 января 2024 - суббота: первой подходит пятница 5-го, потом 12-е и само 13-е
+		{"dom or dow: weekday first", "0 0 13 * 5", utc(2024, 1, 1, 0, 0), utc(2024, 1, 5, 0, 0)},
+		{"dom or dow: next weekday", "0 0 13 * 5", utc(2024, 1, 5, 0, 0), utc(2024, 1, 12, 0, 0)},
+		{"dom or dow: day of month", "0 0 13 * 5", utc(2024, 1, 12, 0, 0), utc(2024, 1, 13, 0, 0)},
+		// "*/2" в дне месяца - все равно "*": нужны и нечетное число, и понедельник
+		{"star step dom and dow", "0 0 */2 * 1", utc(2024, 1, 1, 0, 0), utc(2024, 1, 15, 0, 0)},
+		// "*/2" в дне недели (вс, вт, чт, сб) тоже "*": 2-е число нужно в один из этих дней
+		{"dom and star step dow", "0 0 2 * */2", utc(2024, 1, 1, 0, 0), utc(2024, 1, 2, 0, 0)},
+		{"dom and star step dow skips month", "0 0 2 * */2", utc(2024, 1, 2, 0, 0), utc(2024, 3, 2, 0, 0)},
+		{"never fires", "0 0 30 2 *", utc(2024, 1, 1, 0, 0), time.Time{}},
+		// 10 марта 2024 в Нью-Йорке 02:00 EST сразу становится 03:00 EDT
+		{"dst gap", "30 2 * * *", time.Date(2024, 3, 10, 0, 0, 0, 0, newYork), utc(2024, 3, 10, 7, 30)},
+		{"dst gap next day", "30 2 * * *", utc(2024, 3, 10, 7, 30).In(newYork), utc(2024, 3, 11, 6, 30)},
+		// 3 ноября 2024 час 01:00-02:00 повторяется: 01:30 EDT, затем 01:30 EST
+		{"dst overlap fires once", "30 1 * * *", utc(2024, 11, 3, 5, 30).In(newYork), utc(2024, 11, 4, 6, 30)},
+		{"dst overlap hourly", "0 * * * *", utc(2024, 11, 3, 5, 0).In(newYork), utc(2024, 11, 3, 7, 0)},
+		{"dst wall clock", "0 9 * * *", time.Date(2024, 11, 2, 9, 0, 0, 0, newYork), utc(2024, 11, 3, 14, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.spec)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+			}
+			got := schedule.Next(tt.from)
+			if !got.Equal(tt.want) {
+				t.Errorf("ParseCron(%q).Next(%v) = %v, want %v", tt.spec, tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"@every",
+		"@every x",
+		"@every 0s",
+		"@every 500ms",
+		"@often",
+	}
+
+	for _, spec := range specs {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want error", spec)
+		}
+	}
+}
@@ -6,6 +6,19 @@ import (
 	"strconv"
 )
 
+// Лимиты пагинации по умолчанию. Переопределяются из конфига через ConfigurePagination.
+var (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// ConfigurePagination задает лимит по умолчанию и максимальный лимит страницы.
+// Вызывается один раз при старте приложения.
+func ConfigurePagination(defaultLimit, maxLimit int) {
+	defaultPageLimit = defaultLimit
+	maxPageLimit = maxLimit
+}
+
 // (вспомогательная функция для парсинга, чтобы не дублировать код)
 func ParsePaginationParams(r *http.Request) (page, limit int, err error) {
 	// Получаем параметры из URL, например /users?page=2&limit=25
@@ -14,7 +27,7 @@ func ParsePaginationParams(r *http.Request) (page, limit int, err error) {
 
 	// --- Устанавливаем значения по умолчанию ---
 	page = 1
-	limit = 20 // Хорошее значение по умолчанию
+	limit = defaultPageLimit
 
 	// --- Парсим и валидируем ---
 	if pageStr != "" {
@@ -31,9 +44,9 @@ func ParsePaginationParams(r *http.Request) (page, limit int, err error) {
 		}
 	}
 
-	// (Опционально) Ограничиваем максимальный limit, чтобы защититься от DoS-атак
-	if limit > 100 {
-		limit = 100
+	// Ограничиваем максимальный limit, чтобы защититься от DoS-атак
+	if limit > maxPageLimit {
+		limit = maxPageLimit
 	}
 
 	return page, limit, nil
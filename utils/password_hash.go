@@ -2,6 +2,15 @@ package utils
 
 import "golang.org/x/crypto/bcrypt"
 
+// bcryptCost - "стоимость" хэширования. Переопределяется из конфига через ConfigurePasswordHashing.
+var bcryptCost = bcrypt.DefaultCost
+
+// ConfigurePasswordHashing задает стоимость bcrypt для новых хэшей.
+// Уже сохраненные хэши продолжают проверяться: стоимость записана в самом хэше.
+func ConfigurePasswordHashing(cost int) {
+	bcryptCost = cost
+}
+
 // GetPasswordHash создает безопасный bcrypt хэш из строки пароля.
 // Эта функция будет использоваться при регистрации пользователя или смене пароля.
 func GetPasswordHash(password string) (string, error) {
@@ -9,8 +18,8 @@ func GetPasswordHash(password string) (string, error) {
 	// Второй аргумент - это "стоимость" (cost) хэширования.
 	// Чем она выше, тем дольше вычисляется хэш и тем сложнее его
 	// взломать методом перебора (брутфорсом).
-	// bcrypt.DefaultCost - это хороший, сбалансированный выбор (сейчас это 10).
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	// bcrypt.DefaultCost (10) - хороший, сбалансированный выбор, он же значение по умолчанию.
+	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
 	if err != nil {
 		// Если при хэшировании произошла ошибка, возвращаем ее.
 		return "", err
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Алфавит без похожих символов (0/o, 1/l/i), чтобы код было проще переписать с бумаги.
+const recoveryAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+// GenerateRecoveryCode создает код вида "abcd-efgh-jkmn" (~70 бит энтропии).
+func GenerateRecoveryCode() (string, error) {
+	raw := make([]byte, 12)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	for i, v := range raw {
+		if i > 0 && i%4 == 0 {
+			b.WriteByte('-')
+		}
+		b.WriteByte(recoveryAlphabet[int(v)%len(recoveryAlphabet)])
+	}
+	return b.String(), nil
+}
+
+// HashRecoveryCode нормализует код и возвращает его SHA-256.
+// bcrypt здесь не нужен: у случайного кода достаточно энтропии против перебора.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
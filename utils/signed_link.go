@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"time"
+)
+
+// SignLink подписывает ресурс и срок действия ссылки на него. Подпись и срок
+// передаются в query-параметрах, поэтому ссылку можно открыть без токена.
+func SignLink(secret []byte, resource string, expires time.Time) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(resource + "|" + strconv.FormatInt(expires.Unix(), 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyLink проверяет подпись из SignLink и то, что срок ссылки не истек.
+// expires - значение query-параметра (Unix-время в секундах).
+func VerifyLink(secret []byte, resource, expires, signature string, now time.Time) bool {
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return false
+	}
+	expiresAt := time.Unix(expiresUnix, 0)
+	if now.After(expiresAt) {
+		return false
+	}
+
+	expected := SignLink(secret, resource, expiresAt)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,55 @@
+package utils
+
+import "strings"
+
+// DescribeDevice делает из User-Agent короткое описание для списка сессий,
+// например "Firefox on Linux". Это подсказка для пользователя, а не точное определение.
+func DescribeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := firstMatch(userAgent, []uaRule{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+	})
+	os := firstMatch(userAgent, []uaRule{
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Windows", "Windows"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	})
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return os
+	default:
+		return "Unknown device"
+	}
+}
+
+type uaRule struct {
+	marker string
+	name   string
+}
+
+// firstMatch возвращает имя первого правила, чей маркер есть в строке.
+// Порядок важен: например, User-Agent Chrome содержит и "Safari/".
+func firstMatch(userAgent string, rules []uaRule) string {
+	for _, rule := range rules {
+		if strings.Contains(userAgent, rule.marker) {
+			return rule.name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// Group запускает фоновые задачи приложения и останавливает их вместе с сервером.
+// Все задачи получают общий контекст, который отменяется в Shutdown.
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	logger *slog.Logger
+}
+
+func NewGroup(logger *slog.Logger) *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{ctx: ctx, cancel: cancel, logger: logger}
+}
+
+// Go запускает долгоживущую задачу. Задача должна вернуться после отмены ctx.
+func (g *Group) Go(name string, fn func(ctx context.Context)) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.logger.Info("Background worker started", "worker", name)
+		fn(g.ctx)
+		g.logger.Info("Background worker stopped", "worker", name)
+	}()
+}
+
+// Shutdown отменяет контекст задач и ждет их завершения, но не дольше,
+// чем позволяет переданный ctx.
+func (g *Group) Shutdown(ctx context.Context) error {
+	g.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}